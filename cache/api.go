@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/magic-lib/go-plat-utils/conv"
 	"time"
@@ -14,6 +15,155 @@ type CommCache[V any] interface {
 	Del(ctx context.Context, key string) (bool, error)
 }
 
+const (
+	TTLNoExpire time.Duration = -1 // key 存在但没有过期时间
+	TTLNotFound time.Duration = -2 // key 不存在或已过期
+)
+
+// ExpiringCache 可查询、修改过期时间的缓存，与 CommCache 配合使用
+type ExpiringCache interface {
+	// TTL 返回剩余有效期，不存在返回 TTLNotFound，永不过期返回 TTLNoExpire
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	// Expire 重新设置过期时间，不改写值，ttl<=0 时与 Set 的默认时长一致
+	Expire(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	// Touch 按写入时的时长重新计算过期时间
+	Touch(ctx context.Context, key string) (bool, error)
+}
+
+// CounterCache 原子计数器，适用于限流、配额等场景
+type CounterCache interface {
+	// IncrBy 原子增加 delta 并返回增加后的值，key 不存在或已过期时从 0 开始计数并设置过期时间 ttl
+	IncrBy(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error)
+	// DecrBy 原子减少 delta 并返回减少后的值，过期时间规则同 IncrBy
+	DecrBy(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error)
+	// GetCounter 获取当前计数，不存在返回 0
+	GetCounter(ctx context.Context, key string) (int64, error)
+}
+
+// VersionedCache 带版本号的缓存，用于乐观并发控制。
+// 每次 Set 或 CompareAndSet 成功后版本号加 1，版本号 0 只表示 key 不存在，
+// 所以期望版本号为 0 的 CompareAndSet 只能写入不存在的 key，不会覆盖并发的 Set
+type VersionedCache[V any] interface {
+	// GetWithVersion 取值及其版本号
+	GetWithVersion(ctx context.Context, key string) (V, int64, error)
+	// CompareAndSet 当前版本号等于 expectedVersion 时写入，返回新的版本号，不一致时返回 *ErrVersionConflict
+	CompareAndSet(ctx context.Context, key string, expectedVersion int64, val V, ttl time.Duration) (int64, error)
+}
+
+// ErrVersionConflict CompareAndSet 时版本号不一致
+type ErrVersionConflict struct {
+	Key      string
+	Expected int64 // 期望的版本号
+	Actual   int64 // 当前的版本号
+}
+
+func (e *ErrVersionConflict) Error() string {
+	return fmt.Sprintf("version conflict: key=%s, expected=%d, actual=%d", e.Key, e.Expected, e.Actual)
+}
+
+// ScannableCache 可按前缀遍历、删除 key 的缓存，与 CommCache 配合使用
+type ScannableCache interface {
+	// Scan 按游标遍历以 prefix 开头的 key，cursor 为空时从头开始，返回的 nextCursor 为空表示遍历结束。
+	// 遍历期间有写入时，可能遗漏或重复返回部分 key
+	Scan(ctx context.Context, prefix string, cursor string, count int) (keys []string, nextCursor string, err error)
+	// DeletePrefix 删除以 prefix 开头的所有 key，返回删除的数量
+	DeletePrefix(ctx context.Context, prefix string) (int64, error)
+}
+
+// RangeableCache 一次遍历所有未过期的条目，适合需要全量读取的场景，比按游标多次 Scan 开销小
+type RangeableCache[V any] interface {
+	// Range 遍历以 prefix 开头的未过期条目及剩余有效期，永不过期时为 TTLNoExpire，fn 返回 false 时停止
+	Range(ctx context.Context, prefix string, fn func(key string, val V, ttl time.Duration) bool) error
+}
+
+// TagIndex tag 到 key 的索引，索引条目随 key 的有效期过期清理
+type TagIndex interface {
+	// AddTagKeys 记录 key 属于 tags，ttl 与 key 的有效期一致
+	AddTagKeys(ctx context.Context, key string, ttl time.Duration, tags ...string) error
+	// TagKeys 返回 tag 下未过期的 key
+	TagKeys(ctx context.Context, tag string) ([]string, error)
+	// DelTags 删除 tags 的索引
+	DelTags(ctx context.Context, tags ...string) error
+	// DelTagKeys 从 tag 的索引中删除指定的 key
+	DelTagKeys(ctx context.Context, tag string, keys ...string) error
+}
+
+// TaggedCache 支持按 tag 批量失效的缓存
+type TaggedCache[V any] interface {
+	CommCache[V]
+	// SetWithTags 写入并给 key 打上 tags
+	SetWithTags(ctx context.Context, key string, val V, ttl time.Duration, tags ...string) (bool, error)
+	// InvalidateTags 删除 tags 下的所有 key，返回删除的 key 数量
+	InvalidateTags(ctx context.Context, tags ...string) (int64, error)
+}
+
+// LoaderFunc 缓存未命中时加载数据，返回值及其有效期，有效期<=0 时使用默认时长。
+// 数据不存在时返回 ErrNotFound，可配合负缓存避免穿透
+type LoaderFunc[V any] func(ctx context.Context, key string) (V, time.Duration, error)
+
+// LoadingCache 未命中时自动加载的缓存，并发加载同一个 key 时只执行一次
+type LoadingCache[V any] interface {
+	CommCache[V]
+	// GetOrLoad 先查缓存，未命中时用 loader 加载并写入
+	GetOrLoad(ctx context.Context, key string, loader LoaderFunc[V]) (V, error)
+}
+
+// HashCache 按 key 分组存储多个 field，比如用户的各项设置，有效期作用于整个 key
+type HashCache[V any] interface {
+	// HGet 取 field 的值，key 或 field 不存在时返回 ErrNotFound
+	HGet(ctx context.Context, key, field string) (V, error)
+	// HSet 写入 field 并将整个 key 的有效期重置为 ttl
+	HSet(ctx context.Context, key, field string, val V, ttl time.Duration) (bool, error)
+	// HMSet 批量写入 field，有效期规则同 HSet
+	HMSet(ctx context.Context, key string, values map[string]V, ttl time.Duration) (bool, error)
+	// HDel 删除 fields，返回实际删除的数量，fields 为空时删除整个 key
+	HDel(ctx context.Context, key string, fields ...string) (int64, error)
+	// HGetAll 取出所有 field，key 不存在时返回空 map
+	HGetAll(ctx context.Context, key string) (map[string]V, error)
+	// HLen field 的数量
+	HLen(ctx context.Context, key string) (int64, error)
+}
+
+// ShardedCache 按一致性哈希把 key 分散到多个独立节点，key 中带 {ns} 时按 ns 路由，同一命名空间在同一个节点
+type ShardedCache[V any] interface {
+	CommCache[V]
+	// MGet 批量取值，按节点分组并发执行，只返回取到的 key
+	MGet(ctx context.Context, keys ...string) (map[string]V, error)
+	// MSet 批量写入，按节点分组并发执行
+	MSet(ctx context.Context, values map[string]V, timeout time.Duration) error
+	// MDel 批量删除，返回删除成功的数量
+	MDel(ctx context.Context, keys ...string) (int64, error)
+	// AddNode 添加节点，同名节点会被替换，只有落到新节点上的 key 会重新映射
+	AddNode(name string, node CommCache[V])
+	// RemoveNode 删除节点，只有原来落在该节点上的 key 会重新映射
+	RemoveNode(name string)
+	// NodeFor key 所在的节点名称，没有节点时返回空
+	NodeFor(key string) string
+}
+
+// HotKeyCache 探测热点 key 并提升到本地内存，降低单个分片的压力
+type HotKeyCache[V any] interface {
+	CommCache[V]
+	// HotKeys 当前的热点 key，按估算访问次数从大到小排列
+	HotKeys() []HotKey
+}
+
+// PipelineStatsProvider 开启自动合并 pipeline 的缓存，可通过类型断言取得统计
+type PipelineStatsProvider interface {
+	// PipelineStats 合并的批次、命令数及耗时，未开启时返回 nil
+	PipelineStats() *RedisPipelineStats
+}
+
+// BreakerCache 带熔断的缓存，远程缓存不可用时快速失败或降级
+type BreakerCache[V any] interface {
+	CommCache[V]
+	// State 熔断器当前状态
+	State() BreakerState
+}
+
+// ErrNotFound 数据不存在
+var ErrNotFound = errors.New("cache: not found")
+
 type CommLocker interface {
 	TryLock(ctx context.Context, key string, ttl time.Duration) (lockId string, err error)
 	Renew(ctx context.Context, key string, lockId string, ttl time.Duration) (ok bool, err error)
@@ -30,7 +180,12 @@ func getNsKey(ns string, key string) string {
 }
 
 func NsGetStr[V any](ctx context.Context, co CommCache[string], ns string, key string) (V, error) {
-	retStr, err := co.Get(ctx, getNsKey(ns, key))
+	nsKey, err := nsKeyOf(ctx, co, ns, key)
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+	retStr, err := co.Get(ctx, nsKey)
 	if err != nil {
 		var zero V
 		return zero, err
@@ -38,22 +193,47 @@ func NsGetStr[V any](ctx context.Context, co CommCache[string], ns string, key s
 	return strToVal[V](retStr)
 }
 func NsSetStr[V any](ctx context.Context, co CommCache[string], ns string, key string, val V, timeout time.Duration) (bool, error) {
-	return co.Set(ctx, getNsKey(ns, key), conv.String(val), timeout)
+	nsKey, err := nsKeyOf(ctx, co, ns, key)
+	if err != nil {
+		return false, err
+	}
+	return co.Set(ctx, nsKey, conv.String(val), timeout)
 }
 
-// NsGet xxx
+// NsGet 存储实现了 CounterCache 时，key 中会带上命名空间的代数，见 NsInvalidate
 func NsGet[V any](ctx context.Context, co CommCache[V], ns string, key string) (V, error) {
-	return co.Get(ctx, getNsKey(ns, key))
+	nsKey, err := nsKeyOf(ctx, co, ns, key)
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+	return co.Get(ctx, nsKey)
 }
 
 // NsSet xxx
 func NsSet[V any](ctx context.Context, co CommCache[V], ns string, key string, val V, timeout time.Duration) (bool, error) {
-	return co.Set(ctx, getNsKey(ns, key), val, timeout)
+	nsKey, err := nsKeyOf(ctx, co, ns, key)
+	if err != nil {
+		return false, err
+	}
+	return co.Set(ctx, nsKey, val, timeout)
 }
 
 // NsDel xxx
 func NsDel[V any](ctx context.Context, co CommCache[V], ns string, key string) (bool, error) {
-	return co.Del(ctx, getNsKey(ns, key))
+	nsKey, err := nsKeyOf(ctx, co, ns, key)
+	if err != nil {
+		return false, err
+	}
+	return co.Del(ctx, nsKey)
+}
+
+// NsDeleteAll 删除 namespace 下的所有 key
+func NsDeleteAll(ctx context.Context, co ScannableCache, ns string) (int64, error) {
+	if ns == "" {
+		return 0, fmt.Errorf("namespace is empty")
+	}
+	return co.DeletePrefix(ctx, getNsKey(ns, ""))
 }
 
 var (
@@ -61,9 +241,70 @@ var (
 	_ CommCache[any]  = (*redisCache[any])(nil)
 	_ CommCache[any]  = (*memGoCache[any])(nil)
 	_ CommCache[any]  = (*memLruCache[any])(nil)
+	_ CommCache[any]  = (*fastCache[any])(nil)
+	_ CommCache[any]  = (*memVersionedCache[any])(nil)
 	_ CommCache[any]  = (*diskCache[any])(nil)
 	_ CommCache[any]  = (*mySQLCache[any])(nil)
+	_ CommCache[any]  = (*jitterCache[any])(nil)
+	_ CommCache[any]  = (*chunkedCache[any])(nil)
 	_ CommCache[any]  = (*JetCache[any])(nil)
 	_ CommCache[bool] = (*cuckooFilter[bool])(nil)
 	_ CommCache[bool] = (*countingFilter[bool])(nil)
+
+	_ ExpiringCache = (*defaultCache[any])(nil)
+	_ ExpiringCache = (*redisCache[any])(nil)
+	_ ExpiringCache = (*memGoCache[any])(nil)
+	_ ExpiringCache = (*memLruCache[any])(nil)
+	_ ExpiringCache = (*fastCache[any])(nil)
+	_ ExpiringCache = (*diskCache[any])(nil)
+	_ ExpiringCache = (*mySQLCache[any])(nil)
+	_ ExpiringCache = (*BBoltCache[any])(nil)
+	_ ExpiringCache = (*slidingCache[any])(nil)
+
+	_ CounterCache = (*redisCache[any])(nil)
+	_ CounterCache = (*redisClient)(nil)
+	_ CounterCache = (*mySQLCache[any])(nil)
+	_ CounterCache = (*BBoltCache[any])(nil)
+	_ CounterCache = (*memCounter)(nil)
+
+	_ ScannableCache = (*defaultCache[any])(nil)
+	_ ScannableCache = (*redisCache[any])(nil)
+	_ ScannableCache = (*memGoCache[any])(nil)
+	_ ScannableCache = (*memLruCache[any])(nil)
+	_ ScannableCache = (*memVersionedCache[any])(nil)
+	_ ScannableCache = (*diskCache[any])(nil)
+	_ ScannableCache = (*mySQLCache[any])(nil)
+	_ ScannableCache = (*BBoltCache[any])(nil)
+
+	_ RangeableCache[any] = (*diskCache[any])(nil)
+	_ RangeableCache[any] = (*BBoltCache[any])(nil)
+
+	_ TagIndex = (*redisCache[any])(nil)
+	_ TagIndex = (*mySQLCache[any])(nil)
+	_ TagIndex = (*BBoltCache[any])(nil)
+	_ TagIndex = (*memTagIndex)(nil)
+
+	_ TaggedCache[any] = (*taggedCache[any])(nil)
+
+	_ LoadingCache[any] = (*loadingCache[any])(nil)
+
+	_ BreakerCache[any] = (*breakerCache[any])(nil)
+
+	_ HashCache[any] = (*redisCache[any])(nil)
+	_ HashCache[any] = (*mySQLCache[any])(nil)
+	_ HashCache[any] = (*BBoltCache[any])(nil)
+	_ HashCache[any] = (*memHashCache[any])(nil)
+
+	_ ShardedCache[any] = (*shardedCache[any])(nil)
+	_ batchNode[any]    = (*redisCache[any])(nil)
+
+	_ VersionedCache[any] = (*redisCache[any])(nil)
+	_ VersionedCache[any] = (*mySQLCache[any])(nil)
+	_ VersionedCache[any] = (*BBoltCache[any])(nil)
+	_ VersionedCache[any] = (*memVersionedCache[any])(nil)
+
+	_ HotKeyCache[any] = (*hotKeyCache[any])(nil)
+
+	_ PipelineStatsProvider = (*redisCache[any])(nil)
+	_ PipelineStatsProvider = (*redisClient)(nil)
 )
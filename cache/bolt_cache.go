@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -8,13 +9,16 @@ import (
 	"github.com/magic-lib/go-plat-utils/conv"
 	"github.com/samber/lo"
 	bolt "go.etcd.io/bbolt"
+	"strings"
 	"time"
 )
 
 // boltStoredValue 存储在 bbolt 中的值，含过期时间
 type boltStoredValue struct {
 	Data      string `json:"d"`
-	ExpiresAt int64  `json:"e"` // 过期时间(unix nano), 0 永不过期
+	ExpiresAt int64  `json:"e"`           // 过期时间(unix nano), 0 永不过期
+	TTL       int64  `json:"t,omitempty"` // 写入时的有效期(nano)，用于 Touch 续期
+	Version   int64  `json:"v,omitempty"` // 版本号，Set 和 CompareAndSet 成功后加 1
 }
 
 // BBoltCache 基于 BoltDB 的缓存实现
@@ -22,6 +26,7 @@ type BBoltCache[V any] struct {
 	db      *bolt.DB
 	config  *BBoltCacheConfig
 	closeCh chan struct{}
+	nsGens  nsGenerationCache
 }
 
 // BBoltCacheConfig BoltDB 缓存配置
@@ -160,23 +165,26 @@ func (co *BBoltCache[V]) Set(ctx context.Context, key string, val V, timeout tim
 	bucketName := co.getDefaultBucket()
 	storeKey := co.buildKey(key)
 
-	var expiresAt int64
-	if timeout > 0 {
-		expiresAt = time.Now().Add(timeout).UnixNano()
-	}
-
 	stored := boltStoredValue{
 		Data:      conv.String(val),
-		ExpiresAt: expiresAt,
+		ExpiresAt: expiresAtOf(timeout, time.Now()),
+		TTL:       int64(timeout),
+		Version:   1,
 	}
 
-	data := conv.String(stored)
 	err := co.db.Update(func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(bucketName))
 		if b == nil {
 			return fmt.Errorf("bucket %s not found", bucketName)
 		}
-		return b.Put([]byte(storeKey), []byte(data))
+		old, ok, err := getBoltStoredValue(b, storeKey)
+		if err != nil {
+			return err
+		}
+		if ok {
+			stored.Version = boltVersion(old) + 1
+		}
+		return b.Put([]byte(storeKey), []byte(conv.String(stored)))
 	})
 
 	if err != nil {
@@ -207,6 +215,645 @@ func (co *BBoltCache[V]) Del(ctx context.Context, key string) (bool, error) {
 	return true, nil
 }
 
+// TTL 返回剩余有效期
+func (co *BBoltCache[V]) TTL(ctx context.Context, key string) (time.Duration, error) {
+	if co.isClosed() {
+		return TTLNotFound, errDBClosed
+	}
+	bucketName := co.getDefaultBucket()
+	storeKey := co.buildKey(key)
+
+	ttl := TTLNotFound
+	err := co.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketName))
+		if b == nil {
+			return fmt.Errorf("bucket %s not found", bucketName)
+		}
+		stored, ok, err := getBoltStoredValue(b, storeKey)
+		if err != nil || !ok {
+			return err
+		}
+		ttl = ttlOf(stored.ExpiresAt, time.Now())
+		return nil
+	})
+	return ttl, err
+}
+
+// Expire 重新设置过期时间，ttl<=0 表示永不过期
+func (co *BBoltCache[V]) Expire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	return co.resetExpire(key, func(stored *boltStoredValue) time.Duration {
+		return ttl
+	})
+}
+
+// Touch 按写入时的时长续期
+func (co *BBoltCache[V]) Touch(ctx context.Context, key string) (bool, error) {
+	return co.resetExpire(key, func(stored *boltStoredValue) time.Duration {
+		return time.Duration(stored.TTL)
+	})
+}
+
+// resetExpire 在一个写事务中读出未过期的值并按 ttlFn 的结果重新计算过期时间
+func (co *BBoltCache[V]) resetExpire(key string, ttlFn func(stored *boltStoredValue) time.Duration) (bool, error) {
+	if co.isClosed() {
+		return false, errDBClosed
+	}
+	bucketName := co.getDefaultBucket()
+	storeKey := co.buildKey(key)
+
+	found := false
+	err := co.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketName))
+		if b == nil {
+			return fmt.Errorf("bucket %s not found", bucketName)
+		}
+		stored, ok, err := getBoltStoredValue(b, storeKey)
+		if err != nil || !ok {
+			return err
+		}
+		ttl := ttlFn(stored)
+		stored.ExpiresAt = expiresAtOf(ttl, time.Now())
+		stored.TTL = int64(ttl)
+		found = true
+		return b.Put([]byte(storeKey), []byte(conv.String(*stored)))
+	})
+	if err != nil {
+		return false, err
+	}
+	return found, nil
+}
+
+// IncrBy 在一个写事务中读改写，原子增加计数，已过期的 key 从 0 开始重新计数
+func (co *BBoltCache[V]) IncrBy(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	if co.isClosed() {
+		return 0, errDBClosed
+	}
+	bucketName := co.getDefaultBucket()
+	storeKey := co.buildKey(key)
+
+	var counter int64
+	err := co.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketName))
+		if b == nil {
+			return fmt.Errorf("bucket %s not found", bucketName)
+		}
+		stored, ok, err := getBoltStoredValue(b, storeKey)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			stored = &boltStoredValue{
+				ExpiresAt: expiresAtOf(ttl, time.Now()),
+				TTL:       int64(ttl),
+			}
+		} else if counter, err = conv.Convert[int64](stored.Data); err != nil {
+			return fmt.Errorf("value of %s is not a counter: %w", key, err)
+		}
+		counter += delta
+		stored.Data = conv.String(counter)
+		return b.Put([]byte(storeKey), []byte(conv.String(*stored)))
+	})
+	if err != nil {
+		return 0, err
+	}
+	return counter, nil
+}
+
+// DecrBy 原子减少计数
+func (co *BBoltCache[V]) DecrBy(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	return co.IncrBy(ctx, key, -delta, ttl)
+}
+
+func (co *BBoltCache[V]) nsGenerationCache() *nsGenerationCache {
+	return &co.nsGens
+}
+
+// GetCounter 获取计数，不存在或已过期返回 0
+func (co *BBoltCache[V]) GetCounter(ctx context.Context, key string) (int64, error) {
+	if co.isClosed() {
+		return 0, errDBClosed
+	}
+	bucketName := co.getDefaultBucket()
+	storeKey := co.buildKey(key)
+
+	var counter int64
+	err := co.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketName))
+		if b == nil {
+			return fmt.Errorf("bucket %s not found", bucketName)
+		}
+		stored, ok, err := getBoltStoredValue(b, storeKey)
+		if err != nil || !ok {
+			return err
+		}
+		counter, err = conv.Convert[int64](stored.Data)
+		return err
+	})
+	return counter, err
+}
+
+// GetWithVersion 取值及其版本号
+func (co *BBoltCache[V]) GetWithVersion(ctx context.Context, key string) (v V, version int64, err error) {
+	if co.isClosed() {
+		return v, 0, errDBClosed
+	}
+	bucketName := co.getDefaultBucket()
+	storeKey := co.buildKey(key)
+
+	err = co.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketName))
+		if b == nil {
+			return fmt.Errorf("bucket %s not found", bucketName)
+		}
+		stored, ok, err := getBoltStoredValue(b, storeKey)
+		if err != nil || !ok {
+			return err
+		}
+		version = boltVersion(stored)
+		v, err = strToVal[V](stored.Data)
+		return err
+	})
+	return v, version, err
+}
+
+// CompareAndSet 在一个写事务中比较版本号，一致时写入并将版本号加 1
+func (co *BBoltCache[V]) CompareAndSet(ctx context.Context, key string, expectedVersion int64, val V, ttl time.Duration) (int64, error) {
+	if co.isClosed() {
+		return 0, errDBClosed
+	}
+	bucketName := co.getDefaultBucket()
+	storeKey := co.buildKey(key)
+
+	var current int64
+	err := co.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketName))
+		if b == nil {
+			return fmt.Errorf("bucket %s not found", bucketName)
+		}
+		stored, ok, err := getBoltStoredValue(b, storeKey)
+		if err != nil {
+			return err
+		}
+		if ok {
+			current = boltVersion(stored)
+		}
+		if current != expectedVersion {
+			return &ErrVersionConflict{Key: key, Expected: expectedVersion, Actual: current}
+		}
+		current++
+		newStored := boltStoredValue{
+			Data:      conv.String(val),
+			ExpiresAt: expiresAtOf(ttl, time.Now()),
+			TTL:       int64(ttl),
+			Version:   current,
+		}
+		return b.Put([]byte(storeKey), []byte(conv.String(newStored)))
+	})
+	return current, err
+}
+
+// Scan 按游标遍历以 prefix 开头的 key，bbolt 的 key 有序，直接用 cursor Seek 定位
+func (co *BBoltCache[V]) Scan(ctx context.Context, prefix string, cursor string, count int) ([]string, string, error) {
+	if co.isClosed() {
+		return nil, "", errDBClosed
+	}
+	if count <= 0 {
+		count = defaultScanCount
+	}
+	bucketName := co.getDefaultBucket()
+	nsPrefix := co.buildKey("")
+	storePrefix := []byte(co.buildKey(prefix))
+	seekKey := storePrefix
+	if cursor != "" {
+		seekKey = []byte(co.buildKey(cursor))
+	}
+
+	keys := make([]string, 0)
+	nextCursor := ""
+	now := time.Now().UnixNano()
+	err := co.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketName))
+		if b == nil {
+			return fmt.Errorf("bucket %s not found", bucketName)
+		}
+		c := b.Cursor()
+		for k, v := c.Seek(seekKey); k != nil && bytes.HasPrefix(k, storePrefix); k, v = c.Next() {
+			if cursor != "" && bytes.Equal(k, seekKey) {
+				continue
+			}
+			var stored boltStoredValue
+			if err := json.Unmarshal(v, &stored); err != nil {
+				continue
+			}
+			if stored.ExpiresAt > 0 && stored.ExpiresAt <= now {
+				continue
+			}
+			if len(keys) == count {
+				nextCursor = keys[count-1]
+				break
+			}
+			keys = append(keys, strings.TrimPrefix(string(k), nsPrefix))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return keys, nextCursor, nil
+}
+
+// Range 在一个读事务中遍历以 prefix 开头的未过期条目，fn 中不能再读写同一个 BBoltCache
+func (co *BBoltCache[V]) Range(ctx context.Context, prefix string, fn func(key string, val V, ttl time.Duration) bool) error {
+	if co.isClosed() {
+		return errDBClosed
+	}
+	bucketName := co.getDefaultBucket()
+	nsPrefix := co.buildKey("")
+	storePrefix := []byte(co.buildKey(prefix))
+	now := time.Now()
+	return co.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketName))
+		if b == nil {
+			return fmt.Errorf("bucket %s not found", bucketName)
+		}
+		c := b.Cursor()
+		for k, v := c.Seek(storePrefix); k != nil && bytes.HasPrefix(k, storePrefix); k, v = c.Next() {
+			var stored boltStoredValue
+			if err := json.Unmarshal(v, &stored); err != nil {
+				continue
+			}
+			ttl := ttlOf(stored.ExpiresAt, now)
+			if ttl == TTLNotFound {
+				continue
+			}
+			val, err := strToVal[V](stored.Data)
+			if err != nil {
+				continue
+			}
+			if !fn(strings.TrimPrefix(string(k), nsPrefix), val, ttl) {
+				return nil
+			}
+		}
+		return nil
+	})
+}
+
+// DeletePrefix 在一个写事务中删除以 prefix 开头的所有 key，返回删除的未过期 key 数量
+func (co *BBoltCache[V]) DeletePrefix(ctx context.Context, prefix string) (int64, error) {
+	if co.isClosed() {
+		return 0, errDBClosed
+	}
+	bucketName := co.getDefaultBucket()
+	storePrefix := []byte(co.buildKey(prefix))
+
+	var n int64
+	err := co.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketName))
+		if b == nil {
+			return fmt.Errorf("bucket %s not found", bucketName)
+		}
+		// 遍历时不能删除，先收集再删除，已过期的条目一并删除但不计数
+		var toDelete [][]byte
+		now := time.Now().UnixNano()
+		c := b.Cursor()
+		for k, v := c.Seek(storePrefix); k != nil && bytes.HasPrefix(k, storePrefix); k, v = c.Next() {
+			toDelete = append(toDelete, append([]byte(nil), k...))
+			var stored boltStoredValue
+			if err := json.Unmarshal(v, &stored); err != nil {
+				continue
+			}
+			if stored.ExpiresAt > 0 && stored.ExpiresAt <= now {
+				continue
+			}
+			n++
+		}
+		for _, k := range toDelete {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return n, err
+}
+
+// getTagBucket tag 索引所在的 bucket
+func (co *BBoltCache[V]) getTagBucket() string {
+	return co.getDefaultBucket() + "__tag"
+}
+
+// buildTagPrefix tag 索引条目的 key 前缀，条目的 key 为前缀加缓存的 key
+func (co *BBoltCache[V]) buildTagPrefix(tag string) []byte {
+	return []byte(co.buildKey(tag) + "\x00")
+}
+
+// AddTagKeys 在索引 bucket 中记录 key 属于 tags，值中的过期时间与 key 一致，由 cleanExpired 一并清理
+func (co *BBoltCache[V]) AddTagKeys(ctx context.Context, key string, ttl time.Duration, tags ...string) error {
+	if co.isClosed() {
+		return errDBClosed
+	}
+	stored := boltStoredValue{
+		ExpiresAt: expiresAtOf(ttl, time.Now()),
+	}
+	data := []byte(conv.String(stored))
+	return co.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(co.getTagBucket()))
+		if err != nil {
+			return fmt.Errorf("create bucket %s failed: %w", co.getTagBucket(), err)
+		}
+		for _, tag := range tags {
+			if err = b.Put(append(co.buildTagPrefix(tag), key...), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// TagKeys 返回 tag 下未过期的 key
+func (co *BBoltCache[V]) TagKeys(ctx context.Context, tag string) ([]string, error) {
+	if co.isClosed() {
+		return nil, errDBClosed
+	}
+	tagPrefix := co.buildTagPrefix(tag)
+	now := time.Now().UnixNano()
+
+	keys := make([]string, 0)
+	err := co.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(co.getTagBucket()))
+		if b == nil {
+			return nil
+		}
+		c := b.Cursor()
+		for k, v := c.Seek(tagPrefix); k != nil && bytes.HasPrefix(k, tagPrefix); k, v = c.Next() {
+			var stored boltStoredValue
+			if err := json.Unmarshal(v, &stored); err != nil {
+				continue
+			}
+			if stored.ExpiresAt > 0 && stored.ExpiresAt <= now {
+				continue
+			}
+			keys = append(keys, string(k[len(tagPrefix):]))
+		}
+		return nil
+	})
+	return keys, err
+}
+
+// DelTags 删除 tags 的索引
+func (co *BBoltCache[V]) DelTags(ctx context.Context, tags ...string) error {
+	if co.isClosed() {
+		return errDBClosed
+	}
+	return co.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(co.getTagBucket()))
+		if b == nil {
+			return nil
+		}
+		for _, tag := range tags {
+			tagPrefix := co.buildTagPrefix(tag)
+			var toDelete [][]byte
+			c := b.Cursor()
+			for k, _ := c.Seek(tagPrefix); k != nil && bytes.HasPrefix(k, tagPrefix); k, _ = c.Next() {
+				toDelete = append(toDelete, append([]byte(nil), k...))
+			}
+			for _, k := range toDelete {
+				if err := b.Delete(k); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// DelTagKeys 从 tag 的索引中删除指定的 key
+func (co *BBoltCache[V]) DelTagKeys(ctx context.Context, tag string, keys ...string) error {
+	if co.isClosed() {
+		return errDBClosed
+	}
+	return co.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(co.getTagBucket()))
+		if b == nil {
+			return nil
+		}
+		for _, key := range keys {
+			if err := b.Delete(append(co.buildTagPrefix(tag), key...)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// getHashBucket hash 所在的 bucket，每个 key 对应一个嵌套的子 bucket
+func (co *BBoltCache[V]) getHashBucket() string {
+	return co.getDefaultBucket() + "__hash"
+}
+
+// boltHashMetaKey 子 bucket 中记录有效期的条目，field 不能与之相同
+var boltHashMetaKey = []byte("\x00meta")
+
+// HGet 取 field 的值，key 或 field 不存在时返回 ErrNotFound
+func (co *BBoltCache[V]) HGet(ctx context.Context, key, field string) (v V, err error) {
+	if co.isClosed() {
+		return v, errDBClosed
+	}
+	storeKey := co.buildKey(key)
+	err = co.db.View(func(tx *bolt.Tx) error {
+		hb := getBoltHash(tx.Bucket([]byte(co.getHashBucket())), storeKey)
+		if hb == nil {
+			return ErrNotFound
+		}
+		data := hb.Get([]byte(field))
+		if data == nil {
+			return ErrNotFound
+		}
+		v, err = strToVal[V](string(data))
+		return err
+	})
+	return v, err
+}
+
+// HSet 写入 field 并重置整个 key 的有效期
+func (co *BBoltCache[V]) HSet(ctx context.Context, key, field string, val V, ttl time.Duration) (bool, error) {
+	return co.HMSet(ctx, key, map[string]V{field: val}, ttl)
+}
+
+// HMSet 在一个写事务中批量写入 field，已过期的 key 先清空再写入
+func (co *BBoltCache[V]) HMSet(ctx context.Context, key string, values map[string]V, ttl time.Duration) (bool, error) {
+	if co.isClosed() {
+		return false, errDBClosed
+	}
+	if len(values) == 0 {
+		return false, nil
+	}
+	storeKey := []byte(co.buildKey(key))
+	meta := boltStoredValue{
+		ExpiresAt: expiresAtOf(ttl, time.Now()),
+		TTL:       int64(ttl),
+	}
+	err := co.db.Update(func(tx *bolt.Tx) error {
+		parent, err := tx.CreateBucketIfNotExists([]byte(co.getHashBucket()))
+		if err != nil {
+			return fmt.Errorf("create bucket %s failed: %w", co.getHashBucket(), err)
+		}
+		if hb := parent.Bucket(storeKey); hb != nil && isBoltHashExpired(hb, time.Now().UnixNano()) {
+			if err = parent.DeleteBucket(storeKey); err != nil {
+				return err
+			}
+		}
+		hb, err := parent.CreateBucketIfNotExists(storeKey)
+		if err != nil {
+			return fmt.Errorf("create hash %s failed: %w", key, err)
+		}
+		for field, val := range values {
+			if err = hb.Put([]byte(field), []byte(conv.String(val))); err != nil {
+				return err
+			}
+		}
+		return hb.Put(boltHashMetaKey, []byte(conv.String(meta)))
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// HDel 删除 fields，fields 为空时删除整个 key
+func (co *BBoltCache[V]) HDel(ctx context.Context, key string, fields ...string) (int64, error) {
+	if co.isClosed() {
+		return 0, errDBClosed
+	}
+	storeKey := co.buildKey(key)
+	var n int64
+	err := co.db.Update(func(tx *bolt.Tx) error {
+		parent := tx.Bucket([]byte(co.getHashBucket()))
+		hb := getBoltHash(parent, storeKey)
+		if hb == nil {
+			return nil
+		}
+		if len(fields) == 0 {
+			n = boltHashLen(hb)
+			return parent.DeleteBucket([]byte(storeKey))
+		}
+		for _, field := range fields {
+			if hb.Get([]byte(field)) == nil {
+				continue
+			}
+			if err := hb.Delete([]byte(field)); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// HGetAll 取出所有 field
+func (co *BBoltCache[V]) HGetAll(ctx context.Context, key string) (map[string]V, error) {
+	if co.isClosed() {
+		return nil, errDBClosed
+	}
+	storeKey := co.buildKey(key)
+	values := make(map[string]V)
+	err := co.db.View(func(tx *bolt.Tx) error {
+		hb := getBoltHash(tx.Bucket([]byte(co.getHashBucket())), storeKey)
+		if hb == nil {
+			return nil
+		}
+		return hb.ForEach(func(k, data []byte) error {
+			if data == nil || bytes.Equal(k, boltHashMetaKey) {
+				return nil
+			}
+			v, err := strToVal[V](string(data))
+			if err != nil {
+				return err
+			}
+			values[string(k)] = v
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// HLen field 的数量
+func (co *BBoltCache[V]) HLen(ctx context.Context, key string) (int64, error) {
+	if co.isClosed() {
+		return 0, errDBClosed
+	}
+	storeKey := co.buildKey(key)
+	var n int64
+	err := co.db.View(func(tx *bolt.Tx) error {
+		if hb := getBoltHash(tx.Bucket([]byte(co.getHashBucket())), storeKey); hb != nil {
+			n = boltHashLen(hb)
+		}
+		return nil
+	})
+	return n, err
+}
+
+// getBoltHash 取得未过期的 hash 子 bucket
+func getBoltHash(parent *bolt.Bucket, storeKey string) *bolt.Bucket {
+	if parent == nil {
+		return nil
+	}
+	hb := parent.Bucket([]byte(storeKey))
+	if hb == nil || isBoltHashExpired(hb, time.Now().UnixNano()) {
+		return nil
+	}
+	return hb
+}
+
+// isBoltHashExpired hash 是否已过期
+func isBoltHashExpired(hb *bolt.Bucket, now int64) bool {
+	var meta boltStoredValue
+	if err := json.Unmarshal(hb.Get(boltHashMetaKey), &meta); err != nil {
+		return false
+	}
+	return meta.ExpiresAt > 0 && meta.ExpiresAt <= now
+}
+
+// boltHashLen 统计 field 数量，不含有效期条目
+func boltHashLen(hb *bolt.Bucket) int64 {
+	var n int64
+	c := hb.Cursor()
+	for k, _ := c.First(); k != nil; k, _ = c.Next() {
+		if !bytes.Equal(k, boltHashMetaKey) {
+			n++
+		}
+	}
+	return n
+}
+
+// boltVersion 计数器等不经过 Set 写入的值没有版本号，按 1 处理，版本号 0 只表示不存在
+func boltVersion(stored *boltStoredValue) int64 {
+	if stored.Version <= 0 {
+		return 1
+	}
+	return stored.Version
+}
+
+// getBoltStoredValue 读取未过期的存储值
+func getBoltStoredValue(b *bolt.Bucket, storeKey string) (*boltStoredValue, bool, error) {
+	data := b.Get([]byte(storeKey))
+	if data == nil {
+		return nil, false, nil
+	}
+	var stored boltStoredValue
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, false, fmt.Errorf("unmarshal stored value failed: %w", err)
+	}
+	if stored.ExpiresAt > 0 && time.Now().UnixNano() > stored.ExpiresAt {
+		return nil, false, nil
+	}
+	return &stored, true, nil
+}
+
 // cleanExpiredLoop 后台定期清理过期数据
 func (co *BBoltCache[V]) cleanExpiredLoop() {
 	ticker := time.NewTicker(co.config.RefreshDuration)
@@ -222,34 +869,52 @@ func (co *BBoltCache[V]) cleanExpiredLoop() {
 	}
 }
 
-// cleanExpired 清理当前 bucket 中的过期数据
+// cleanExpired 清理当前 bucket 及 tag 索引中的过期数据
 func (co *BBoltCache[V]) cleanExpired() {
 	if co.isClosed() {
 		return
 	}
 	now := time.Now().UnixNano()
-	bucketName := co.getDefaultBucket()
 
 	_ = co.db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(bucketName))
-		if b == nil {
-			return nil
-		}
-
-		c := b.Cursor()
-		var toDelete [][]byte
-		for k, v := c.First(); k != nil; k, v = c.Next() {
-			var stored boltStoredValue
-			if err := json.Unmarshal(v, &stored); err != nil {
+		for _, bucketName := range []string{co.getDefaultBucket(), co.getTagBucket()} {
+			b := tx.Bucket([]byte(bucketName))
+			if b == nil {
 				continue
 			}
-			if stored.ExpiresAt > 0 && stored.ExpiresAt <= now {
-				toDelete = append(toDelete, k)
+
+			c := b.Cursor()
+			var toDelete [][]byte
+			for k, v := c.First(); k != nil; k, v = c.Next() {
+				var stored boltStoredValue
+				if err := json.Unmarshal(v, &stored); err != nil {
+					continue
+				}
+				if stored.ExpiresAt > 0 && stored.ExpiresAt <= now {
+					toDelete = append(toDelete, k)
+				}
+			}
+
+			for _, k := range toDelete {
+				_ = b.Delete(k)
 			}
 		}
 
-		for _, k := range toDelete {
-			_ = b.Delete(k)
+		// 过期的 hash 整个子 bucket 删除
+		if b := tx.Bucket([]byte(co.getHashBucket())); b != nil {
+			var toDelete [][]byte
+			c := b.Cursor()
+			for k, v := c.First(); k != nil; k, v = c.Next() {
+				if v != nil {
+					continue
+				}
+				if hb := b.Bucket(k); hb != nil && isBoltHashExpired(hb, now) {
+					toDelete = append(toDelete, k)
+				}
+			}
+			for _, k := range toDelete {
+				_ = b.DeleteBucket(k)
+			}
 		}
 		return nil
 	})
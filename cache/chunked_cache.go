@@ -0,0 +1,252 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/magic-lib/go-plat-utils/conv"
+	"hash/crc32"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultChunkSize = 32 * 1024 // 编码后约 43KB，低于 fastcache 的 64KB 限制
+	maxChunkBaseLen  = 200       // 分片 key 中原 key 部分的最大长度，加上分片后缀不超过 mysql cache_key 的 255
+
+	chunkInlinePrefix   = "="
+	chunkManifestPrefix = "#"
+)
+
+// ChunkConfig 大值分片配置，零值字段使用默认值
+type ChunkConfig struct {
+	Threshold int // 序列化后超过该长度时分片，默认同 ChunkSize
+	ChunkSize int // 每个分片的原始长度，默认 32KB
+}
+
+// chunkManifest 分片清单，保存在原 key 下
+type chunkManifest struct {
+	gen   string // 每次写入不同，避免新旧分片混在一起
+	count int
+	size  int
+	sum   uint32
+}
+
+func (m *chunkManifest) String() string {
+	return fmt.Sprintf("%s%s.%d.%d.%08x", chunkManifestPrefix, m.gen, m.count, m.size, m.sum)
+}
+
+func parseChunkManifest(s string) (*chunkManifest, error) {
+	parts := strings.Split(strings.TrimPrefix(s, chunkManifestPrefix), ".")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("chunked cache: invalid manifest %q", s)
+	}
+	m := &chunkManifest{gen: parts[0]}
+	var err error
+	if m.count, err = strconv.Atoi(parts[1]); err != nil || m.count <= 0 {
+		return nil, fmt.Errorf("chunked cache: invalid manifest %q", s)
+	}
+	if m.size, err = strconv.Atoi(parts[2]); err != nil {
+		return nil, fmt.Errorf("chunked cache: invalid manifest %q", s)
+	}
+	sum, err := strconv.ParseUint(parts[3], 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("chunked cache: invalid manifest %q", s)
+	}
+	m.sum = uint32(sum)
+	return m, nil
+}
+
+// chunkedCache 大值分片装饰器，超过阈值的值拆成多个分片 key，原 key 下保存分片清单。
+// 写入的内容都是带引号的 ASCII 字符串，可以直接保存到 mysql 的 JSON 列
+type chunkedCache[V any] struct {
+	cCache    CommCache[string]
+	threshold int
+	chunkSize int
+}
+
+// NewChunkedCache 为缓存增加大值分片，读取时任一分片缺失或校验和不一致都按不存在处理，返回 ErrNotFound
+func NewChunkedCache[V any](cCache CommCache[string], cfg *ChunkConfig) CommCache[V] {
+	cc := &chunkedCache[V]{
+		cCache: cCache,
+	}
+	if cfg != nil {
+		cc.threshold = cfg.Threshold
+		cc.chunkSize = cfg.ChunkSize
+	}
+	if cc.chunkSize <= 0 {
+		cc.chunkSize = defaultChunkSize
+	}
+	if cc.threshold <= 0 {
+		cc.threshold = cc.chunkSize
+	}
+	return cc
+}
+
+// chunkKey 第 i 个分片的 key，原 key 带 hash tag 时分片在 redis 集群和分片缓存中落在同一个节点
+func chunkKey(key, gen string, i int) string {
+	return fmt.Sprintf("%s#chunk:%s:%d", chunkKeyBase(key), gen, i)
+}
+
+// chunkKeyBase 过长的 key 用摘要代替，保留不太长的 hash tag
+func chunkKeyBase(key string) string {
+	if len(key) <= maxChunkBaseLen {
+		return key
+	}
+	sum := sha1.Sum([]byte(key))
+	base := hex.EncodeToString(sum[:])
+	if tag := redisHashTag(key); tag != "" && len(tag)+2+len(base) <= maxChunkBaseLen {
+		base = "{" + tag + "}" + base
+	}
+	return base
+}
+
+// quoteChunk 保存为 JSON 字符串，内容只有 base64 字符和前缀，不需要转义
+func quoteChunk(s string) string {
+	return `"` + s + `"`
+}
+
+// unquoteChunk 后端反序列化时可能已经去掉了引号
+func unquoteChunk(s string) string {
+	return strings.Trim(s, `"`)
+}
+
+// getManifest 取原 key 下的内容，小值直接返回，大值返回分片清单
+func (cc *chunkedCache[V]) getManifest(ctx context.Context, key string) (string, *chunkManifest, error) {
+	raw, err := cc.cCache.Get(ctx, key)
+	if err != nil {
+		return "", nil, err
+	}
+	raw = unquoteChunk(raw)
+	if raw == "" {
+		return "", nil, nil
+	}
+	if strings.HasPrefix(raw, chunkInlinePrefix) {
+		data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(raw, chunkInlinePrefix))
+		if err != nil {
+			return "", nil, fmt.Errorf("chunked cache: decode value error: %w", err)
+		}
+		return string(data), nil, nil
+	}
+	m, err := parseChunkManifest(raw)
+	if err != nil {
+		return "", nil, err
+	}
+	return "", m, nil
+}
+
+// getChunks 读取并拼接所有分片
+func (cc *chunkedCache[V]) getChunks(ctx context.Context, key string, m *chunkManifest) (string, error) {
+	var buf strings.Builder
+	buf.Grow(m.size)
+	for i := 0; i < m.count; i++ {
+		raw, err := cc.cCache.Get(ctx, chunkKey(key, m.gen, i))
+		if err != nil || unquoteChunk(raw) == "" {
+			return "", ErrNotFound
+		}
+		data, err := base64.StdEncoding.DecodeString(unquoteChunk(raw))
+		if err != nil {
+			return "", ErrNotFound
+		}
+		buf.Write(data)
+	}
+	if buf.Len() != m.size || crc32.ChecksumIEEE([]byte(buf.String())) != m.sum {
+		return "", ErrNotFound
+	}
+	return buf.String(), nil
+}
+
+// delChunks 删除分片，忽略不存在的分片
+func (cc *chunkedCache[V]) delChunks(ctx context.Context, key string, m *chunkManifest) error {
+	var retErr error
+	for i := 0; i < m.count; i++ {
+		if _, err := cc.cCache.Del(ctx, chunkKey(key, m.gen, i)); err != nil && retErr == nil {
+			retErr = err
+		}
+	}
+	return retErr
+}
+
+// Get 取值，大值读取所有分片后校验
+func (cc *chunkedCache[V]) Get(ctx context.Context, key string) (V, error) {
+	var zero V
+	valueStr, m, err := cc.getManifest(ctx, key)
+	if err != nil {
+		return zero, err
+	}
+	if m != nil {
+		if valueStr, err = cc.getChunks(ctx, key, m); err != nil {
+			return zero, err
+		}
+	}
+	if valueStr == "" {
+		return zero, nil
+	}
+	return strToVal[V](valueStr)
+}
+
+// Set 超过阈值时先写分片再写清单，成功后删除旧的分片。
+// 并发写入同一个 key 时，写完清单后再读一次，清单已被其他写入覆盖则删除自己的分片，
+// 极端情况下残留的分片与清单的有效期相同，到期后自动清理
+func (cc *chunkedCache[V]) Set(ctx context.Context, key string, val V, timeout time.Duration) (bool, error) {
+	valueStr := conv.String(val)
+	if len(valueStr) <= cc.threshold {
+		_, old, _ := cc.getManifest(ctx, key)
+		ok, err := cc.cCache.Set(ctx, key, quoteChunk(chunkInlinePrefix+base64.StdEncoding.EncodeToString([]byte(valueStr))), timeout)
+		if err == nil && old != nil {
+			_ = cc.delChunks(ctx, key, old)
+		}
+		return ok, err
+	}
+
+	m := &chunkManifest{
+		gen:  strconv.FormatInt(time.Now().UnixNano(), 36) + strconv.FormatUint(uint64(rand.Uint32()), 36),
+		size: len(valueStr),
+		sum:  crc32.ChecksumIEEE([]byte(valueStr)),
+	}
+	m.count = (m.size + cc.chunkSize - 1) / cc.chunkSize
+	for i := 0; i < m.count; i++ {
+		end := min((i+1)*cc.chunkSize, m.size)
+		chunk := base64.StdEncoding.EncodeToString([]byte(valueStr[i*cc.chunkSize : end]))
+		if _, err := cc.cCache.Set(ctx, chunkKey(key, m.gen, i), quoteChunk(chunk), timeout); err != nil {
+			_ = cc.delChunks(ctx, key, m)
+			return false, err
+		}
+	}
+	// 写完分片后再读旧的清单，尽量拿到写入清单前最新的一份
+	_, old, _ := cc.getManifest(ctx, key)
+	ok, err := cc.cCache.Set(ctx, key, quoteChunk(m.String()), timeout)
+	if err != nil {
+		_ = cc.delChunks(ctx, key, m)
+		return false, err
+	}
+	_, cur, err := cc.getManifest(ctx, key)
+	if errors.Is(err, ErrNotFound) || (err == nil && (cur == nil || cur.gen != m.gen)) {
+		_ = cc.delChunks(ctx, key, m)
+		return ok, nil
+	}
+	if old != nil {
+		_ = cc.delChunks(ctx, key, old)
+	}
+	return ok, nil
+}
+
+// Del 先删除清单再删除分片
+func (cc *chunkedCache[V]) Del(ctx context.Context, key string) (bool, error) {
+	_, m, _ := cc.getManifest(ctx, key)
+	ok, err := cc.cCache.Del(ctx, key)
+	if err != nil {
+		return ok, err
+	}
+	if m != nil {
+		if err = cc.delChunks(ctx, key, m); err != nil {
+			return ok, err
+		}
+	}
+	return ok, nil
+}
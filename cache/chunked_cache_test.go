@@ -0,0 +1,112 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"github.com/magic-lib/go-plat-cache/cache"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestChunkedCache(t *testing.T) {
+	ctx := context.Background()
+	backend := cache.NewMemGoCache[string](time.Minute, time.Minute)
+	cc := cache.NewChunkedCache[string](backend, &cache.ChunkConfig{ChunkSize: 100})
+
+	small := "hello"
+	if _, err := cc.Set(ctx, "small", small, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := cc.Get(ctx, "small"); err != nil || v != small {
+		t.Fatalf("Get small got %q, %v", v, err)
+	}
+
+	big := strings.Repeat("大值abc", 100)
+	if _, err := cc.Set(ctx, "{ns}big", big, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := cc.Get(ctx, "{ns}big"); err != nil || v != big {
+		t.Fatalf("Get big got %d bytes, %v", len(v), err)
+	}
+	keys, _, _ := backend.(cache.ScannableCache).Scan(ctx, "{ns}big#chunk:", "", 100)
+	if len(keys) != (len(big)+99)/100 {
+		t.Fatalf("got %d chunks", len(keys))
+	}
+
+	// 覆盖写入后旧的分片被删除
+	if _, err := cc.Set(ctx, "{ns}big", big+"!", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	keys, _, _ = backend.(cache.ScannableCache).Scan(ctx, "{ns}big#chunk:", "", 100)
+	if len(keys) != (len(big)+100)/100 {
+		t.Fatalf("got %d chunks after overwrite", len(keys))
+	}
+
+	// 缺少分片按不存在处理
+	_, _ = backend.Del(ctx, keys[0])
+	if _, err := cc.Get(ctx, "{ns}big"); !errors.Is(err, cache.ErrNotFound) {
+		t.Fatalf("Get with missing chunk got %v", err)
+	}
+
+	if _, err := cc.Del(ctx, "{ns}big"); err != nil {
+		t.Fatal(err)
+	}
+	keys, _, _ = backend.(cache.ScannableCache).Scan(ctx, "{ns}big", "", 100)
+	if len(keys) != 0 {
+		t.Fatalf("keys left after Del: %v", keys)
+	}
+}
+
+func TestChunkedCacheConcurrentSet(t *testing.T) {
+	ctx := context.Background()
+	backend := cache.NewMemGoCache[string](time.Minute, time.Minute)
+	cc := cache.NewChunkedCache[string](backend, &cache.ChunkConfig{ChunkSize: 100})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, _ = cc.Set(ctx, "{ns}big", strings.Repeat(strconv.Itoa(i%10), 1000), time.Minute)
+		}(i)
+	}
+	wg.Wait()
+
+	// 只保留最后写入的清单对应的分片
+	v, err := cc.Get(ctx, "{ns}big")
+	if err != nil || len(v) != 1000 {
+		t.Fatalf("Get got %d bytes, %v", len(v), err)
+	}
+	keys, _, _ := backend.(cache.ScannableCache).Scan(ctx, "{ns}big#chunk:", "", 1000)
+	if len(keys) != 10 {
+		t.Fatalf("got %d chunks after concurrent Set", len(keys))
+	}
+}
+
+func TestChunkedCacheLongKey(t *testing.T) {
+	ctx := context.Background()
+	backend := cache.NewMemGoCache[string](time.Minute, time.Minute)
+	cc := cache.NewChunkedCache[string](backend, &cache.ChunkConfig{ChunkSize: 100})
+
+	key := "{ns}" + strings.Repeat("k", 250)
+	big := strings.Repeat("v", 1000)
+	if _, err := cc.Set(ctx, key, big, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := cc.Get(ctx, key); err != nil || v != big {
+		t.Fatalf("Get got %d bytes, %v", len(v), err)
+	}
+	// 分片 key 不超过 mysql cache_key 的长度，并保留 hash tag
+	keys, _, _ := backend.(cache.ScannableCache).Scan(ctx, "{ns}", "", 100)
+	for _, k := range keys {
+		if k != key && (len(k) > 255 || !strings.HasPrefix(k, "{ns}")) {
+			t.Fatalf("invalid chunk key %q", k)
+		}
+	}
+	if len(keys) != 11 {
+		t.Fatalf("got %d keys", len(keys))
+	}
+}
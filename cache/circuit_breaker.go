@@ -0,0 +1,274 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"sync"
+	"time"
+)
+
+// BreakerState 熔断器状态
+type BreakerState int32
+
+const (
+	BreakerClosed   BreakerState = iota // 关闭，正常访问
+	BreakerOpen                         // 打开，快速失败或使用降级缓存
+	BreakerHalfOpen                     // 半开，放行少量请求探测是否恢复
+)
+
+// String 状态名称
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// ErrBreakerOpen 熔断器打开，且没有降级缓存时返回
+var ErrBreakerOpen = errors.New("cache: circuit breaker is open")
+
+const (
+	defaultBreakerWindow           = 10 * time.Second
+	defaultBreakerMinRequests      = 20
+	defaultBreakerErrorRate        = 0.5
+	defaultBreakerSlowRate         = 0.5
+	defaultBreakerOpenTimeout      = 5 * time.Second
+	defaultBreakerHalfOpenRequests = 3
+)
+
+// BreakerConfig 熔断器配置，零值字段使用默认值
+type BreakerConfig struct {
+	Name             string                                   // 名称，状态变化事件中使用
+	Window           time.Duration                            // 统计窗口，默认 10s
+	MinRequests      int                                      // 窗口内请求数达到后才判断是否打开，默认 20
+	ErrorRate        float64                                  // 错误率阈值，默认 0.5
+	SlowThreshold    time.Duration                            // 耗时超过该值计为慢请求，<=0 不统计
+	SlowRate         float64                                  // 慢请求比例阈值，默认 0.5
+	OpenTimeout      time.Duration                            // 打开后多久进入半开，默认 5s
+	HalfOpenRequests int                                      // 半开时放行的探测请求数，全部成功后关闭，默认 3
+	IsFailure        func(err error) bool                     // 是否计为失败，默认 key 不存在不算失败
+	OnStateChange    func(name string, from, to BreakerState) // 状态变化事件，同步调用，不要阻塞
+}
+
+// CircuitBreaker 熔断器，按窗口统计错误率和慢请求比例，超过阈值后打开，
+// 打开 OpenTimeout 后进入半开，放行 HalfOpenRequests 个请求探测，全部成功则关闭，有失败则重新打开
+type CircuitBreaker struct {
+	cfg BreakerConfig
+
+	mu          sync.Mutex
+	state       BreakerState
+	windowStart time.Time
+	requests    int
+	failures    int
+	slows       int
+	openedAt    time.Time
+	probes      int // 半开时已放行的请求数
+	successes   int // 半开时成功的请求数
+}
+
+// NewCircuitBreaker 新建熔断器
+func NewCircuitBreaker(cfg *BreakerConfig) *CircuitBreaker {
+	b := &CircuitBreaker{}
+	if cfg != nil {
+		b.cfg = *cfg
+	}
+	if b.cfg.Window <= 0 {
+		b.cfg.Window = defaultBreakerWindow
+	}
+	if b.cfg.MinRequests <= 0 {
+		b.cfg.MinRequests = defaultBreakerMinRequests
+	}
+	if b.cfg.ErrorRate <= 0 {
+		b.cfg.ErrorRate = defaultBreakerErrorRate
+	}
+	if b.cfg.SlowRate <= 0 {
+		b.cfg.SlowRate = defaultBreakerSlowRate
+	}
+	if b.cfg.OpenTimeout <= 0 {
+		b.cfg.OpenTimeout = defaultBreakerOpenTimeout
+	}
+	if b.cfg.HalfOpenRequests <= 0 {
+		b.cfg.HalfOpenRequests = defaultBreakerHalfOpenRequests
+	}
+	if b.cfg.IsFailure == nil {
+		b.cfg.IsFailure = isBreakerFailure
+	}
+	b.windowStart = time.Now()
+	return b
+}
+
+// isBreakerFailure key 不存在不算失败
+func isBreakerFailure(err error) bool {
+	return err != nil && !errors.Is(err, redis.Nil) && !errors.Is(err, ErrNotFound)
+}
+
+// State 当前状态，打开超过 OpenTimeout 时返回半开
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.checkOpenTimeout(time.Now())
+	return b.state
+}
+
+// Allow 是否放行请求，放行后需要调用 Done 记录结果
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.checkOpenTimeout(time.Now())
+	switch b.state {
+	case BreakerOpen:
+		return false
+	case BreakerHalfOpen:
+		if b.probes >= b.cfg.HalfOpenRequests {
+			return false
+		}
+		b.probes++
+	}
+	return true
+}
+
+// Done 记录请求结果和耗时
+func (b *CircuitBreaker) Done(err error, cost time.Duration) {
+	failed := b.cfg.IsFailure(err)
+	slow := b.cfg.SlowThreshold > 0 && cost >= b.cfg.SlowThreshold
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	switch b.state {
+	case BreakerHalfOpen:
+		if failed || slow {
+			b.setState(BreakerOpen, now)
+			return
+		}
+		b.successes++
+		if b.successes >= b.cfg.HalfOpenRequests {
+			b.setState(BreakerClosed, now)
+		}
+	case BreakerClosed:
+		if now.Sub(b.windowStart) > b.cfg.Window {
+			b.resetWindow(now)
+		}
+		b.requests++
+		if failed {
+			b.failures++
+		}
+		if slow {
+			b.slows++
+		}
+		if b.requests < b.cfg.MinRequests {
+			return
+		}
+		total := float64(b.requests)
+		if float64(b.failures)/total >= b.cfg.ErrorRate ||
+			(b.cfg.SlowThreshold > 0 && float64(b.slows)/total >= b.cfg.SlowRate) {
+			b.setState(BreakerOpen, now)
+		}
+	}
+}
+
+// checkOpenTimeout 打开超过 OpenTimeout 后进入半开，调用方需持有锁
+func (b *CircuitBreaker) checkOpenTimeout(now time.Time) {
+	if b.state == BreakerOpen && now.Sub(b.openedAt) >= b.cfg.OpenTimeout {
+		b.setState(BreakerHalfOpen, now)
+	}
+}
+
+// setState 切换状态并触发事件，调用方需持有锁
+func (b *CircuitBreaker) setState(state BreakerState, now time.Time) {
+	from := b.state
+	if from == state {
+		return
+	}
+	b.state = state
+	b.probes, b.successes = 0, 0
+	b.resetWindow(now)
+	if state == BreakerOpen {
+		b.openedAt = now
+	}
+	if b.cfg.OnStateChange != nil {
+		b.cfg.OnStateChange(b.cfg.Name, from, state)
+	}
+}
+
+func (b *CircuitBreaker) resetWindow(now time.Time) {
+	b.windowStart = now
+	b.requests, b.failures, b.slows = 0, 0, 0
+}
+
+// breakerCache 熔断装饰器，打开时快速失败或使用降级缓存
+type breakerCache[V any] struct {
+	cCache   CommCache[V]
+	breaker  *CircuitBreaker
+	fallback CommCache[V]
+}
+
+// NewBreakerCache 为缓存增加熔断，打开时读写 fallback，没有 fallback 时返回 ErrBreakerOpen。
+// 打开期间的删除只作用于 fallback，恢复后远程缓存中可能有旧数据，需要较短的有效期
+func NewBreakerCache[V any](cCache CommCache[V], cfg *BreakerConfig, fallback ...CommCache[V]) BreakerCache[V] {
+	bc := &breakerCache[V]{
+		cCache:  cCache,
+		breaker: NewCircuitBreaker(cfg),
+	}
+	for _, one := range fallback {
+		if one != nil {
+			bc.fallback = one
+			break
+		}
+	}
+	return bc
+}
+
+// State 熔断器状态
+func (bc *breakerCache[V]) State() BreakerState {
+	return bc.breaker.State()
+}
+
+// Get 从缓存中取得一个值
+func (bc *breakerCache[V]) Get(ctx context.Context, key string) (V, error) {
+	if !bc.breaker.Allow() {
+		if bc.fallback != nil {
+			return bc.fallback.Get(ctx, key)
+		}
+		var zero V
+		return zero, ErrBreakerOpen
+	}
+	start := time.Now()
+	v, err := bc.cCache.Get(ctx, key)
+	bc.breaker.Done(err, time.Since(start))
+	return v, err
+}
+
+// Set 设置缓存值
+func (bc *breakerCache[V]) Set(ctx context.Context, key string, val V, timeout time.Duration) (bool, error) {
+	if !bc.breaker.Allow() {
+		if bc.fallback != nil {
+			return bc.fallback.Set(ctx, key, val, timeout)
+		}
+		return false, ErrBreakerOpen
+	}
+	start := time.Now()
+	ok, err := bc.cCache.Set(ctx, key, val, timeout)
+	bc.breaker.Done(err, time.Since(start))
+	return ok, err
+}
+
+// Del 删除缓存值
+func (bc *breakerCache[V]) Del(ctx context.Context, key string) (bool, error) {
+	if !bc.breaker.Allow() {
+		if bc.fallback != nil {
+			return bc.fallback.Del(ctx, key)
+		}
+		return false, ErrBreakerOpen
+	}
+	start := time.Now()
+	ok, err := bc.cCache.Del(ctx, key)
+	bc.breaker.Done(err, time.Since(start))
+	return ok, err
+}
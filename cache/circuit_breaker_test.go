@@ -0,0 +1,117 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"github.com/magic-lib/go-plat-cache/cache"
+	"testing"
+	"time"
+)
+
+// flakyCache 按开关返回错误的缓存
+type flakyCache struct {
+	cache.CommCache[string]
+	down bool
+}
+
+func (f *flakyCache) Get(ctx context.Context, key string) (string, error) {
+	if f.down {
+		return "", errors.New("connection refused")
+	}
+	return f.CommCache.Get(ctx, key)
+}
+
+func (f *flakyCache) Del(ctx context.Context, key string) (bool, error) {
+	if f.down {
+		return false, errors.New("connection refused")
+	}
+	return f.CommCache.Del(ctx, key)
+}
+
+func TestBreakerCache(t *testing.T) {
+	ctx := context.Background()
+	remote := &flakyCache{CommCache: cache.NewMemGoCache[string](time.Minute, time.Minute)}
+	fallback := cache.NewMemGoCache[string](time.Minute, time.Minute)
+	_, _ = remote.Set(ctx, "k", "remote", time.Minute)
+	_, _ = fallback.Set(ctx, "k", "fallback", time.Minute)
+
+	events := make([]string, 0)
+	bc := cache.NewBreakerCache[string](remote, &cache.BreakerConfig{
+		MinRequests:      4,
+		OpenTimeout:      50 * time.Millisecond,
+		HalfOpenRequests: 2,
+		OnStateChange: func(name string, from, to cache.BreakerState) {
+			events = append(events, from.String()+"->"+to.String())
+		},
+	}, fallback)
+
+	remote.down = true
+	for i := 0; i < 4; i++ {
+		_, _ = bc.Get(ctx, "k")
+	}
+	if bc.State() != cache.BreakerOpen {
+		t.Fatalf("breaker should be open, got %s", bc.State())
+	}
+	if v, err := bc.Get(ctx, "k"); err != nil || v != "fallback" {
+		t.Errorf("open breaker should read fallback, got %q, %v", v, err)
+	}
+
+	remote.down = false
+	time.Sleep(60 * time.Millisecond)
+	if bc.State() != cache.BreakerHalfOpen {
+		t.Fatalf("breaker should be half-open, got %s", bc.State())
+	}
+	for i := 0; i < 2; i++ {
+		if v, _ := bc.Get(ctx, "k"); v != "remote" {
+			t.Errorf("half-open probe should read remote, got %q", v)
+		}
+	}
+	if bc.State() != cache.BreakerClosed {
+		t.Errorf("breaker should be closed, got %s", bc.State())
+	}
+	want := []string{"closed->open", "open->half-open", "half-open->closed"}
+	if len(events) != len(want) {
+		t.Fatalf("unexpected events %v", events)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("event %d: want %s, got %s", i, want[i], events[i])
+		}
+	}
+
+	noFallback := cache.NewBreakerCache[string](remote, &cache.BreakerConfig{MinRequests: 1})
+	remote.down = true
+	_, _ = noFallback.Get(ctx, "k")
+	if _, err := noFallback.Get(ctx, "k"); !errors.Is(err, cache.ErrBreakerOpen) {
+		t.Errorf("expected ErrBreakerOpen, got %v", err)
+	}
+}
+
+func TestDefaultCacheDelBreakerOpen(t *testing.T) {
+	ctx := context.Background()
+	cache.SetDefaultBreakerConfig(&cache.BreakerConfig{MinRequests: 2, OpenTimeout: 50 * time.Millisecond, HalfOpenRequests: 1})
+	defer cache.SetDefaultBreakerConfig(&cache.BreakerConfig{})
+
+	remote := &flakyCache{CommCache: cache.NewMemGoCache[string](time.Minute, time.Minute)}
+	dc := cache.New[string]("breaker-del", remote)
+	_, _ = dc.Set(ctx, "k", "old", time.Minute)
+
+	remote.down = true
+	for i := 0; i < 2; i++ {
+		_, _ = dc.Get(ctx, "other")
+	}
+	// 熔断打开时远程没有删除，Del 要返回错误
+	if _, err := dc.Del(ctx, "k"); !errors.Is(err, cache.ErrBreakerOpen) {
+		t.Fatalf("expected ErrBreakerOpen, got %v", err)
+	}
+
+	// 恢复后先补删，不能读回旧值
+	remote.down = false
+	time.Sleep(60 * time.Millisecond)
+	if v, _ := dc.Get(ctx, "k"); v != "" {
+		t.Errorf("deleted key should not be read back, got %q", v)
+	}
+	if v, _ := remote.Get(ctx, "{breaker-del}k"); v != "" {
+		t.Errorf("remote key should be deleted after recovery, got %q", v)
+	}
+}
@@ -0,0 +1,36 @@
+package cache_test
+
+import (
+	"context"
+	"github.com/magic-lib/go-plat-cache/cache"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemCounter(t *testing.T) {
+	ctx := context.Background()
+	counter := cache.NewMemCounter(0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = counter.IncrBy(ctx, "qps", 2, 100*time.Millisecond)
+		}()
+	}
+	wg.Wait()
+
+	if n, _ := counter.DecrBy(ctx, "qps", 10, 0); n != 190 {
+		t.Errorf("counter should be 190, got %d", n)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if n, _ := counter.GetCounter(ctx, "qps"); n != 0 {
+		t.Errorf("expired counter should be 0, got %d", n)
+	}
+	if n, _ := counter.IncrBy(ctx, "qps", 1, time.Second); n != 1 {
+		t.Errorf("expired counter should restart from 0, got %d", n)
+	}
+}
@@ -4,21 +4,39 @@ import (
 	"bytes"
 	"context"
 	"encoding/gob"
+	"errors"
+	"fmt"
 	"github.com/magic-lib/go-plat-utils/conv"
 	"log"
+	"strings"
+	"sync"
 	"time"
 )
 
 type defaultCache[T any] struct {
 	cCache     CommCache[string]
-	ns         string // 命名空间
-	isMemCache bool   //是否是默认的，避免重复提交
+	ns         string          // 命名空间
+	isMemCache bool            //是否是默认的，避免重复提交
+	breaker    *CircuitBreaker // 远程缓存的熔断器，打开时直接使用本地默认缓存
+	pendingDel sync.Map        // 远程删除失败(如熔断打开)的 key，恢复后读取前先补删，避免读回旧值
 }
 
 var (
-	defaultMemCache = NewMemGoCache[any](5*time.Minute, 10*time.Minute) //本地默认缓存
+	defaultMemCache      = NewMemGoCache[any](5*time.Minute, 10*time.Minute) //本地默认缓存
+	defaultBreakerConfig = &BreakerConfig{
+		OnStateChange: func(name string, from, to BreakerState) {
+			log.Printf("default cache circuit breaker %s: %s -> %s", name, from, to)
+		},
+	}
 )
 
+// SetDefaultBreakerConfig 设置 New 创建的缓存访问远程缓存时使用的熔断配置，只影响之后创建的缓存
+func SetDefaultBreakerConfig(cfg *BreakerConfig) {
+	if cfg != nil {
+		defaultBreakerConfig = cfg
+	}
+}
+
 // New 新建
 func New[T any](ns string, con ...CommCache[string]) CommCache[T] {
 	com := new(defaultCache[T])
@@ -28,6 +46,11 @@ func New[T any](ns string, con ...CommCache[string]) CommCache[T] {
 			if item != nil {
 				com.isMemCache = false
 				com.cCache = item
+				breakerCfg := *defaultBreakerConfig
+				if breakerCfg.Name == "" {
+					breakerCfg.Name = ns
+				}
+				com.breaker = NewCircuitBreaker(&breakerCfg)
 				return com
 			}
 		}
@@ -66,7 +89,17 @@ func (co *defaultCache[T]) getOne(ctx context.Context, key string) (T, error) {
 		return *new(T), err2
 	}
 
-	ret, err := co.cCache.Get(ctx, key)
+	if err := co.retryDel(ctx, key); err != nil {
+		return *new(T), err2
+	}
+	var ret string
+	err := co.remoteDo(func() (err error) {
+		ret, err = co.cCache.Get(ctx, key)
+		return err
+	})
+	if errors.Is(err, ErrBreakerOpen) {
+		return *new(T), err2
+	}
 	if err != nil {
 		log.Printf("default cache getOne: %v, %v", ret, err)
 		ret = "" //如果报错，则可以表示没有查到，redis没有连接上的情况
@@ -113,8 +146,12 @@ func (co *defaultCache[T]) setOne(ctx context.Context, key string, val T, timeou
 	var retError, err, err2 error
 	if co.cCache != nil { //如果获取成功，则不用默认的内存了
 		saveStr := encodeValue[T](val)
-		ret, err = co.cCache.Set(ctx, key, saveStr, timeout)
+		err = co.remoteDo(func() (err error) {
+			ret, err = co.cCache.Set(ctx, key, saveStr, timeout)
+			return err
+		})
 		if err == nil && ret {
+			co.pendingDel.Delete(key)
 			return true, nil
 		}
 	}
@@ -124,27 +161,192 @@ func (co *defaultCache[T]) setOne(ctx context.Context, key string, val T, timeou
 		retBool = ret2
 		retError = err2
 	} else {
-		log.Printf("default cache setOne: %v, %v", ret, err)
+		if !errors.Is(err, ErrBreakerOpen) {
+			log.Printf("default cache setOne: %v, %v", ret, err)
+		}
 		retBool = ret2 //如果报错，则redis没有连接上的情况,则利用memcache
 		retError = err2
 	}
 	return retBool, retError
 }
 
+// delOne 远程删除失败(包括熔断打开)时返回错误，并记下该 key，远程恢复后读取前先补删
 func (co *defaultCache[T]) delOne(ctx context.Context, key string) (bool, error) {
-	var retBool, ret, ret2 bool
-	var retError, err, err2 error
-	ret2, err2 = defaultMemCache.Del(ctx, key)
-	if co.cCache != nil {
+	var ret bool
+	var err error
+	ret2, err2 := defaultMemCache.Del(ctx, key)
+	if co.isMemCache || co.cCache == nil {
+		return ret2, err2
+	}
+	err = co.remoteDo(func() (err error) {
 		ret, err = co.cCache.Del(ctx, key)
+		return err
+	})
+	if err != nil {
+		if !errors.Is(err, ErrBreakerOpen) {
+			log.Printf("default cache delOne: %v, %v", ret, err)
+		}
+		co.pendingDel.Store(key, struct{}{})
+		return false, err
 	}
-	if co.isMemCache {
-		retBool = ret2
-		retError = err2
-	} else {
-		log.Printf("default cache delOne: %v, %v", ret, err)
-		retBool = ret2
-		retError = err2
+	co.pendingDel.Delete(key)
+	return ret2, err2
+}
+
+// retryDel 补删之前远程删除失败的 key，补删失败时不能读取远程，否则会读回已删除的旧值
+func (co *defaultCache[T]) retryDel(ctx context.Context, key string) error {
+	if _, ok := co.pendingDel.Load(key); !ok {
+		return nil
 	}
-	return retBool, retError
+	err := co.remoteDo(func() error {
+		_, err := co.cCache.Del(ctx, key)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	co.pendingDel.Delete(key)
+	return nil
+}
+
+// remoteDo 熔断器放行时访问远程缓存并记录结果，熔断打开时返回 ErrBreakerOpen
+func (co *defaultCache[T]) remoteDo(fn func() error) error {
+	if co.breaker == nil {
+		return fn()
+	}
+	if !co.breaker.Allow() {
+		return ErrBreakerOpen
+	}
+	start := time.Now()
+	err := fn()
+	co.breaker.Done(err, time.Since(start))
+	return err
+}
+
+// remoteAvailable 是否有远程缓存且熔断器未打开
+func (co *defaultCache[T]) remoteAvailable() bool {
+	if co.isMemCache || co.cCache == nil {
+		return false
+	}
+	return co.breaker == nil || co.breaker.State() != BreakerOpen
+}
+
+// expiringStores 返回支持过期操作的存储，远端优先，本地默认缓存兜底
+func (co *defaultCache[T]) expiringStores() []ExpiringCache {
+	stores := make([]ExpiringCache, 0, 2)
+	if co.remoteAvailable() {
+		if one, ok := co.cCache.(ExpiringCache); ok {
+			stores = append(stores, one)
+		}
+	}
+	if one, ok := defaultMemCache.(ExpiringCache); ok {
+		stores = append(stores, one)
+	}
+	return stores
+}
+
+// TTL 返回剩余有效期
+func (co *defaultCache[T]) TTL(ctx context.Context, key string) (time.Duration, error) {
+	key = getNsKey(co.ns, key)
+	var lastErr error
+	for _, one := range co.expiringStores() {
+		ttl, err := one.TTL(ctx, key)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if ttl != TTLNotFound {
+			return ttl, nil
+		}
+	}
+	return TTLNotFound, lastErr
+}
+
+// Expire 重新设置过期时间，远端和本地默认缓存同时设置
+func (co *defaultCache[T]) Expire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	key = getNsKey(co.ns, key)
+	return co.eachExpiring(func(one ExpiringCache) (bool, error) {
+		return one.Expire(ctx, key, ttl)
+	})
+}
+
+// Touch 按写入时的时长续期，远端和本地默认缓存同时续期
+func (co *defaultCache[T]) Touch(ctx context.Context, key string) (bool, error) {
+	key = getNsKey(co.ns, key)
+	return co.eachExpiring(func(one ExpiringCache) (bool, error) {
+		return one.Touch(ctx, key)
+	})
+}
+
+func (co *defaultCache[T]) eachExpiring(fn func(one ExpiringCache) (bool, error)) (bool, error) {
+	var retBool bool
+	var lastErr error
+	for _, one := range co.expiringStores() {
+		ret, err := fn(one)
+		if err != nil {
+			log.Printf("default cache expire: %v, %v", ret, err)
+			lastErr = err
+			continue
+		}
+		retBool = retBool || ret
+	}
+	if retBool {
+		return true, nil
+	}
+	return false, lastErr
+}
+
+// scannableStores 返回支持遍历的存储，远端优先，本地默认缓存兜底
+func (co *defaultCache[T]) scannableStores() []ScannableCache {
+	stores := make([]ScannableCache, 0, 2)
+	if co.remoteAvailable() {
+		if one, ok := co.cCache.(ScannableCache); ok {
+			stores = append(stores, one)
+		}
+	}
+	if one, ok := defaultMemCache.(ScannableCache); ok {
+		stores = append(stores, one)
+	}
+	return stores
+}
+
+// Scan 按游标遍历命名空间下以 prefix 开头的 key，返回的 key 不含命名空间
+func (co *defaultCache[T]) Scan(ctx context.Context, prefix string, cursor string, count int) ([]string, string, error) {
+	stores := co.scannableStores()
+	if len(stores) == 0 {
+		return nil, "", fmt.Errorf("default cache: no scannable store")
+	}
+	keys, next, err := stores[0].Scan(ctx, getNsKey(co.ns, prefix), cursor, count)
+	if err != nil {
+		return nil, "", err
+	}
+	nsPrefix := getNsKey(co.ns, "")
+	for i, key := range keys {
+		keys[i] = strings.TrimPrefix(key, nsPrefix)
+	}
+	return keys, next, nil
+}
+
+// DeletePrefix 删除命名空间下以 prefix 开头的所有 key，远端和本地默认缓存同时删除。
+// 本地默认缓存只是远端的副本，返回的数量以远端为准，远端不可用或删除失败时才用本地的数量
+func (co *defaultCache[T]) DeletePrefix(ctx context.Context, prefix string) (int64, error) {
+	var total int64
+	var lastErr error
+	counted := false
+	for _, one := range co.scannableStores() {
+		n, err := one.DeletePrefix(ctx, getNsKey(co.ns, prefix))
+		if err != nil {
+			log.Printf("default cache DeletePrefix: %v, %v", n, err)
+			lastErr = err
+			continue
+		}
+		if !counted {
+			total = n
+			counted = true
+		}
+	}
+	if counted {
+		return total, nil
+	}
+	return total, lastErr
 }
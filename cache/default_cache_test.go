@@ -3,8 +3,8 @@ package cache_test
 import (
 	"context"
 	"fmt"
-	"github.com/go-redis/redis/v8"
 	"github.com/magic-lib/go-plat-cache/cache"
+	"github.com/redis/go-redis/v9"
 	"github.com/samber/lo"
 	"testing"
 	"time"
@@ -9,12 +9,16 @@ import (
 	"github.com/peterbourgon/diskv"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 )
 
 type DataWithExpiry struct {
+	Key    string `json:",omitempty"` // 原始 key，文件名是 key 的摘要，遍历时需要用到
 	Data   string
 	Expiry time.Time
+	TTL    time.Duration `json:",omitempty"` // 写入时的有效期，用于 Touch 续期
 }
 
 const (
@@ -28,6 +32,7 @@ type diskCache[V any] struct {
 	diskCache     *diskcache.Cache
 	basePath      string
 	maxExpireTime time.Duration
+	mu            sync.Mutex // 保证读改写的原子性
 }
 
 // NewDiskCache 新建diskCache
@@ -153,37 +158,174 @@ func (co *diskCache[V]) cleanExpiredFiles(rootDir string, maxAge time.Duration)
 
 // Get 从缓存中取得一个值
 func (co *diskCache[V]) Get(_ context.Context, key string) (V, error) {
-	ret, ok := co.diskCache.Get(key)
 	var zero V
+	dataWithExpiryRead, ok, err := co.getData(key)
+	if err != nil || !ok {
+		return zero, err
+	}
+	return strToVal[V](dataWithExpiryRead.Data)
+}
+
+// Set timeout<=0 时使用最长过期时间
+func (co *diskCache[V]) Set(_ context.Context, key string, val V, timeout time.Duration) (bool, error) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	co.setData(key, conv.String(val), timeout)
+	return true, nil
+}
+
+// Del 从缓存中删除一个key
+func (co *diskCache[V]) Del(_ context.Context, key string) (bool, error) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	co.diskCache.Delete(key)
+	return true, nil
+}
+
+// TTL 返回剩余有效期
+func (co *diskCache[V]) TTL(_ context.Context, key string) (time.Duration, error) {
+	dataWithExpiryRead, ok, err := co.getData(key)
+	if err != nil || !ok {
+		return TTLNotFound, err
+	}
+	return ttlOf(dataWithExpiryRead.Expiry.UnixNano(), time.Now()), nil
+}
+
+// Expire 重新设置过期时间，ttl<=0 时使用最长过期时间
+func (co *diskCache[V]) Expire(_ context.Context, key string, ttl time.Duration) (bool, error) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	dataWithExpiryRead, ok, err := co.getData(key)
+	if err != nil || !ok {
+		return false, err
+	}
+	co.setData(key, dataWithExpiryRead.Data, ttl)
+	return true, nil
+}
+
+// Touch 按写入时的时长续期
+func (co *diskCache[V]) Touch(_ context.Context, key string) (bool, error) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	dataWithExpiryRead, ok, err := co.getData(key)
+	if err != nil || !ok {
+		return false, err
+	}
+	co.setData(key, dataWithExpiryRead.Data, dataWithExpiryRead.TTL)
+	return true, nil
+}
+
+// getData 读取未过期的数据，已过期的直接删除
+func (co *diskCache[V]) getData(key string) (*DataWithExpiry, bool, error) {
+	ret, ok := co.diskCache.Get(key)
 	if !ok {
-		return zero, nil
+		return nil, false, nil
 	}
 	var dataWithExpiryRead DataWithExpiry
 	err := conv.Unmarshal(ret, &dataWithExpiryRead)
 	if err != nil {
-		return zero, err
+		return nil, false, err
 	}
 	// 判断是否过期
 	if time.Now().After(dataWithExpiryRead.Expiry) {
 		co.diskCache.Delete(key)
-		return zero, nil
+		return nil, false, nil
 	}
-	return strToVal[V](dataWithExpiryRead.Data)
+	return &dataWithExpiryRead, true, nil
 }
 
-// Set timeout无效
-func (co *diskCache[V]) Set(_ context.Context, key string, val V, timeout time.Duration) (bool, error) {
+// setData 写入数据，调用方需持有锁
+func (co *diskCache[V]) setData(key string, data string, timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = co.maxExpireTime
+	}
 	dataWithExpiry := DataWithExpiry{
-		Data:   conv.String(val),
+		Key:    key,
+		Data:   data,
 		Expiry: time.Now().Add(timeout),
+		TTL:    timeout,
 	}
 	serialized := conv.String(dataWithExpiry)
 	co.diskCache.Set(key, []byte(serialized))
-	return true, nil
 }
 
-// Del 从缓存中删除一个key
-func (co *diskCache[V]) Del(_ context.Context, key string) (bool, error) {
-	co.diskCache.Delete(key)
-	return true, nil
+// Scan 按游标遍历以 prefix 开头的 key，需要遍历整个目录，老版本写入的文件没有记录 key，不会返回
+func (co *diskCache[V]) Scan(_ context.Context, prefix string, cursor string, count int) ([]string, string, error) {
+	keys, err := co.walkKeys(prefix)
+	if err != nil {
+		return nil, "", err
+	}
+	keys, next := scanSortedKeys(keys, prefix, cursor, count)
+	return keys, next, nil
+}
+
+// DeletePrefix 删除以 prefix 开头的所有 key
+func (co *diskCache[V]) DeletePrefix(_ context.Context, prefix string) (int64, error) {
+	keys, err := co.walkKeys(prefix)
+	if err != nil {
+		return 0, err
+	}
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	for _, key := range keys {
+		co.diskCache.Delete(key)
+	}
+	return int64(len(keys)), nil
+}
+
+// Range 遍历一次缓存目录，读出以 prefix 开头的未过期条目
+func (co *diskCache[V]) Range(_ context.Context, prefix string, fn func(key string, val V, ttl time.Duration) bool) error {
+	return co.walkData(prefix, func(data *DataWithExpiry) bool {
+		v, err := strToVal[V](data.Data)
+		if err != nil {
+			return true
+		}
+		ttl := ttlOf(data.Expiry.UnixNano(), time.Now())
+		if ttl == TTLNotFound {
+			return true
+		}
+		return fn(data.Key, v, ttl)
+	})
+}
+
+// walkKeys 遍历缓存目录，返回以 prefix 开头且未过期的 key
+func (co *diskCache[V]) walkKeys(prefix string) ([]string, error) {
+	keys := make([]string, 0)
+	err := co.walkData(prefix, func(data *DataWithExpiry) bool {
+		keys = append(keys, data.Key)
+		return true
+	})
+	return keys, err
+}
+
+// walkData 遍历缓存目录中以 prefix 开头且未过期的数据，fn 返回 false 时停止
+func (co *diskCache[V]) walkData(prefix string, fn func(data *DataWithExpiry) bool) error {
+	now := time.Now()
+	err := filepath.Walk(co.basePath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return fmt.Errorf("访问路径失败: %s, 错误: %w", path, err)
+		}
+		if info.IsDir() {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		var dataWithExpiryRead DataWithExpiry
+		if err = conv.Unmarshal(string(content), &dataWithExpiryRead); err != nil {
+			return nil
+		}
+		if dataWithExpiryRead.Key == "" || now.After(dataWithExpiryRead.Expiry) {
+			return nil
+		}
+		if strings.HasPrefix(dataWithExpiryRead.Key, prefix) && !fn(&dataWithExpiryRead) {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return err
 }
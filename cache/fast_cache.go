@@ -2,16 +2,22 @@ package cache
 
 import (
 	"context"
+	"encoding/binary"
 	"github.com/VictoriaMetrics/fastcache"
 	"github.com/magic-lib/go-plat-utils/conv"
+	"sync"
 	"time"
 )
 
+// fastCacheHeaderLen 值前缀占用的字节数：过期时间(8字节) + 写入时的有效期(8字节)
+const fastCacheHeaderLen = 16
+
 type fastCache[V any] struct {
 	mCache *fastcache.Cache
+	mu     sync.Mutex // 保证读改写的原子性
 }
 
-// NewFastCache 新建memGoCache
+// NewFastCache 新建fastCache
 func NewFastCache[V any](maxSize int) CommCache[V] {
 	if maxSize <= 1024 {
 		maxSize = 128 * 1024 * 1024
@@ -21,10 +27,46 @@ func NewFastCache[V any](maxSize int) CommCache[V] {
 	}
 }
 
+// encodeFastCacheValue 在值前加上过期时间(unix nano)和有效期，过期时间为 0 表示永不过期
+func encodeFastCacheValue(data []byte, timeout time.Duration) []byte {
+	buf := make([]byte, fastCacheHeaderLen+len(data))
+	binary.BigEndian.PutUint64(buf[:8], uint64(expiresAtOf(timeout, time.Now())))
+	binary.BigEndian.PutUint64(buf[8:fastCacheHeaderLen], uint64(timeout))
+	copy(buf[fastCacheHeaderLen:], data)
+	return buf
+}
+
+// decodeFastCacheValue 拆出过期时间、有效期和原始值
+func decodeFastCacheValue(buf []byte) (data []byte, expiresAt int64, timeout time.Duration, ok bool) {
+	if len(buf) < fastCacheHeaderLen {
+		return nil, 0, 0, false
+	}
+	expiresAt = int64(binary.BigEndian.Uint64(buf[:8]))
+	timeout = time.Duration(binary.BigEndian.Uint64(buf[8:fastCacheHeaderLen]))
+	return buf[fastCacheHeaderLen:], expiresAt, timeout, true
+}
+
+// getRaw 取出未过期的原始值
+func (co *fastCache[V]) getRaw(key string) (data []byte, expiresAt int64, timeout time.Duration, ok bool) {
+	buf := co.mCache.Get(nil, []byte(key))
+	data, expiresAt, timeout, ok = decodeFastCacheValue(buf)
+	if !ok {
+		return nil, 0, 0, false
+	}
+	if expiresAt > 0 && time.Now().UnixNano() > expiresAt {
+		co.mCache.Del([]byte(key))
+		return nil, 0, 0, false
+	}
+	return data, expiresAt, timeout, true
+}
+
 // Get 从缓存中取得一个值
 func (co *fastCache[V]) Get(_ context.Context, key string) (V, error) {
 	var zero V
-	data := co.mCache.Get(nil, []byte(key))
+	data, _, _, ok := co.getRaw(key)
+	if !ok {
+		return zero, nil
+	}
 	if len(data) == 0 {
 		return zero, nil
 	}
@@ -32,14 +74,51 @@ func (co *fastCache[V]) Get(_ context.Context, key string) (V, error) {
 	return conv.Convert[V](retString)
 }
 
-// Set timeout为秒，由于fastcache不支持过期时间，这里只是简单设置值
-func (co *fastCache[V]) Set(_ context.Context, key string, val V, _ time.Duration) (bool, error) {
-	co.mCache.Set([]byte(key), []byte(conv.String(val)))
+// Set timeout<=0 表示永不过期，fastcache 本身不支持过期时间，这里把过期时间存到值的前缀中
+func (co *fastCache[V]) Set(_ context.Context, key string, val V, timeout time.Duration) (bool, error) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	co.mCache.Set([]byte(key), encodeFastCacheValue([]byte(conv.String(val)), timeout))
 	return true, nil
 }
 
 // Del 从缓存中删除一个key
 func (co *fastCache[V]) Del(_ context.Context, key string) (bool, error) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
 	co.mCache.Del([]byte(key))
 	return true, nil
 }
+
+// TTL 返回剩余有效期
+func (co *fastCache[V]) TTL(_ context.Context, key string) (time.Duration, error) {
+	_, expiresAt, _, ok := co.getRaw(key)
+	if !ok {
+		return TTLNotFound, nil
+	}
+	return ttlOf(expiresAt, time.Now()), nil
+}
+
+// Expire 重新设置过期时间，ttl<=0 表示永不过期
+func (co *fastCache[V]) Expire(_ context.Context, key string, ttl time.Duration) (bool, error) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	data, _, _, ok := co.getRaw(key)
+	if !ok {
+		return false, nil
+	}
+	co.mCache.Set([]byte(key), encodeFastCacheValue(data, ttl))
+	return true, nil
+}
+
+// Touch 按写入时的时长续期
+func (co *fastCache[V]) Touch(_ context.Context, key string) (bool, error) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	data, _, timeout, ok := co.getRaw(key)
+	if !ok {
+		return false, nil
+	}
+	co.mCache.Set([]byte(key), encodeFastCacheValue(data, timeout))
+	return true, nil
+}
@@ -0,0 +1,67 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"github.com/magic-lib/go-plat-cache/cache"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type userSetting struct {
+	Theme string `json:"theme"`
+	Size  int    `json:"size"`
+}
+
+func TestHashCache(t *testing.T) {
+	ctx := context.Background()
+	boltCache, err := cache.NewBBoltCache[*userSetting](&cache.BBoltCacheConfig{
+		DbPath: filepath.Join(t.TempDir(), "hash.db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cacheList := map[string]cache.HashCache[*userSetting]{
+		"mem":  cache.NewMemHashCache[*userSetting](time.Minute),
+		"bolt": boltCache.(cache.HashCache[*userSetting]),
+	}
+	for name, hc := range cacheList {
+		_, _ = hc.HSet(ctx, "user:1", "web", &userSetting{Theme: "dark", Size: 12}, time.Minute)
+		_, _ = hc.HMSet(ctx, "user:1", map[string]*userSetting{
+			"app": {Theme: "light", Size: 14},
+			"pad": {Theme: "dark", Size: 16},
+		}, time.Minute)
+
+		if v, err := hc.HGet(ctx, "user:1", "app"); err != nil || v.Size != 14 {
+			t.Fatalf("%s: HGet got %v, %v", name, v, err)
+		}
+		if _, err = hc.HGet(ctx, "user:1", "tv"); !errors.Is(err, cache.ErrNotFound) {
+			t.Fatalf("%s: missing field should return ErrNotFound, got %v", name, err)
+		}
+		if n, _ := hc.HLen(ctx, "user:1"); n != 3 {
+			t.Fatalf("%s: HLen got %d", name, n)
+		}
+		if n, _ := hc.HDel(ctx, "user:1", "pad", "tv"); n != 1 {
+			t.Fatalf("%s: HDel got %d", name, n)
+		}
+		all, _ := hc.HGetAll(ctx, "user:1")
+		if len(all) != 2 || all["web"].Theme != "dark" {
+			t.Fatalf("%s: HGetAll got %v", name, all)
+		}
+		if n, _ := hc.HDel(ctx, "user:1"); n != 2 {
+			t.Fatalf("%s: HDel whole key got %d", name, n)
+		}
+
+		// 有效期作用于整个 key，过期后重新写入不会带回旧的 field
+		_, _ = hc.HSet(ctx, "user:2", "web", &userSetting{Size: 1}, 50*time.Millisecond)
+		time.Sleep(100 * time.Millisecond)
+		if n, _ := hc.HLen(ctx, "user:2"); n != 0 {
+			t.Fatalf("%s: expired hash should be empty, got %d", name, n)
+		}
+		_, _ = hc.HSet(ctx, "user:2", "app", &userSetting{Size: 2}, time.Minute)
+		if all, _ = hc.HGetAll(ctx, "user:2"); len(all) != 1 {
+			t.Fatalf("%s: expired fields should not come back, got %v", name, all)
+		}
+	}
+}
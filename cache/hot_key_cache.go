@@ -0,0 +1,361 @@
+package cache
+
+import (
+	"context"
+	"golang.org/x/sync/singleflight"
+	"hash/fnv"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultHotKeyWindow    = 10 * time.Second
+	defaultHotKeyThreshold = 1000
+	defaultHotKeyTopK      = 32
+	defaultHotKeyLocalTTL  = time.Second
+	defaultSketchWidth     = 2048
+	defaultSketchDepth     = 4
+)
+
+// HotKeyConfig 热点 key 探测配置，零值字段使用默认值
+type HotKeyConfig struct {
+	Window      time.Duration // 滑动窗口，默认 10s
+	Threshold   uint64        // 窗口内访问次数达到后提升到本地缓存，默认 1000
+	TopK        int           // 最多跟踪的候选 key 数，也是本地缓存的上限，默认 32
+	LocalTTL    time.Duration // 本地缓存有效期，决定多实例之间的最大不一致时间，默认 1s
+	SampleRate  float64       // 采样比例 (0,1]，按比例放大估算值，默认 1 即全部统计
+	SketchWidth int           // count-min sketch 每行的计数器数，默认 2048
+	SketchDepth int           // count-min sketch 的行数，默认 4
+}
+
+// HotKey 热点 key 及窗口内的估算访问次数
+type HotKey struct {
+	Key   string
+	Count uint64
+}
+
+// countMinSketch 只会高估不会低估的频率统计，计数器为原子操作，读写不需要加锁
+type countMinSketch struct {
+	width uint32
+	rows  [][]atomic.Uint32
+}
+
+func newCountMinSketch(width, depth int) *countMinSketch {
+	s := &countMinSketch{
+		width: uint32(width),
+		rows:  make([][]atomic.Uint32, depth),
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]atomic.Uint32, width)
+	}
+	return s
+}
+
+// sketchHash 由一次 64 位哈希拆出两个哈希，按 h1+i*h2 得到每一行的位置
+func sketchHash(key string) (uint32, uint32) {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	sum := h.Sum64()
+	return uint32(sum), uint32(sum>>32) | 1
+}
+
+func (s *countMinSketch) add(h1, h2 uint32) {
+	for i, row := range s.rows {
+		row[(h1+uint32(i)*h2)%s.width].Add(1)
+	}
+}
+
+func (s *countMinSketch) estimate(h1, h2 uint32) uint64 {
+	var est uint32
+	for i, row := range s.rows {
+		n := row[(h1+uint32(i)*h2)%s.width].Load()
+		if i == 0 || n < est {
+			est = n
+		}
+	}
+	return uint64(est)
+}
+
+func (s *countMinSketch) reset() {
+	for _, row := range s.rows {
+		for i := range row {
+			row[i].Store(0)
+		}
+	}
+}
+
+// hotEntry 已提升的热点 key，local 为本地缓存的值，未加载或已清除时为空
+type hotEntry[V any] struct {
+	count atomic.Uint64
+	local atomic.Pointer[hotValue[V]]
+}
+
+type hotValue[V any] struct {
+	val      V
+	expireAt time.Time
+}
+
+// hotKeyCache 热点 key 装饰器，用上一个窗口和当前窗口两个 sketch 按时间加权估算滑动窗口内的访问次数，
+// 超过阈值且在 TopK 内的 key 提升到本地缓存，降温后在窗口切换时移出。
+// 热点 key 表是只读快照，变化时复制后整体替换，读取、未采样的访问和热点 key 的访问都不需要加锁
+type hotKeyCache[V any] struct {
+	cCache CommCache[V]
+	cfg    HotKeyConfig
+	group  singleflight.Group
+
+	cur         atomic.Pointer[countMinSketch]
+	prev        atomic.Pointer[countMinSketch]
+	windowStart atomic.Int64 // 当前窗口的开始时间，UnixNano
+	hot         atomic.Pointer[map[string]*hotEntry[V]]
+
+	mu         sync.Mutex        // 保护窗口切换、候选 key 和热点 key 表的替换
+	candidates map[string]uint64 // TopK 候选 key 及最近一次的估算值
+}
+
+// NewHotKeyCache 为缓存增加热点 key 探测，热点 key 的读取在 LocalTTL 内只访问本地内存。
+// 写入和删除会清除本实例的本地缓存，其他实例或并发读写时最多在 LocalTTL 内读到旧值
+func NewHotKeyCache[V any](cCache CommCache[V], cfg *HotKeyConfig) HotKeyCache[V] {
+	hc := &hotKeyCache[V]{
+		cCache:     cCache,
+		candidates: make(map[string]uint64),
+	}
+	if cfg != nil {
+		hc.cfg = *cfg
+	}
+	if hc.cfg.Window <= 0 {
+		hc.cfg.Window = defaultHotKeyWindow
+	}
+	if hc.cfg.Threshold == 0 {
+		hc.cfg.Threshold = defaultHotKeyThreshold
+	}
+	if hc.cfg.TopK <= 0 {
+		hc.cfg.TopK = defaultHotKeyTopK
+	}
+	if hc.cfg.LocalTTL <= 0 {
+		hc.cfg.LocalTTL = defaultHotKeyLocalTTL
+	}
+	if hc.cfg.SampleRate <= 0 || hc.cfg.SampleRate > 1 {
+		hc.cfg.SampleRate = 1
+	}
+	if hc.cfg.SketchWidth <= 0 {
+		hc.cfg.SketchWidth = defaultSketchWidth
+	}
+	if hc.cfg.SketchDepth <= 0 {
+		hc.cfg.SketchDepth = defaultSketchDepth
+	}
+	hc.cur.Store(newCountMinSketch(hc.cfg.SketchWidth, hc.cfg.SketchDepth))
+	hc.prev.Store(newCountMinSketch(hc.cfg.SketchWidth, hc.cfg.SketchDepth))
+	hc.windowStart.Store(time.Now().UnixNano())
+	hc.hot.Store(&map[string]*hotEntry[V]{})
+	return hc
+}
+
+// hotEntries 热点 key 表的快照，只读
+func (hc *hotKeyCache[V]) hotEntries() map[string]*hotEntry[V] {
+	return *hc.hot.Load()
+}
+
+// updateHot 复制热点 key 表，修改后整体替换，调用方需持有锁
+func (hc *hotKeyCache[V]) updateHot(fn func(hot map[string]*hotEntry[V])) {
+	hot := make(map[string]*hotEntry[V], len(hc.hotEntries())+1)
+	for key, entry := range hc.hotEntries() {
+		hot[key] = entry
+	}
+	fn(hot)
+	hc.hot.Store(&hot)
+}
+
+// advance 到了窗口切换时间时加锁切换，其余时候不加锁
+func (hc *hotKeyCache[V]) advance(now time.Time) {
+	if now.UnixNano()-hc.windowStart.Load() < int64(hc.cfg.Window) {
+		return
+	}
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	hc.advanceLocked(now)
+}
+
+// advanceLocked 切换窗口，并重新估算候选和热点 key，降温的热点 key 移出本地缓存，调用方需持有锁
+func (hc *hotKeyCache[V]) advanceLocked(now time.Time) {
+	windowStart := time.Unix(0, hc.windowStart.Load())
+	elapsed := now.Sub(windowStart)
+	if elapsed < hc.cfg.Window {
+		return
+	}
+	cur, prev := hc.cur.Load(), hc.prev.Load()
+	if elapsed >= 2*hc.cfg.Window {
+		prev.reset()
+		cur.reset()
+		hc.windowStart.Store(now.UnixNano())
+	} else {
+		prev.reset()
+		hc.cur.Store(prev)
+		hc.prev.Store(cur)
+		hc.windowStart.Store(windowStart.Add(hc.cfg.Window).UnixNano())
+	}
+	for key := range hc.candidates {
+		est := hc.estimate(key, now)
+		if est == 0 {
+			delete(hc.candidates, key)
+			continue
+		}
+		hc.candidates[key] = est
+	}
+	hc.updateHot(func(hot map[string]*hotEntry[V]) {
+		for key, entry := range hot {
+			est := hc.candidates[key]
+			if est < hc.cfg.Threshold {
+				delete(hot, key)
+				continue
+			}
+			entry.count.Store(est)
+		}
+	})
+}
+
+// estimate 滑动窗口内的估算访问次数，上一个窗口按未过去的比例计入
+func (hc *hotKeyCache[V]) estimate(key string, now time.Time) uint64 {
+	h1, h2 := sketchHash(key)
+	weight := 1 - float64(now.UnixNano()-hc.windowStart.Load())/float64(hc.cfg.Window)
+	if weight < 0 {
+		weight = 0
+	}
+	n := float64(hc.cur.Load().estimate(h1, h2)) + float64(hc.prev.Load().estimate(h1, h2))*weight
+	return uint64(n / hc.cfg.SampleRate)
+}
+
+// record 记录一次访问，返回 key 是否为热点。
+// 未采样的访问和热点 key 的访问不加锁，只有采样到的非热点 key 需要加锁维护候选
+func (hc *hotKeyCache[V]) record(key string) bool {
+	now := time.Now()
+	hc.advance(now)
+	if hc.cfg.SampleRate < 1 && rand.Float64() >= hc.cfg.SampleRate {
+		_, ok := hc.hotEntries()[key]
+		return ok
+	}
+	hc.cur.Load().add(sketchHash(key))
+	est := hc.estimate(key, now)
+	if entry, ok := hc.hotEntries()[key]; ok {
+		entry.count.Store(est)
+		return true
+	}
+
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	if _, ok := hc.hotEntries()[key]; ok {
+		return true
+	}
+	if !hc.addCandidate(key, est) || est < hc.cfg.Threshold {
+		return false
+	}
+	hc.updateHot(func(hot map[string]*hotEntry[V]) {
+		entry := &hotEntry[V]{}
+		entry.count.Store(est)
+		hot[key] = entry
+	})
+	return true
+}
+
+// addCandidate 维护 TopK 候选，满了时替换估算值最小的 key，热点 key 按最新的估算值比较，调用方需持有锁
+func (hc *hotKeyCache[V]) addCandidate(key string, est uint64) bool {
+	if _, ok := hc.candidates[key]; ok || len(hc.candidates) < hc.cfg.TopK {
+		hc.candidates[key] = est
+		return true
+	}
+	hot := hc.hotEntries()
+	minKey, minEst := "", uint64(0)
+	for one, n := range hc.candidates {
+		if entry, ok := hot[one]; ok {
+			n = entry.count.Load()
+		}
+		if minKey == "" || n < minEst {
+			minKey, minEst = one, n
+		}
+	}
+	if est <= minEst {
+		return false
+	}
+	delete(hc.candidates, minKey)
+	if _, ok := hot[minKey]; ok {
+		hc.updateHot(func(hot map[string]*hotEntry[V]) {
+			delete(hot, minKey)
+		})
+	}
+	hc.candidates[key] = est
+	return true
+}
+
+func (hc *hotKeyCache[V]) getLocal(key string) (V, bool) {
+	if entry, ok := hc.hotEntries()[key]; ok {
+		if local := entry.local.Load(); local != nil && time.Now().Before(local.expireAt) {
+			return local.val, true
+		}
+	}
+	var zero V
+	return zero, false
+}
+
+func (hc *hotKeyCache[V]) setLocal(key string, val V) {
+	if entry, ok := hc.hotEntries()[key]; ok {
+		entry.local.Store(&hotValue[V]{val: val, expireAt: time.Now().Add(hc.cfg.LocalTTL)})
+	}
+}
+
+func (hc *hotKeyCache[V]) delLocal(key string) {
+	if entry, ok := hc.hotEntries()[key]; ok {
+		entry.local.Store(nil)
+	}
+}
+
+// HotKeys 当前的热点 key，按估算访问次数从大到小排列
+func (hc *hotKeyCache[V]) HotKeys() []HotKey {
+	hc.advance(time.Now())
+	hot := hc.hotEntries()
+	list := make([]HotKey, 0, len(hot))
+	for key, entry := range hot {
+		list = append(list, HotKey{Key: key, Count: entry.count.Load()})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Key < list[j].Key
+	})
+	return list
+}
+
+// Get 热点 key 先读本地缓存，本地没有时合并并发请求读取一次
+func (hc *hotKeyCache[V]) Get(ctx context.Context, key string) (V, error) {
+	if !hc.record(key) {
+		return hc.cCache.Get(ctx, key)
+	}
+	if v, ok := hc.getLocal(key); ok {
+		return v, nil
+	}
+	ret, err, _ := hc.group.Do(key, func() (any, error) {
+		v, err := hc.cCache.Get(ctx, key)
+		if err == nil && !isZeroValue(v) {
+			hc.setLocal(key, v)
+		}
+		return v, err
+	})
+	v, _ := ret.(V)
+	return v, err
+}
+
+// Set 写入后清除本地缓存
+func (hc *hotKeyCache[V]) Set(ctx context.Context, key string, val V, timeout time.Duration) (bool, error) {
+	ok, err := hc.cCache.Set(ctx, key, val, timeout)
+	hc.delLocal(key)
+	return ok, err
+}
+
+// Del 删除后清除本地缓存
+func (hc *hotKeyCache[V]) Del(ctx context.Context, key string) (bool, error) {
+	ok, err := hc.cCache.Del(ctx, key)
+	hc.delLocal(key)
+	return ok, err
+}
@@ -0,0 +1,97 @@
+package cache_test
+
+import (
+	"context"
+	"github.com/magic-lib/go-plat-cache/cache"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type countingGetCache struct {
+	cache.CommCache[string]
+	gets atomic.Int64
+}
+
+func (c *countingGetCache) Get(ctx context.Context, key string) (string, error) {
+	c.gets.Add(1)
+	return c.CommCache.Get(ctx, key)
+}
+
+func TestHotKeyCache(t *testing.T) {
+	ctx := context.Background()
+	backend := &countingGetCache{CommCache: cache.NewMemGoCache[string](time.Minute, time.Minute)}
+	hc := cache.NewHotKeyCache[string](backend, &cache.HotKeyConfig{
+		Window:    200 * time.Millisecond,
+		Threshold: 10,
+		TopK:      4,
+		LocalTTL:  time.Minute,
+	})
+	_, _ = hc.Set(ctx, "hot", "v1", time.Minute)
+	_, _ = hc.Set(ctx, "cold", "c", time.Minute)
+
+	for i := 0; i < 100; i++ {
+		if v, err := hc.Get(ctx, "hot"); err != nil || v != "v1" {
+			t.Fatalf("Get got %q, %v", v, err)
+		}
+	}
+	_, _ = hc.Get(ctx, "cold")
+	// 达到阈值前的 9 次，第 10 次提升并读入本地缓存，再加上 cold 的一次
+	if n := backend.gets.Load(); n != 11 {
+		t.Fatalf("backend gets %d", n)
+	}
+	hot := hc.HotKeys()
+	if len(hot) != 1 || hot[0].Key != "hot" || hot[0].Count < 10 {
+		t.Fatalf("HotKeys got %+v", hot)
+	}
+
+	// 写入后清除本地缓存
+	_, _ = hc.Set(ctx, "hot", "v2", time.Minute)
+	if v, _ := hc.Get(ctx, "hot"); v != "v2" {
+		t.Fatalf("Get after Set got %q", v)
+	}
+
+	// 两个窗口没有访问后降温
+	time.Sleep(450 * time.Millisecond)
+	if hot = hc.HotKeys(); len(hot) != 0 {
+		t.Fatalf("HotKeys after cooling got %+v", hot)
+	}
+}
+
+func TestHotKeyCacheConcurrent(t *testing.T) {
+	ctx := context.Background()
+	backend := cache.NewMemGoCache[string](time.Minute, time.Minute)
+	hc := cache.NewHotKeyCache[string](backend, &cache.HotKeyConfig{
+		Window:     20 * time.Millisecond,
+		Threshold:  50,
+		TopK:       2,
+		SampleRate: 0.5,
+	})
+	_, _ = hc.Set(ctx, "hot", "v", time.Minute)
+
+	// 热点 key 和不断更换的冷 key 并发读写，穿过多个窗口切换，配合 -race 检查
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 2000; j++ {
+				if v, err := hc.Get(ctx, "hot"); err != nil || v != "v" {
+					t.Errorf("Get got %q, %v", v, err)
+					return
+				}
+				_, _ = hc.Get(ctx, "cold"+strconv.Itoa(i*2000+j))
+				if j%500 == 0 {
+					_, _ = hc.Set(ctx, "hot", "v", time.Minute)
+					_ = hc.HotKeys()
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if hot := hc.HotKeys(); len(hot) == 0 || hot[0].Key != "hot" {
+		t.Errorf("HotKeys got %+v", hot)
+	}
+}
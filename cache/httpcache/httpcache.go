@@ -20,6 +20,9 @@ type Config[RQ any, RD any] struct {
 	Expiration              time.Duration                                                           //数据多长时间过期，过期以后被动淘汰
 	CleanupInterval         time.Duration                                                           //间隔过久执行清理，主动清理
 	AsyncExecuteDuration    time.Duration                                                           //在这段时间里不执行异步更新，避免瞬时压力
+	TTLJitter               *cache.TTLJitter                                                        //有效期随机抖动，避免同一批数据同时过期
+	Beta                    float64                                                                 //XFetch 系数，>0 时按加载耗时概率提前异步更新，替代 AsyncExecuteDuration 的固定时间判断
+	Breaker                 *cache.BreakerConfig                                                    //CacheList 中每个存储的熔断配置，熔断打开时跳过该存储，使用后面的存储
 	NeedAsyncExecuteHandler func(ctx context.Context, responseData RD) bool                         //这个数据是否需要自动异步更新
 	GetDataHandler          func(ctx context.Context, cacheKey string, requestParam RQ) (RD, error) //动态获取数据
 }
@@ -66,10 +69,16 @@ func (c *cacheIns[RQ, RD]) Get(ctx context.Context, cacheKey string, requestPara
 
 // Set 外部手动进行设置
 func (c *cacheIns[RQ, RD]) Set(ctx context.Context, cacheKey string, responseData RD) bool {
+	return c.set(ctx, cacheKey, responseData, 0)
+}
+
+// set 设置数据，computeDuration 为获取数据的耗时
+func (c *cacheIns[RQ, RD]) set(ctx context.Context, cacheKey string, responseData RD, computeDuration time.Duration) bool {
 	if cond.IsNil(responseData) || cacheKey == "" {
 		return false
 	}
-	ret, err := multiSetData(ctx, c.cfg.CacheList, c.cfg.Namespace, cacheKey, responseData, c.cfg.Expiration)
+	expiration := c.cfg.TTLJitter.Apply(cacheKey, c.cfg.Expiration)
+	ret, err := multiSetData(ctx, c.cfg.CacheList, c.cfg.Namespace, cacheKey, responseData, expiration, computeDuration)
 	if err != nil {
 		return false
 	}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/magic-lib/go-plat-cache/cache"
 	"github.com/magic-lib/go-plat-cache/cache/internal/gmlock"
+	"github.com/magic-lib/go-plat-cache/cache/internal/xfetch"
 	"github.com/magic-lib/go-plat-utils/cond"
 	"github.com/magic-lib/go-plat-utils/goroutines"
 	"github.com/magic-lib/go-plat-utils/id-generator/id"
@@ -30,9 +31,10 @@ type cacheIns[P any, V any] struct {
 }
 
 type cacheData[V any] struct {
-	data           V         //存储数据
-	createTime     time.Time //创建时间
-	expirationTime time.Time //过期时间
+	data            V             //存储数据
+	createTime      time.Time     //创建时间
+	expirationTime  time.Time     //过期时间
+	computeDuration time.Duration //获取数据的耗时，XFetch 提前更新使用
 }
 
 /*
@@ -73,6 +75,9 @@ func (cfg *Config[P, V]) checkParam(fileName string, fileLine int) error {
 	if cfg.GetDataHandler == nil {
 		err = fmt.Errorf("GetDataHandler null")
 	}
+	if len(cfg.CacheList) > 0 && cfg.Breaker != nil {
+		cfg.CacheList = withBreaker(cfg.Namespace, cfg.CacheList, cfg.Breaker)
+	}
 	if cfg.CacheList == nil || len(cfg.CacheList) == 0 {
 		if storeList, ok := storeListCacheMap.Get(cfg.Namespace); ok {
 			if storeListTemp, ok := storeList.([]cache.CommCache[*cacheData[V]]); ok {
@@ -101,6 +106,21 @@ func (cfg *Config[P, V]) checkParam(fileName string, fileLine int) error {
 	return err
 }
 
+// withBreaker 为每个存储增加熔断，已有熔断的不重复增加
+func withBreaker[V any](namespace string, storeList []cache.CommCache[*cacheData[V]], breakerCfg *cache.BreakerConfig) []cache.CommCache[*cacheData[V]] {
+	newList := make([]cache.CommCache[*cacheData[V]], 0, len(storeList))
+	for i, one := range storeList {
+		if _, ok := one.(cache.BreakerCache[*cacheData[V]]); ok || one == nil {
+			newList = append(newList, one)
+			continue
+		}
+		oneCfg := *breakerCfg
+		oneCfg.Name = fmt.Sprintf("%s#%d", namespace, i)
+		newList = append(newList, cache.NewBreakerCache[*cacheData[V]](one, &oneCfg))
+	}
+	return newList
+}
+
 // 根据参数初始化默认Store
 func newDefaultStore[P any, V any](cfg *Config[P, V]) cache.CommCache[*cacheData[V]] {
 	if cfg.MaxSize == 0 {
@@ -118,10 +138,13 @@ func (c *cacheIns[P, V]) needAsyncGetData(ctx context.Context, tempData *cacheDa
 	}
 
 	isUpdate := false
-	duration := time.Now().Sub(tempData.createTime)
-	if duration > c.cfg.AsyncExecuteDuration {
+	if c.cfg.Beta > 0 {
+		//按获取数据的耗时，越接近过期越可能异步更新，避免多个实例同时更新
+		isUpdate = xfetch.ShouldRefresh(tempData.computeDuration, tempData.expirationTime, c.cfg.Beta)
+	} else if duration := time.Now().Sub(tempData.createTime); duration > c.cfg.AsyncExecuteDuration {
 		isUpdate = true //如果超时了，则异步更新
-	} else {
+	}
+	if !isUpdate {
 		//根据程序判断是否需要异步自动更新
 		if c.cfg.NeedAsyncExecuteHandler != nil {
 			isUpdate = c.cfg.NeedAsyncExecuteHandler(ctx, tempData.data)
@@ -287,6 +310,7 @@ func (c *cacheIns[P, V]) executeHandler(ctx context.Context, cacheKey string, ge
 
 	logger := logs.CtxLogger(ctx)
 
+	start := time.Now()
 	goroutines.GoSync(func(params ...interface{}) {
 		ctx1, _ := params[0].(context.Context)
 		cacheKey1, ok2 := params[1].(string)
@@ -306,7 +330,7 @@ func (c *cacheIns[P, V]) executeHandler(ctx context.Context, cacheKey string, ge
 
 	if err == nil {
 		//如果获取成功，则立即进行缓存
-		c.Set(ctx, cacheKey, value)
+		c.set(ctx, cacheKey, value, time.Since(start))
 	}
 	return value, err
 }
@@ -59,7 +59,7 @@ func multiGetData[V any](ctx context.Context, storeList []cache.CommCache[*cache
 }
 
 // 根据 store 设置数据
-func multiSetData[V any](ctx context.Context, storeList []cache.CommCache[*cacheData[V]], namespace string, cacheKey string, dataValue V, expiration time.Duration) (bool, error) {
+func multiSetData[V any](ctx context.Context, storeList []cache.CommCache[*cacheData[V]], namespace string, cacheKey string, dataValue V, expiration time.Duration, computeDuration time.Duration) (bool, error) {
 	if storeList == nil || len(storeList) == 0 {
 		return false, fmt.Errorf("multiSetData storeList empty")
 	}
@@ -70,9 +70,10 @@ func multiSetData[V any](ctx context.Context, storeList []cache.CommCache[*cache
 	storeKey := getStoreCacheKey(namespace, cacheKey)
 	var lastErr error
 	newCacheData := &cacheData[V]{
-		data:           dataValue,
-		createTime:     time.Now(),
-		expirationTime: time.Now().Add(expiration),
+		data:            dataValue,
+		createTime:      time.Now(),
+		expirationTime:  time.Now().Add(expiration),
+		computeDuration: computeDuration,
 	}
 	for _, oneFactory := range storeList {
 		_, err := oneFactory.Set(ctx, storeKey, newCacheData, expiration)
@@ -0,0 +1,26 @@
+// Package xfetch 概率提前过期(XFetch)，避免热点 key 同时过期时所有调用方同时重新加载。
+// 参考 Optimal Probabilistic Cache Stampede Prevention (Vattani, 2015)
+package xfetch
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// DefaultBeta 默认系数，>1 更倾向于提前刷新，<1 更倾向于接近过期时才刷新
+const DefaultBeta = 1.0
+
+// ShouldRefresh 判断是否需要提前刷新，delta 为上次加载数据的耗时，expiresAt 为过期时间点。
+// 满足 now - delta*beta*ln(rand) >= expiresAt 时刷新，越接近过期、加载越慢，刷新的概率越大
+func ShouldRefresh(delta time.Duration, expiresAt time.Time, beta float64) bool {
+	if beta <= 0 || expiresAt.IsZero() {
+		return false
+	}
+	if delta <= 0 {
+		delta = time.Millisecond
+	}
+	// 1-rand 的取值范围是 (0,1]，避免 ln(0)
+	gap := -float64(delta) * beta * math.Log(1-rand.Float64())
+	return !time.Now().Add(time.Duration(gap)).Before(expiresAt)
+}
@@ -0,0 +1,30 @@
+package xfetch_test
+
+import (
+	"github.com/magic-lib/go-plat-cache/cache/internal/xfetch"
+	"testing"
+	"time"
+)
+
+func TestShouldRefresh(t *testing.T) {
+	if xfetch.ShouldRefresh(time.Second, time.Now().Add(-time.Second), xfetch.DefaultBeta) != true {
+		t.Error("expired item should refresh")
+	}
+	if xfetch.ShouldRefresh(time.Second, time.Now().Add(time.Second), 0) {
+		t.Error("beta<=0 should disable refresh")
+	}
+
+	// 剩余时间远大于加载耗时，几乎不会刷新；接近过期时大概率刷新
+	far, near := 0, 0
+	for i := 0; i < 1000; i++ {
+		if xfetch.ShouldRefresh(time.Millisecond, time.Now().Add(time.Hour), xfetch.DefaultBeta) {
+			far++
+		}
+		if xfetch.ShouldRefresh(100*time.Millisecond, time.Now().Add(10*time.Millisecond), xfetch.DefaultBeta) {
+			near++
+		}
+	}
+	if far > 0 || near < 800 {
+		t.Errorf("unexpected refresh count far=%d near=%d", far, near)
+	}
+}
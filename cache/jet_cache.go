@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"github.com/magic-lib/go-plat-startupcfg/startupcfg"
 	"github.com/magic-lib/go-plat-utils/conv"
 	jCache "github.com/mgtv-tech/jetcache-go"
 	"github.com/mgtv-tech/jetcache-go/local"
@@ -13,6 +14,7 @@ import (
 
 type JetCache[V any] struct {
 	JetCacheGo jCache.Cache
+	jitter     *TTLJitter
 }
 
 type JetCacheConfig struct {
@@ -20,12 +22,14 @@ type JetCacheConfig struct {
 	FreeCacheExpiration time.Duration
 	Namespace           string
 	RedisConfig         *redis.RingOptions
+	RedisCfg            *startupcfg.RedisConfig // 优先使用，与 redisCache 等共用连接池
 	RefreshDuration     time.Duration
 	ErrNotFound         error
 	JCacheOption        []jCache.Option
+	TTLJitter           *TTLJitter // 写入时有效期随机抖动，避免同时过期
 }
 
-// NewJetCache 新建JetCache
+// NewJetCache 新建JetCache，RedisCfg 连接失败时不使用远程缓存
 func NewJetCache[V any](jConfig *JetCacheConfig) *JetCache[V] {
 	if jConfig == nil {
 		jConfig = &JetCacheConfig{}
@@ -35,7 +39,11 @@ func NewJetCache[V any](jConfig *JetCacheConfig) *JetCache[V] {
 	}
 
 	jCacheOption := make([]jCache.Option, 0)
-	if jConfig.RedisConfig != nil {
+	if jConfig.RedisCfg != nil {
+		if cli, err := GetRedisClient(jConfig.RedisCfg); err == nil {
+			jCacheOption = append(jCacheOption, jCache.WithRemote(remote.NewGoRedisV9Adapter(cli)))
+		}
+	} else if jConfig.RedisConfig != nil {
 		ring := redis.NewRing(jConfig.RedisConfig)
 		jCacheOption = append(jCacheOption, jCache.WithRemote(remote.NewGoRedisV9Adapter(ring)))
 	}
@@ -65,6 +73,7 @@ func NewJetCache[V any](jConfig *JetCacheConfig) *JetCache[V] {
 
 	return &JetCache[V]{
 		JetCacheGo: jetCache,
+		jitter:     jConfig.TTLJitter,
 	}
 }
 
@@ -80,7 +89,7 @@ func (co *JetCache[V]) Get(ctx context.Context, key string) (v V, err error) {
 
 // Set timeout
 func (co *JetCache[V]) Set(ctx context.Context, key string, val V, timeout time.Duration) (bool, error) {
-	err := co.JetCacheGo.Set(ctx, key, jCache.Value(val), jCache.TTL(timeout))
+	err := co.JetCacheGo.Set(ctx, key, jCache.Value(val), jCache.TTL(co.jitter.Apply(key, timeout)))
 	if err != nil {
 		return false, err
 	}
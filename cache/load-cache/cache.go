@@ -1,140 +1,277 @@
 package cache
 
 import (
-	"container/list"
 	"context"
-	"hash/fnv"
+	"fmt"
+	"hash/maphash"
+	"sync"
 	"time"
 
+	commcache "github.com/magic-lib/go-plat-cache/cache"
+	"github.com/magic-lib/go-plat-cache/cache/internal/xfetch"
 	"golang.org/x/sync/singleflight"
 )
 
+const (
+	defaultShardCount  = 16   // 默认分片数量
+	defaultSketchWidth = 1024 // 默认每个分片 TinyLFU 频率统计的计数器数量
+
+	defaultCleanupInterval = time.Minute // 默认过期清理间隔
+	defaultCleanupBatch    = 64          // 默认每个分片每轮检查的条目数
+)
+
 // Weigher 用于自定义缓存条目的权重（如字节大小），影响 LRU 驱逐。
-type Weigher func(key string, val interface{}) int64
+type Weigher[K comparable, V any] func(key K, val V) int64
+
+// Loader 缓存未命中时加载数据，返回值及其有效期。
+type Loader[V any] func(context.Context) (V, time.Duration, error)
 
 // CacheOptions 用于配置缓存的行为和参数。
-type CacheOptions struct {
-	DefaultTTL     time.Duration // 默认过期时间
-	EmptyTTL       time.Duration // loader 返回 nil 时的过期时间
-	RefreshFactor  float64       // 异步刷新触发因子（剩余时间 < ttl*factor 时刷新）
-	ShardCount     int           // 分片数量，影响并发性能
-	TotalMaxBytes  int64         // 总最大缓存字节数
-	BackgroundJobs int           // 后台持久化并发任务数
-	Weigher        Weigher       // 权重计算函数
-	Persister      Persister     // 持久化接口实现
-}
-
-// Cache 是主缓存结构，支持分片、LRU、TTL、singleflight、持久化等。
-type Cache struct {
-	shards         []*shard           // 分片数组
-	group          singleflight.Group // singleflight 防止重复加载
-	defaultTTL     time.Duration      // 默认 TTL
-	emptyTTL       time.Duration      // 空值 TTL
-	refreshFactor  float64            // 刷新因子
-	backgroundPool chan struct{}      // 后台任务池
-	weigher        Weigher            // 权重计算
-	persister      Persister          // 持久化实现
-	totalMaxBytes  int64              // 总最大字节数
+type CacheOptions[K comparable, V any] struct {
+	DefaultTTL      time.Duration        // 默认过期时间，loader 未返回有效期时使用
+	EmptyTTL        time.Duration        // loader 返回零值时的过期时间
+	RefreshFactor   float64              // 异步刷新触发因子（剩余时间 < ttl*factor 时刷新）
+	Beta            float64              // XFetch 系数，>0 时按加载耗时概率提前刷新，替代 RefreshFactor
+	TTLJitter       *commcache.TTLJitter // 写入时有效期随机抖动，避免同一批条目同时过期
+	ShardCount      int                  // 分片数量，影响并发性能
+	TotalMaxBytes   int64                // 总最大缓存字节数，<=0 不限制
+	BackgroundJobs  int                  // 后台持久化并发任务数
+	Weigher         Weigher[K, V]        // 权重计算函数，为空时字符串按长度计算，其他为 1
+	Persister       Persister[K, V]      // 持久化接口实现
+	TinyLFU         bool                 // 开启 W-TinyLFU 准入策略，访问频率低的新条目不会挤掉热点条目，可抵抗扫描
+	SketchWidth     int                  // 每个分片 TinyLFU 频率统计的计数器数量，建议与分片内的条目数相当
+	CleanupInterval time.Duration        // 后台过期清理间隔，默认 1 分钟，<0 不启动后台清理
+	CleanupBatch    int                  // 每轮每个分片抽查的条目数，过期比例高时会继续清理
+	WarmStart       bool                 // 启动时从 Persister 加载未过期的条目，避免重启后缓存全部失效
+}
+
+// Cache 是主缓存结构，支持分片、LRU、W-TinyLFU、TTL、singleflight、持久化等。
+type Cache[K comparable, V any] struct {
+	shards         []*shard[K, V]       // 分片数组
+	seed           maphash.Seed         // key 哈希种子
+	group          singleflight.Group   // singleflight 防止重复加载
+	defaultTTL     time.Duration        // 默认 TTL
+	emptyTTL       time.Duration        // 空值 TTL
+	refreshFactor  float64              // 刷新因子
+	beta           float64              // XFetch 系数
+	ttlJitter      *commcache.TTLJitter // 有效期抖动
+	backgroundPool chan struct{}        // 后台任务池
+	weigher        Weigher[K, V]        // 权重计算
+	persister      Persister[K, V]      // 持久化实现
+	totalMaxBytes  int64                // 总最大字节数
+	cleanupBatch   int                  // 每轮每个分片抽查的条目数
+	closeCh        chan struct{}        // 关闭后台清理
+	closeOnce      sync.Once
 }
 
 // NewCache 创建一个新的 Cache 实例。
-func NewCache(opt CacheOptions) *Cache {
-	shards := make([]*shard, opt.ShardCount)
+func NewCache[K comparable, V any](opt CacheOptions[K, V]) *Cache[K, V] {
+	if opt.ShardCount <= 0 {
+		opt.ShardCount = defaultShardCount
+	}
+	if opt.TinyLFU && opt.SketchWidth <= 0 {
+		opt.SketchWidth = defaultSketchWidth
+	}
+	if opt.CleanupInterval == 0 {
+		opt.CleanupInterval = defaultCleanupInterval
+	}
+	if opt.CleanupBatch <= 0 {
+		opt.CleanupBatch = defaultCleanupBatch
+	}
+	shards := make([]*shard[K, V], opt.ShardCount)
 	for i := 0; i < opt.ShardCount; i++ {
-		shards[i] = newShard()
-		shards[i].capBytes = opt.TotalMaxBytes / int64(opt.ShardCount)
+		var sketch *countMinSketch
+		if opt.TinyLFU {
+			sketch = newCountMinSketch(opt.SketchWidth)
+		}
+		shards[i] = newShard[K, V](opt.TotalMaxBytes/int64(opt.ShardCount), sketch)
 	}
-	return &Cache{
+	c := &Cache[K, V]{
 		shards:         shards,
+		seed:           maphash.MakeSeed(),
 		defaultTTL:     opt.DefaultTTL,
 		emptyTTL:       opt.EmptyTTL,
 		refreshFactor:  opt.RefreshFactor,
+		beta:           opt.Beta,
+		ttlJitter:      opt.TTLJitter,
 		backgroundPool: make(chan struct{}, opt.BackgroundJobs),
 		weigher:        opt.Weigher,
 		persister:      opt.Persister,
 		totalMaxBytes:  opt.TotalMaxBytes,
+		cleanupBatch:   opt.CleanupBatch,
+		closeCh:        make(chan struct{}),
 	}
+	if opt.WarmStart {
+		_, _ = c.Rehydrate()
+	}
+	if opt.CleanupInterval > 0 {
+		go c.cleanExpiredLoop(opt.CleanupInterval)
+	}
+	return c
+}
+
+// hashOf 计算 key 的哈希，用于分片和频率统计。
+func (c *Cache[K, V]) hashOf(key K) uint64 {
+	return maphash.Comparable(c.seed, key)
 }
 
 // getShard 根据 key 哈希分配到对应分片。
-func (c *Cache) getShard(key string) *shard {
-	h := fnv.New32a()
-	h.Write([]byte(key))
-	return c.shards[int(h.Sum32())%len(c.shards)]
+func (c *Cache[K, V]) getShard(hash uint64) *shard[K, V] {
+	return c.shards[hash%uint64(len(c.shards))]
 }
 
-// GetOrLoadCtx 先查缓存，miss 时用 loader 加载并写入，支持 singleflight 防抖。
-func (c *Cache) GetOrLoadCtx(ctx context.Context, key string,
-	loader func(context.Context) (interface{}, time.Duration, error)) (interface{}, error) {
+// weightOf 计算条目权重，优先使用配置的 Weigher。
+func (c *Cache[K, V]) weightOf(key K, val V) int64 {
+	if c.weigher != nil {
+		return c.weigher(key, val)
+	}
+	return weightOf(val)
+}
+
+// keyString 将 key 转为字符串，用于 singleflight 和 TTL 抖动。
+func keyString[K comparable](key K) string {
+	if s, ok := any(key).(string); ok {
+		return s
+	}
+	return fmt.Sprint(key)
+}
 
+// GetOrLoadCtx 先查缓存，miss 时用 loader 加载并写入，支持 singleflight 防抖。
+func (c *Cache[K, V]) GetOrLoadCtx(ctx context.Context, key K, loader Loader[V]) (V, error) {
 	if val, ok := c.Get(key); ok {
-		c.asyncRefresh(key, loader, val) // 命中时异步刷新
+		c.asyncRefresh(key, loader) // 命中时异步刷新
 		return val, nil
 	}
 
-	v, err, _ := c.group.Do(key, func() (interface{}, error) {
-		val, ttl, err := loader(ctx)
-		if err != nil {
-			return nil, err
-		}
-		if val == nil {
-			ttl = c.emptyTTL
-		}
-		c.SetWithTTL(key, val, ttl)
-		return val, nil
+	v, err, _ := c.group.Do(keyString(key), func() (interface{}, error) {
+		return c.load(ctx, key, loader)
 	})
-	return v, err
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+	// V 为接口时 loader 可能返回 nil，按空值缓存，直接断言会 panic
+	val, _ := v.(V)
+	return val, nil
+}
+
+// load 调用 loader 加载并写入缓存，记录加载耗时。
+func (c *Cache[K, V]) load(ctx context.Context, key K, loader Loader[V]) (V, error) {
+	start := time.Now()
+	val, ttl, err := loader(ctx)
+	if err != nil {
+		return val, err
+	}
+	if isZero(val) {
+		ttl = c.emptyTTL
+	} else if ttl <= 0 {
+		ttl = c.defaultTTL
+	}
+	c.setWithDelta(key, val, ttl, time.Since(start))
+	return val, nil
 }
 
 // asyncRefresh 命中缓存时，剩余时间不足则异步刷新。
-func (c *Cache) asyncRefresh(key string, loader func(context.Context) (interface{}, time.Duration, error), oldVal interface{}) {
-	sh := c.getShard(key)
-	item, ok := sh.items[key].Value.(*cacheItem)
+// 设置了 Beta 时使用 XFetch，按加载耗时概率提前刷新，避免多个副本在同一时刻刷新同一个热点 key。
+func (c *Cache[K, V]) asyncRefresh(key K, loader Loader[V]) {
+	hash := c.hashOf(key)
+	item, ok := c.getShard(hash).peek(key)
 	if !ok || item.ttl <= 0 {
 		return
 	}
-	remain := time.Until(time.Unix(0, item.expiration))
-	if remain < time.Duration(float64(item.ttl)*c.refreshFactor) {
-		go func() {
-			_, _, _ = c.group.Do(key, func() (interface{}, error) {
-				val, ttl, err := loader(context.Background())
-				if err == nil && val != nil {
-					c.SetWithTTL(key, val, ttl)
-				}
-				return nil, nil
-			})
-		}()
+	if !c.needRefresh(item) {
+		return
 	}
+	go func() {
+		_, _, _ = c.group.Do(keyString(key), func() (interface{}, error) {
+			val, err := c.load(context.Background(), key, loader)
+			return val, err
+		})
+	}()
+}
+
+// needRefresh 判断命中的条目是否需要提前刷新。
+func (c *Cache[K, V]) needRefresh(item *cacheItem[K, V]) bool {
+	expiresAt := time.Unix(0, item.expiration)
+	if c.beta > 0 {
+		return xfetch.ShouldRefresh(item.delta, expiresAt, c.beta)
+	}
+	remain := time.Until(expiresAt)
+	return remain < time.Duration(float64(item.ttl)*c.refreshFactor)
 }
 
 // Delete 删除指定 key 的缓存。
-func (c *Cache) Delete(key string) {
-	sh := c.getShard(key)
-	sh.delete(key)
+func (c *Cache[K, V]) Delete(key K) {
+	hash := c.hashOf(key)
+	c.getShard(hash).delete(key)
 }
 
 // Purge 清空所有分片缓存。
-func (c *Cache) Purge() {
+func (c *Cache[K, V]) Purge() {
 	for _, sh := range c.shards {
-		sh.mu.Lock()
-		sh.items = make(map[string]*list.Element)
-		sh.lru.Init()
-		sh.curBytes = 0
-		sh.mu.Unlock()
+		sh.purge()
 	}
 }
 
 // ShardCount 返回分片数量。
-func (c *Cache) ShardCount() int {
+func (c *Cache[K, V]) ShardCount() int {
 	return len(c.shards)
 }
 
 // ShardItems 返回指定分片的条目数量。
-func (c *Cache) ShardItems(index int) int {
+func (c *Cache[K, V]) ShardItems(index int) int {
+	if index < 0 || index >= len(c.shards) {
+		return 0
+	}
+	return c.shards[index].len()
+}
+
+// ShardBytes 返回指定分片已用的容量。
+func (c *Cache[K, V]) ShardBytes(index int) int64 {
 	if index < 0 || index >= len(c.shards) {
 		return 0
 	}
-	c.shards[index].mu.Lock()
-	defer c.shards[index].mu.Unlock()
-	return len(c.shards[index].items)
+	return c.shards[index].bytes()
+}
+
+// Len 返回所有分片的条目数量，包含已过期但还未清理的条目。
+func (c *Cache[K, V]) Len() int {
+	n := 0
+	for _, sh := range c.shards {
+		n += sh.len()
+	}
+	return n
+}
+
+// Bytes 返回所有分片已用的容量。
+func (c *Cache[K, V]) Bytes() int64 {
+	var n int64
+	for _, sh := range c.shards {
+		n += sh.bytes()
+	}
+	return n
+}
+
+// Close 停止后台过期清理。
+func (c *Cache[K, V]) Close() {
+	c.closeOnce.Do(func() {
+		close(c.closeCh)
+	})
+}
+
+// cleanExpiredLoop 后台定期逐个分片清理过期条目。
+func (c *Cache[K, V]) cleanExpiredLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			for _, sh := range c.shards {
+				sh.cleanExpired(c.cleanupBatch)
+			}
+		case <-c.closeCh:
+			return
+		}
+	}
 }
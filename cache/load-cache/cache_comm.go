@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	commcache "github.com/magic-lib/go-plat-cache/cache"
+)
+
+// commCache 将 Cache 适配为 CommCache，便于与其他缓存组合使用。
+type commCache[V any] struct {
+	c *Cache[string, V]
+}
+
+var _ commcache.CommCache[any] = (*commCache[any])(nil)
+
+// NewCommCache 将 Cache 适配为 CommCache。
+func NewCommCache[V any](c *Cache[string, V]) commcache.CommCache[V] {
+	return &commCache[V]{c: c}
+}
+
+// Get 获取缓存值，不存在时返回零值。
+func (co *commCache[V]) Get(_ context.Context, key string) (V, error) {
+	v, _ := co.c.Get(key)
+	return v, nil
+}
+
+// Set 设置缓存值，timeout<=0 时使用 DefaultTTL。
+func (co *commCache[V]) Set(_ context.Context, key string, val V, timeout time.Duration) (bool, error) {
+	if timeout <= 0 {
+		timeout = co.c.defaultTTL
+	}
+	co.c.SetWithTTL(key, val, timeout)
+	return true, nil
+}
+
+// Del 删除缓存值。
+func (co *commCache[V]) Del(_ context.Context, key string) (bool, error) {
+	co.c.Delete(key)
+	return true, nil
+}
@@ -2,16 +2,29 @@ package cache
 
 import "time"
 
+// segment 条目所在的 LRU 段。
+type segment uint8
+
+const (
+	segmentWindow    segment = iota // 窗口段，新条目先进入这里；未开启 TinyLFU 时为唯一的 LRU
+	segmentProbation                // 试用段，从窗口段准入的条目
+	segmentProtected                // 保护段，在试用段再次被访问的条目
+)
+
 // cacheItem 表示缓存中的单个条目。
-type cacheItem struct {
-	key        string        // 缓存键
-	value      interface{}   // 缓存值
+type cacheItem[K comparable, V any] struct {
+	key        K             // 缓存键
+	value      V             // 缓存值
+	hash       uint64        // key 的哈希，用于频率统计
+	weight     int64         // 写入时计算的权重
+	segment    segment       // 所在的 LRU 段
 	expiration int64         // 过期时间（UnixNano）
 	ttl        time.Duration // 有效期
+	delta      time.Duration // 上次加载耗时，XFetch 提前刷新使用
 }
 
 // isExpired 判断条目是否已过期。
-func (it *cacheItem) isExpired() bool {
+func (it *cacheItem[K, V]) isExpired() bool {
 	if it.ttl <= 0 {
 		return false // ttl<=0 表示永不过期
 	}
@@ -1,11 +1,20 @@
 package cache
 
-// weightOf 计算缓存值的权重（如字节数），用于 LRU 驱逐。
+import "reflect"
+
+// weightOf 未配置 Weigher 时计算缓存值的权重（如字节数），用于 LRU 驱逐。
 func weightOf(val interface{}) int64 {
 	switch v := val.(type) {
 	case string:
 		return int64(len(v))
+	case []byte:
+		return int64(len(v))
 	default:
 		return 1
 	}
 }
+
+// isZero 判断是否为零值，loader 返回零值时按 EmptyTTL 缓存。
+func isZero[V any](v V) bool {
+	return reflect.ValueOf(&v).Elem().IsZero()
+}
@@ -1,22 +1,159 @@
 package cache
 
+import (
+	"context"
+	"fmt"
+	"time"
+
+	commcache "github.com/magic-lib/go-plat-cache/cache"
+)
+
 // Persister 定义缓存持久化接口。
-type Persister interface {
-	Save(key string, value interface{}) error // 保存条目
-	Load(key string) (interface{}, bool)      // 加载条目
+type Persister[K comparable, V any] interface {
+	Save(key K, value V, ttl time.Duration) error                // 保存条目，ttl<=0 表示永不过期
+	Load(key K) (V, time.Duration, bool)                         // 加载条目及剩余有效期，0 表示永不过期
+	Range(fn func(key K, value V, ttl time.Duration) bool) error // 遍历未过期的条目，fn 返回 false 时停止
 }
 
 // enqueuePersist 异步持久化缓存条目。
-func (c *Cache) enqueuePersist(key string, value interface{}) {
+func (c *Cache[K, V]) enqueuePersist(key K, value V, ttl time.Duration) {
 	select {
 	case c.backgroundPool <- struct{}{}:
 		go func() {
 			defer func() { <-c.backgroundPool }()
 			if c.persister != nil {
-				_ = c.persister.Save(key, value)
+				_ = c.persister.Save(key, value, ttl)
 			}
 		}()
 	default:
 		// 丢弃以避免阻塞
 	}
 }
+
+// Rehydrate 从 Persister 加载未过期的条目到各分片，不会再次持久化，
+// 设置了 TotalMaxBytes 时加载到总容量为止，返回加载的条目数。
+func (c *Cache[K, V]) Rehydrate() (int, error) {
+	if c.persister == nil {
+		return 0, nil
+	}
+	var loaded int
+	var totalBytes int64
+	err := c.persister.Range(func(key K, value V, ttl time.Duration) bool {
+		weight := c.weightOf(key, value)
+		if c.totalMaxBytes > 0 && totalBytes+weight > c.totalMaxBytes {
+			return false
+		}
+		hash := c.hashOf(key)
+		c.getShard(hash).set(&cacheItem[K, V]{
+			key:        key,
+			value:      value,
+			hash:       hash,
+			weight:     weight,
+			ttl:        ttl,
+			expiration: time.Now().Add(ttl).UnixNano(),
+		})
+		totalBytes += weight
+		loaded++
+		return true
+	})
+	return loaded, err
+}
+
+const persistScanCount = 1000 // 不支持 RangeableCache 时每次 Scan 的数量
+
+// persistStore 可作为 Persister 的存储：支持过期时间查询和遍历。
+type persistStore[V any] interface {
+	commcache.CommCache[V]
+	commcache.ExpiringCache
+	commcache.ScannableCache
+}
+
+// storePersister 基于 CommCache 的持久化实现。
+type storePersister[V any] struct {
+	store persistStore[V]
+}
+
+var _ Persister[string, any] = (*storePersister[any])(nil)
+
+// NewStorePersister 基于 CommCache 创建 Persister，store 需要同时实现 ExpiringCache 和 ScannableCache。
+func NewStorePersister[V any](store commcache.CommCache[V]) (Persister[string, V], error) {
+	ps, ok := store.(persistStore[V])
+	if !ok {
+		return nil, fmt.Errorf("store %T must implement ExpiringCache and ScannableCache", store)
+	}
+	return &storePersister[V]{store: ps}, nil
+}
+
+// NewBoltPersister 基于 BBoltCache 创建 Persister。
+func NewBoltPersister[V any](cfg *commcache.BBoltCacheConfig) (Persister[string, V], error) {
+	store, err := commcache.NewBBoltCache[V](cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewStorePersister[V](store)
+}
+
+// NewDiskPersister 基于 diskCache 创建 Persister，ttl<=0 的条目按 maxExpireTime 保存。
+func NewDiskPersister[V any](basePath string, maxExpireTime time.Duration) (Persister[string, V], error) {
+	return NewStorePersister[V](commcache.NewDiskCache[V](basePath, maxExpireTime))
+}
+
+// Save 保存条目。
+func (p *storePersister[V]) Save(key string, value V, ttl time.Duration) error {
+	if ttl < 0 {
+		ttl = 0
+	}
+	_, err := p.store.Set(context.Background(), key, value, ttl)
+	return err
+}
+
+// Load 加载条目及剩余有效期。
+func (p *storePersister[V]) Load(key string) (V, time.Duration, bool) {
+	var zero V
+	ctx := context.Background()
+	ttl, err := p.store.TTL(ctx, key)
+	if err != nil || ttl == commcache.TTLNotFound {
+		return zero, 0, false
+	}
+	if ttl == commcache.TTLNoExpire {
+		ttl = 0
+	}
+	val, err := p.store.Get(ctx, key)
+	if err != nil {
+		return zero, 0, false
+	}
+	return val, ttl, true
+}
+
+// Range 遍历所有未过期的条目，存储支持 RangeableCache 时只遍历一次，否则按 Scan 游标分批遍历。
+func (p *storePersister[V]) Range(fn func(key string, value V, ttl time.Duration) bool) error {
+	ctx := context.Background()
+	if rc, ok := p.store.(commcache.RangeableCache[V]); ok {
+		return rc.Range(ctx, "", func(key string, value V, ttl time.Duration) bool {
+			if ttl == commcache.TTLNoExpire {
+				ttl = 0
+			}
+			return fn(key, value, ttl)
+		})
+	}
+	cursor := ""
+	for {
+		keys, next, err := p.store.Scan(ctx, "", cursor, persistScanCount)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			val, ttl, ok := p.Load(key)
+			if !ok {
+				continue
+			}
+			if !fn(key, val, ttl) {
+				return nil
+			}
+		}
+		if next == "" {
+			return nil
+		}
+		cursor = next
+	}
+}
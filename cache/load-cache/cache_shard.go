@@ -5,76 +5,240 @@ import (
 	"sync"
 )
 
+const (
+	windowPercent    = 0.01 // W-TinyLFU 窗口段占分片容量的比例
+	protectedPercent = 0.8  // 保护段占主段（试用段+保护段）容量的比例
+)
+
 // shard 表示缓存的一个分片，包含 LRU、容量、条目等。
-type shard struct {
-	mu       sync.Mutex               // 分片锁
-	items    map[string]*list.Element // key 到 LRU 节点的映射
-	lru      *list.List               // LRU 链表
-	capBytes int64                    // 分片最大容量（字节）
-	curBytes int64                    // 当前已用容量
+// 开启 TinyLFU 时按 W-TinyLFU 分为窗口段、试用段、保护段：新条目先进入窗口段，
+// 窗口段溢出的条目与试用段末尾的条目比较访问频率，频率更高的才能留在主段中。
+type shard[K comparable, V any] struct {
+	mu       sync.Mutex          // 分片锁
+	items    map[K]*list.Element // key 到 LRU 节点的映射
+	lists    [3]*list.List       // 各段的 LRU 链表，下标为 segment
+	segBytes [3]int64            // 各段已用容量
+	capBytes int64               // 分片最大容量（字节）
+	curBytes int64               // 当前已用容量
+	sketch   *countMinSketch     // 访问频率统计，为空表示未开启 TinyLFU
 }
 
 // newShard 创建一个新的分片。
-func newShard() *shard {
-	return &shard{
-		items: make(map[string]*list.Element),
-		lru:   list.New(),
+func newShard[K comparable, V any](capBytes int64, sketch *countMinSketch) *shard[K, V] {
+	return &shard[K, V]{
+		items:    make(map[K]*list.Element),
+		lists:    [3]*list.List{list.New(), list.New(), list.New()},
+		capBytes: capBytes,
+		sketch:   sketch,
 	}
 }
 
-// get 获取 key 对应的条目，并将其移到 LRU 前端。
-func (s *shard) get(key string) (*cacheItem, bool) {
+// get 获取 key 对应的未过期条目，并记录一次访问，已过期的条目直接删除。
+func (s *shard[K, V]) get(key K) (*cacheItem[K, V], bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ele, ok := s.items[key]
+	if !ok {
+		return nil, false
+	}
+	item := ele.Value.(*cacheItem[K, V])
+	if item.isExpired() {
+		s.removeElement(ele)
+		return nil, false
+	}
+	if s.sketch != nil {
+		s.sketch.increment(item.hash)
+	}
+	s.onAccess(ele)
+	return item, true
+}
+
+// peek 获取 key 对应的条目，不影响 LRU 顺序和访问频率。
+func (s *shard[K, V]) peek(key K) (*cacheItem[K, V], bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if ele, ok := s.items[key]; ok {
-		s.lru.MoveToFront(ele)
-		return ele.Value.(*cacheItem), true
+		return ele.Value.(*cacheItem[K, V]), true
 	}
 	return nil, false
 }
 
 // set 插入或更新条目，并根据权重调整容量，必要时驱逐。
-func (s *shard) set(item *cacheItem, weight int64) {
+func (s *shard[K, V]) set(item *cacheItem[K, V]) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if s.sketch != nil {
+		s.sketch.increment(item.hash)
+	}
 	if ele, ok := s.items[item.key]; ok {
-		s.lru.MoveToFront(ele)
-		oldItem := ele.Value.(*cacheItem)
-		s.curBytes -= weightOf(oldItem.value)
+		oldItem := ele.Value.(*cacheItem[K, V])
+		s.addBytes(oldItem.segment, item.weight-oldItem.weight)
+		item.segment = oldItem.segment
 		ele.Value = item
-		s.curBytes += weight
+		s.onAccess(ele)
+		s.evict()
 		return
 	}
 	// 新条目
-	ele := s.lru.PushFront(item)
-	s.items[item.key] = ele
-	s.curBytes += weight
+	item.segment = segmentWindow
+	s.items[item.key] = s.lists[segmentWindow].PushFront(item)
+	s.addBytes(segmentWindow, item.weight)
 	s.evict()
 }
 
 // delete 删除指定 key 的条目。
-func (s *shard) delete(key string) {
+func (s *shard[K, V]) delete(key K) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	if ele, ok := s.items[key]; ok {
-		item := ele.Value.(*cacheItem)
-		s.curBytes -= weightOf(item.value)
-		s.lru.Remove(ele)
-		delete(s.items, key)
+		s.removeElement(ele)
+	}
+}
+
+// purge 清空分片。
+func (s *shard[K, V]) purge() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items = make(map[K]*list.Element)
+	for i := range s.lists {
+		s.lists[i].Init()
+		s.segBytes[i] = 0
 	}
+	s.curBytes = 0
 }
 
-// evict 按 LRU 驱逐，直到容量满足要求。
-func (s *shard) evict() {
-	for s.capBytes > 0 && s.curBytes > s.capBytes && s.lru.Len() > 0 {
-		back := s.lru.Back()
-		if back == nil {
+// len 返回条目数量。
+func (s *shard[K, V]) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.items)
+}
+
+// bytes 返回已用容量。
+func (s *shard[K, V]) bytes() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.curBytes
+}
+
+// cleanExpired 增量清理过期条目：每轮随机抽查 batch 个条目，过期比例超过 1/4 时继续下一轮，
+// 避免一次遍历整个分片长时间持有锁。
+func (s *shard[K, V]) cleanExpired(batch int) {
+	for {
+		s.mu.Lock()
+		checked, expired := 0, 0
+		// map 遍历的起点是随机的，相当于随机抽查
+		for _, ele := range s.items {
+			if checked >= batch {
+				break
+			}
+			checked++
+			if ele.Value.(*cacheItem[K, V]).isExpired() {
+				s.removeElement(ele)
+				expired++
+			}
+		}
+		s.mu.Unlock()
+		if checked < batch || expired*4 <= checked {
+			return
+		}
+	}
+}
+
+// onAccess 命中后调整条目位置：试用段的条目晋升到保护段，保护段溢出时末尾的条目降级回试用段。
+func (s *shard[K, V]) onAccess(ele *list.Element) {
+	item := ele.Value.(*cacheItem[K, V])
+	if item.segment != segmentProbation {
+		s.lists[item.segment].MoveToFront(ele)
+		return
+	}
+	s.moveTo(ele, segmentProtected)
+	protectedCap := int64(float64(s.mainCap()) * protectedPercent)
+	for s.segBytes[segmentProtected] > protectedCap && s.lists[segmentProtected].Len() > 1 {
+		s.moveTo(s.lists[segmentProtected].Back(), segmentProbation)
+	}
+}
+
+// evict 按 LRU 驱逐，直到容量满足要求；开启 TinyLFU 时窗口段溢出的条目需要与试用段末尾的条目竞争准入。
+func (s *shard[K, V]) evict() {
+	if s.capBytes <= 0 {
+		return
+	}
+	if s.sketch == nil {
+		s.evictLRU(segmentWindow)
+		return
+	}
+
+	windowCap := int64(float64(s.capBytes) * windowPercent)
+	window := s.lists[segmentWindow]
+	for s.segBytes[segmentWindow] > windowCap && window.Len() > 0 {
+		s.admit(window.Back())
+	}
+	// 单个条目超过主段容量等情况，兜底按 LRU 驱逐
+	for _, seg := range []segment{segmentWindow, segmentProbation, segmentProtected} {
+		s.evictLRU(seg)
+	}
+}
+
+// evictLRU 从指定段的末尾驱逐，直到总容量满足要求。
+func (s *shard[K, V]) evictLRU(seg segment) {
+	for s.curBytes > s.capBytes && s.lists[seg].Len() > 0 {
+		s.removeElement(s.lists[seg].Back())
+	}
+}
+
+// admit 窗口段溢出的候选条目进入试用段，主段容量不足时与末尾的条目比较访问频率，频率低的被驱逐。
+func (s *shard[K, V]) admit(candidate *list.Element) {
+	cItem := candidate.Value.(*cacheItem[K, V])
+	mainCap := s.mainCap()
+	for s.mainBytes()+cItem.weight > mainCap {
+		victim := s.lists[segmentProbation].Back()
+		if victim == nil {
+			victim = s.lists[segmentProtected].Back()
+		}
+		if victim == nil {
 			break
 		}
-		item := back.Value.(*cacheItem)
-		s.curBytes -= weightOf(item.value)
-		delete(s.items, item.key)
-		s.lru.Remove(back)
+		vItem := victim.Value.(*cacheItem[K, V])
+		if s.sketch.estimate(cItem.hash) <= s.sketch.estimate(vItem.hash) {
+			s.removeElement(candidate)
+			return
+		}
+		s.removeElement(victim)
 	}
+	s.moveTo(candidate, segmentProbation)
+}
+
+// mainCap 主段（试用段+保护段）容量。
+func (s *shard[K, V]) mainCap() int64 {
+	return s.capBytes - int64(float64(s.capBytes)*windowPercent)
+}
+
+// mainBytes 主段已用容量。
+func (s *shard[K, V]) mainBytes() int64 {
+	return s.segBytes[segmentProbation] + s.segBytes[segmentProtected]
+}
+
+// moveTo 将条目移动到指定段的前端。
+func (s *shard[K, V]) moveTo(ele *list.Element, seg segment) {
+	item := ele.Value.(*cacheItem[K, V])
+	s.lists[item.segment].Remove(ele)
+	s.addBytes(item.segment, -item.weight)
+	item.segment = seg
+	s.items[item.key] = s.lists[seg].PushFront(item)
+	s.addBytes(seg, item.weight)
+}
+
+// removeElement 删除条目，按写入时的权重扣减容量。
+func (s *shard[K, V]) removeElement(ele *list.Element) {
+	item := ele.Value.(*cacheItem[K, V])
+	s.lists[item.segment].Remove(ele)
+	s.addBytes(item.segment, -item.weight)
+	delete(s.items, item.key)
+}
+
+func (s *shard[K, V]) addBytes(seg segment, delta int64) {
+	s.segBytes[seg] += delta
+	s.curBytes += delta
 }
@@ -1,17 +1,162 @@
 package cache
 
-//c := cache.NewCache(cache.CacheOptions{
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+//c := cache.NewCache(cache.CacheOptions[string, string]{
 //DefaultTTL:    10 * time.Second,
 //EmptyTTL:      2 * time.Second,
 //RefreshFactor: 0.3,
 //ShardCount:    8,
 //TotalMaxBytes: 1 << 20,
+//TinyLFU:       true,
 //})
 //
 //// 带 context 的加载器
-//loader := func(ctx context.Context) (interface{}, time.Duration, error) {
+//loader := func(ctx context.Context) (string, time.Duration, error) {
 //	// 从 DB / 网络加载
 //	return "value", 5 * time.Second, nil
 //}
 //
 //val, err := c.GetOrLoadCtx(context.Background(), "key-1", loader)
+
+func TestCacheWeigher(t *testing.T) {
+	c := NewCache(CacheOptions[string, []int]{
+		ShardCount:    1,
+		TotalMaxBytes: 10,
+		Weigher: func(key string, val []int) int64 {
+			return int64(len(val))
+		},
+	})
+	c.SetWithTTL("a", make([]int, 4), time.Minute)
+	c.SetWithTTL("b", make([]int, 4), time.Minute)
+	c.SetWithTTL("c", make([]int, 4), time.Minute)
+
+	if _, ok := c.Get("a"); ok {
+		t.Error("a should be evicted by weight")
+	}
+	if c.shards[0].curBytes != 8 {
+		t.Errorf("unexpected bytes %d", c.shards[0].curBytes)
+	}
+}
+
+func TestCacheLoadNil(t *testing.T) {
+	c := NewCache(CacheOptions[string, any]{EmptyTTL: time.Minute})
+	loader := func(ctx context.Context) (any, time.Duration, error) {
+		return nil, 0, nil
+	}
+	// 第二次命中缓存的空值
+	for i := 0; i < 2; i++ {
+		if v, err := c.GetOrLoadCtx(context.Background(), "a", loader); err != nil || v != nil {
+			t.Fatalf("unexpected %v, %v", v, err)
+		}
+	}
+}
+
+func TestCacheTinyLFUScanResistance(t *testing.T) {
+	c := NewCache(CacheOptions[int, int]{
+		ShardCount:    1,
+		TotalMaxBytes: 100,
+		TinyLFU:       true,
+	})
+	// 热点数据多次访问
+	for i := 0; i < 50; i++ {
+		c.SetWithTTL(i, i, time.Minute)
+	}
+	for round := 0; round < 5; round++ {
+		for i := 0; i < 50; i++ {
+			c.Get(i)
+		}
+	}
+	// 一次性扫描大量冷数据
+	for i := 1000; i < 2000; i++ {
+		c.SetWithTTL(i, i, time.Minute)
+	}
+
+	hits := 0
+	for i := 0; i < 50; i++ {
+		if _, ok := c.Get(i); ok {
+			hits++
+		}
+	}
+	if hits < 45 {
+		t.Errorf("hot keys should survive scan, hits=%d", hits)
+	}
+}
+
+func TestCommCacheAdapter(t *testing.T) {
+	ctx := context.Background()
+	c := NewCache(CacheOptions[string, string]{DefaultTTL: time.Minute})
+	cc := NewCommCache(c)
+	_, _ = cc.Set(ctx, "k", "v", 0)
+	if v, _ := cc.Get(ctx, "k"); v != "v" {
+		t.Errorf("expected v, got %q", v)
+	}
+
+	val, err := c.GetOrLoadCtx(ctx, "load", func(ctx context.Context) (string, time.Duration, error) {
+		return "loaded", 0, nil
+	})
+	if err != nil || val != "loaded" {
+		t.Errorf("unexpected %q, %v", val, err)
+	}
+}
+
+func TestCacheCleanExpired(t *testing.T) {
+	c := NewCache(CacheOptions[string, string]{ShardCount: 2, CleanupInterval: 20 * time.Millisecond})
+	defer c.Close()
+
+	for i := 0; i < 200; i++ {
+		c.SetWithTTL(fmt.Sprintf("k%d", i), "val", 10*time.Millisecond)
+	}
+	c.SetWithTTL("keep", "value", time.Minute)
+	if c.Len() != 201 || c.Bytes() != 200*3+5 {
+		t.Fatalf("unexpected stats len=%d bytes=%d", c.Len(), c.Bytes())
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if c.Len() != 1 || c.Bytes() != 5 {
+		t.Errorf("expired items should be swept, len=%d bytes=%d", c.Len(), c.Bytes())
+	}
+	var total int64
+	for i := 0; i < c.ShardCount(); i++ {
+		total += c.ShardBytes(i)
+	}
+	if total != c.Bytes() {
+		t.Errorf("shard bytes %d != total %d", total, c.Bytes())
+	}
+}
+
+func TestCacheWarmStart(t *testing.T) {
+	p, err := NewDiskPersister[string](t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewCache(CacheOptions[string, string]{Persister: p, BackgroundJobs: 1})
+	c.SetWithTTL("a", "aaa", time.Minute)
+	time.Sleep(50 * time.Millisecond)
+	_ = p.Save("b", "bbb", time.Minute)
+	_ = p.Save("c", "ccc", time.Millisecond)
+	c.Close()
+	time.Sleep(10 * time.Millisecond)
+
+	warm := NewCache(CacheOptions[string, string]{Persister: p, WarmStart: true})
+	defer warm.Close()
+	for _, key := range []string{"a", "b"} {
+		if v, ok := warm.Get(key); !ok || v != key+key+key {
+			t.Errorf("%s should be rehydrated, got %q", key, v)
+		}
+	}
+	if _, ok := warm.Get("c"); ok {
+		t.Error("expired entry should not be rehydrated")
+	}
+
+	bounded := NewCache(CacheOptions[string, string]{Persister: p, ShardCount: 1, TotalMaxBytes: 3})
+	defer bounded.Close()
+	if n, err := bounded.Rehydrate(); err != nil || n != 1 || bounded.Bytes() != 3 {
+		t.Errorf("rehydrate should stop at TotalMaxBytes, n=%d bytes=%d err=%v", n, bounded.Bytes(), err)
+	}
+}
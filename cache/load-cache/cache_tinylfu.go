@@ -0,0 +1,73 @@
+package cache
+
+// sketchDepth count-min sketch 的行数。
+const sketchDepth = 4
+
+// sketchSeeds 每一行使用不同的种子打散哈希。
+var sketchSeeds = [sketchDepth]uint64{0xc3a5c85c97cb3127, 0xb492b66fbe98f273, 0x9ae16a3b2f90404f, 0xcbf29ce484222325}
+
+// countMinSketch 近似统计访问频率，每个计数器最大 15，
+// 累计增加次数达到阈值后全部减半，使频率随时间衰减，旧的热点会逐渐被淘汰。
+type countMinSketch struct {
+	rows      [sketchDepth][]uint8
+	mask      uint64
+	additions int
+	resetAt   int
+}
+
+// newCountMinSketch 创建频率统计，width 向上取整为 2 的幂。
+func newCountMinSketch(width int) *countMinSketch {
+	w := 16
+	for w < width {
+		w <<= 1
+	}
+	s := &countMinSketch{
+		mask:    uint64(w - 1),
+		resetAt: w * 10,
+	}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, w)
+	}
+	return s
+}
+
+// indexOf 计算第 i 行的计数器下标。
+func (s *countMinSketch) indexOf(hash uint64, i int) uint64 {
+	h := (hash ^ sketchSeeds[i]) * 0x9e3779b97f4a7c15
+	return (h ^ h>>32) & s.mask
+}
+
+// increment 记录一次访问。
+func (s *countMinSketch) increment(hash uint64) {
+	for i := range s.rows {
+		idx := s.indexOf(hash, i)
+		if s.rows[i][idx] < 15 {
+			s.rows[i][idx]++
+		}
+	}
+	s.additions++
+	if s.additions >= s.resetAt {
+		s.reset()
+	}
+}
+
+// estimate 返回估算的访问频率，取各行的最小值。
+func (s *countMinSketch) estimate(hash uint64) uint8 {
+	var minCount uint8 = 15
+	for i := range s.rows {
+		if c := s.rows[i][s.indexOf(hash, i)]; c < minCount {
+			minCount = c
+		}
+	}
+	return minCount
+}
+
+// reset 所有计数器减半。
+func (s *countMinSketch) reset() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+	s.additions /= 2
+}
@@ -3,26 +3,37 @@ package cache
 import "time"
 
 // SetWithTTL 设置带过期时间的缓存条目。
-func (c *Cache) SetWithTTL(key string, value interface{}, ttl time.Duration) {
-	item := &cacheItem{
+func (c *Cache[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	c.setWithDelta(key, value, ttl, 0)
+}
+
+// setWithDelta 设置缓存条目，并记录加载耗时。
+func (c *Cache[K, V]) setWithDelta(key K, value V, ttl time.Duration, delta time.Duration) {
+	if c.ttlJitter != nil {
+		ttl = c.ttlJitter.Apply(keyString(key), ttl)
+	}
+	hash := c.hashOf(key)
+	item := &cacheItem[K, V]{
 		key:        key,
 		value:      value,
+		hash:       hash,
+		weight:     c.weightOf(key, value),
 		ttl:        ttl,
 		expiration: time.Now().Add(ttl).UnixNano(),
+		delta:      delta,
 	}
-	sh := c.getShard(key)
-	sh.set(item, weightOf(value))
+	c.getShard(hash).set(item)
 	if c.persister != nil {
-		c.enqueuePersist(key, value)
+		c.enqueuePersist(key, value, ttl)
 	}
 }
 
-// Get 获取 key 对应的缓存值，若过期则返回 nil。
-func (c *Cache) Get(key string) (interface{}, bool) {
-	sh := c.getShard(key)
-	item, ok := sh.get(key)
+// Get 获取 key 对应的缓存值，若过期则返回零值。
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	item, ok := c.getShard(c.hashOf(key)).get(key)
 	if !ok || item.isExpired() {
-		return nil, false
+		var zero V
+		return zero, false
 	}
 	return item.value, true
 }
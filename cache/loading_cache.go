@@ -0,0 +1,285 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/magic-lib/go-plat-cache/cache/internal/xfetch"
+	"golang.org/x/sync/singleflight"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultLoadLockTTL  = 10 * time.Second      // 跨进程加载锁的默认有效期
+	defaultLoadLockWait = 3 * time.Second       // 未抢到锁时默认等待的时长
+	loadWaitInterval    = 50 * time.Millisecond // 等待其他进程加载时查询缓存的间隔
+	loadLockPrefix      = "__load_lock:"        // 加载锁 key 的前缀
+	loadDeltaPrefix     = "__load_delta:"       // 加载耗时 key 的前缀，与值存在同一个存储中
+
+	defaultDeltaExpiration = time.Hour // 加载耗时未指定有效期时的保留时长
+)
+
+var defaultLoadGroup singleflight.Group // GetOrLoad 共用的 singleflight
+
+// LoadingOptions LoadingCache 的配置
+type LoadingOptions struct {
+	DefaultTTL  time.Duration // loader 未返回有效期时使用的时长
+	NegativeTTL time.Duration // loader 返回 ErrNotFound 时本地记录不存在的时长，<=0 不做负缓存
+	Locker      CommLocker    // 跨进程加载锁，为空时只在进程内去重
+	LockTTL     time.Duration // 加载锁的有效期
+	LockWait    time.Duration // 未抢到锁时等待其他进程加载完成的最长时间，超时后自己加载
+	// Beta XFetch 系数，>0 时命中后按加载耗时概率提前异步刷新，需要缓存实现 ExpiringCache 以获取剩余有效期。
+	// 缓存实现了 CounterCache 时加载耗时在写入值之前记录到同一个存储中，多个进程共用，否则记录在本地
+	Beta float64
+}
+
+// loadingCache 未命中时自动加载的缓存
+type loadingCache[V any] struct {
+	cCache       CommCache[V]
+	loader       LoaderFunc[V]
+	opt          LoadingOptions
+	group        *singleflight.Group
+	flightPrefix string                   // singleflight key 的前缀，共用 group 时区分不同的缓存
+	negative     CommCache[bool]          // 负缓存，记录不存在的 key
+	deltas       CommCache[time.Duration] // 每个 key 的加载耗时，XFetch 使用，缓存未实现 CounterCache 时使用
+	deltaCounter CounterCache             // 与值存在一起的加载耗时(微秒)
+	lastDelta    atomic.Int64             // 最近一次的加载耗时
+}
+
+// NewLoadingCache 新建未命中时自动加载的缓存，loader 为 Get 未命中时使用的默认加载函数，可为空
+func NewLoadingCache[V any](cCache CommCache[V], loader LoaderFunc[V], opt ...*LoadingOptions) (LoadingCache[V], error) {
+	if cCache == nil {
+		return nil, fmt.Errorf("loading cache: cache is nil")
+	}
+	co := &loadingCache[V]{
+		cCache: cCache,
+		loader: loader,
+		group:  new(singleflight.Group),
+	}
+	if len(opt) > 0 && opt[0] != nil {
+		co.opt = *opt[0]
+	}
+	if co.opt.LockTTL <= 0 {
+		co.opt.LockTTL = defaultLoadLockTTL
+	}
+	if co.opt.LockWait <= 0 {
+		co.opt.LockWait = defaultLoadLockWait
+	}
+	if co.opt.NegativeTTL > 0 {
+		co.negative = NewMemGoCache[bool](co.opt.NegativeTTL, 2*co.opt.NegativeTTL)
+	}
+	if co.opt.Beta > 0 {
+		if _, ok := cCache.(ExpiringCache); !ok {
+			return nil, fmt.Errorf("loading cache: %T not implement ExpiringCache, can not use Beta", cCache)
+		}
+		if counter, ok := cCache.(CounterCache); ok {
+			co.deltaCounter = counter
+		} else {
+			co.deltas = NewMemGoCache[time.Duration](defaultDeltaExpiration, defaultDeltaExpiration)
+		}
+	}
+	return co, nil
+}
+
+// GetOrLoad 先查缓存，未命中时用 loader 加载并以 ttl 写入，进程内并发加载同一个 key 时只执行一次。
+// 缓存中的零值视为未命中
+func GetOrLoad[V any](ctx context.Context, co CommCache[V], key string, ttl time.Duration, loader LoaderFunc[V]) (V, error) {
+	lc := &loadingCache[V]{
+		cCache:       co,
+		opt:          LoadingOptions{DefaultTTL: ttl},
+		group:        &defaultLoadGroup,
+		flightPrefix: fmt.Sprintf("%p|", co),
+	}
+	return lc.GetOrLoad(ctx, key, loader)
+}
+
+// Get 取值，设置了默认 loader 时未命中会自动加载
+func (co *loadingCache[V]) Get(ctx context.Context, key string) (V, error) {
+	if co.loader == nil {
+		return co.cCache.Get(ctx, key)
+	}
+	return co.GetOrLoad(ctx, key, co.loader)
+}
+
+// Set 写入，同时清除负缓存
+func (co *loadingCache[V]) Set(ctx context.Context, key string, val V, timeout time.Duration) (bool, error) {
+	co.clearNegative(ctx, key)
+	return co.cCache.Set(ctx, key, val, timeout)
+}
+
+// Del 从缓存中删除一个key
+func (co *loadingCache[V]) Del(ctx context.Context, key string) (bool, error) {
+	co.clearNegative(ctx, key)
+	return co.cCache.Del(ctx, key)
+}
+
+// GetOrLoad 先查缓存，未命中时用 loader 加载并写入
+func (co *loadingCache[V]) GetOrLoad(ctx context.Context, key string, loader LoaderFunc[V]) (V, error) {
+	var zero V
+	if loader == nil {
+		return zero, fmt.Errorf("loading cache: loader is nil")
+	}
+	if v, ok := co.getCached(ctx, key); ok {
+		co.earlyRefresh(ctx, key, loader)
+		return v, nil
+	}
+	if co.negative != nil {
+		if notFound, _ := co.negative.Get(ctx, key); notFound {
+			return zero, ErrNotFound
+		}
+	}
+
+	// 多个调用方共用一次加载，不能因为先到的调用方取消而让其他调用方一起失败
+	ch := co.group.DoChan(co.flightPrefix+key, func() (interface{}, error) {
+		return co.load(context.WithoutCancel(ctx), key, loader)
+	})
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return zero, res.Err
+		}
+		// V 为接口时加载结果可能是 nil，直接断言会 panic
+		v, _ := res.Val.(V)
+		return v, nil
+	}
+}
+
+// getCached 查询缓存，出错或零值都视为未命中
+func (co *loadingCache[V]) getCached(ctx context.Context, key string) (V, bool) {
+	v, err := co.cCache.Get(ctx, key)
+	if err != nil || isZeroValue(v) {
+		return v, false
+	}
+	return v, true
+}
+
+// load 加载数据，配置了 Locker 时先抢跨进程的加载锁，没抢到则等待其他进程加载完成，抢锁出错时直接加载
+func (co *loadingCache[V]) load(ctx context.Context, key string, loader LoaderFunc[V]) (V, error) {
+	if co.opt.Locker != nil {
+		lockKey := loadLockPrefix + key
+		lockId, err := co.opt.Locker.TryLock(ctx, lockKey, co.opt.LockTTL)
+		switch {
+		case err != nil:
+			log.Printf("loading cache lock %s: %v", key, err)
+		case lockId != "":
+			defer func() {
+				_, _ = co.opt.Locker.UnLock(context.Background(), lockKey, lockId)
+			}()
+			// 抢到锁后再查一次，其他进程可能刚加载完
+			if v, ok := co.getCached(ctx, key); ok {
+				return v, nil
+			}
+		default:
+			if v, ok := co.waitLoaded(ctx, key); ok {
+				return v, nil
+			}
+		}
+	}
+	return co.loadAndSet(ctx, key, loader)
+}
+
+// waitLoaded 等待其他进程加载完成
+func (co *loadingCache[V]) waitLoaded(ctx context.Context, key string) (V, bool) {
+	timer := time.NewTimer(co.opt.LockWait)
+	defer timer.Stop()
+	ticker := time.NewTicker(loadWaitInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			var zero V
+			return zero, false
+		case <-timer.C:
+			var zero V
+			return zero, false
+		case <-ticker.C:
+			if v, ok := co.getCached(ctx, key); ok {
+				return v, true
+			}
+		}
+	}
+}
+
+// earlyRefresh XFetch 按概率提前异步刷新，越接近过期、加载越慢，刷新的概率越大
+func (co *loadingCache[V]) earlyRefresh(ctx context.Context, key string, loader LoaderFunc[V]) {
+	if co.opt.Beta <= 0 {
+		return
+	}
+	remain, err := co.cCache.(ExpiringCache).TTL(ctx, key)
+	if err != nil || remain <= 0 {
+		return
+	}
+	delta := co.getDelta(ctx, key)
+	if delta <= 0 {
+		delta = time.Duration(co.lastDelta.Load())
+	}
+	if !xfetch.ShouldRefresh(delta, time.Now().Add(remain), co.opt.Beta) {
+		return
+	}
+	go func() {
+		_, _, _ = co.group.Do(co.flightPrefix+key, func() (interface{}, error) {
+			return co.loadAndSet(context.Background(), key, loader)
+		})
+	}()
+}
+
+// loadAndSet 调用 loader 并写入缓存，写入失败不影响返回结果
+func (co *loadingCache[V]) loadAndSet(ctx context.Context, key string, loader LoaderFunc[V]) (V, error) {
+	start := time.Now()
+	v, ttl, err := loader(ctx, key)
+	if err != nil {
+		if co.negative != nil && errors.Is(err, ErrNotFound) {
+			_, _ = co.negative.Set(ctx, key, true, co.opt.NegativeTTL)
+		}
+		return v, err
+	}
+	if ttl <= 0 {
+		ttl = co.opt.DefaultTTL
+	}
+	if co.opt.Beta > 0 {
+		co.setDelta(ctx, key, time.Since(start), ttl)
+	}
+	if _, err = co.cCache.Set(ctx, key, v, ttl); err != nil {
+		log.Printf("loading cache set %s: %v", key, err)
+	}
+	co.clearNegative(ctx, key)
+	return v, nil
+}
+
+// getDelta 取 key 最近一次的加载耗时，没有记录时返回 0
+func (co *loadingCache[V]) getDelta(ctx context.Context, key string) time.Duration {
+	if co.deltaCounter != nil {
+		micros, _ := co.deltaCounter.GetCounter(ctx, loadDeltaPrefix+key)
+		return time.Duration(micros) * time.Microsecond
+	}
+	delta, _ := co.deltas.Get(ctx, key)
+	return delta
+}
+
+// setDelta 记录加载耗时，有效期与值一致。计数器只能累加，先删除旧的记录再写入
+func (co *loadingCache[V]) setDelta(ctx context.Context, key string, delta time.Duration, ttl time.Duration) {
+	co.lastDelta.Store(int64(delta))
+	if co.deltaCounter == nil {
+		_, _ = co.deltas.Set(ctx, key, delta, ttl)
+		return
+	}
+	deltaKey := loadDeltaPrefix + key
+	if _, err := co.cCache.Del(ctx, deltaKey); err != nil {
+		log.Printf("loading cache del %s: %v", deltaKey, err)
+		return
+	}
+	if _, err := co.deltaCounter.IncrBy(ctx, deltaKey, max(delta.Microseconds(), 1), ttl); err != nil {
+		log.Printf("loading cache set %s: %v", deltaKey, err)
+	}
+}
+
+func (co *loadingCache[V]) clearNegative(ctx context.Context, key string) {
+	if co.negative != nil {
+		_, _ = co.negative.Del(ctx, key)
+	}
+}
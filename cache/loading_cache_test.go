@@ -0,0 +1,172 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"github.com/magic-lib/go-plat-cache/cache"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetOrLoad(t *testing.T) {
+	ctx := context.Background()
+	lruCache := cache.NewMemLruCache[string](10, time.Minute)
+
+	var loadCount atomic.Int32
+	loader := func(ctx context.Context, key string) (string, time.Duration, error) {
+		loadCount.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		return "val:" + key, 0, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := cache.GetOrLoad[string](ctx, lruCache, "a", time.Minute, loader)
+			if err != nil || v != "val:a" {
+				t.Errorf("unexpected %q, %v", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := loadCount.Load(); n != 1 {
+		t.Errorf("loader should run once, got %d", n)
+	}
+	if v, _ := lruCache.Get(ctx, "a"); v != "val:a" {
+		t.Errorf("loaded value should be cached, got %q", v)
+	}
+}
+
+func TestLoadingCacheNegative(t *testing.T) {
+	ctx := context.Background()
+	var loadCount atomic.Int32
+	lc, err := cache.NewLoadingCache[string](cache.NewMemLruCache[string](10, time.Minute),
+		func(ctx context.Context, key string) (string, time.Duration, error) {
+			loadCount.Add(1)
+			return "", 0, cache.ErrNotFound
+		}, &cache.LoadingOptions{NegativeTTL: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err = lc.Get(ctx, "missing"); !errors.Is(err, cache.ErrNotFound) {
+			t.Fatalf("expected ErrNotFound, got %v", err)
+		}
+	}
+	if n := loadCount.Load(); n != 1 {
+		t.Errorf("negative result should be cached, loader ran %d times", n)
+	}
+
+	_, _ = lc.Set(ctx, "missing", "now exists", 0)
+	if v, _ := lc.Get(ctx, "missing"); v != "now exists" {
+		t.Errorf("Set should clear negative cache, got %q", v)
+	}
+}
+
+// brokenLocker 模拟锁服务不可用
+type brokenLocker struct {
+	cache.CommLocker
+}
+
+func (brokenLocker) TryLock(context.Context, string, time.Duration) (string, error) {
+	return "", errors.New("locker unavailable")
+}
+
+func TestLoadingCacheLockError(t *testing.T) {
+	ctx := context.Background()
+	lc, err := cache.NewLoadingCache[string](cache.NewMemLruCache[string](10, time.Minute),
+		func(ctx context.Context, key string) (string, time.Duration, error) {
+			return "val:" + key, 0, nil
+		}, &cache.LoadingOptions{Locker: brokenLocker{}, LockWait: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if v, err := lc.Get(ctx, "a"); err != nil || v != "val:a" {
+		t.Fatalf("unexpected %q, %v", v, err)
+	}
+	if cost := time.Since(start); cost >= time.Second {
+		t.Errorf("lock error should load immediately, cost %v", cost)
+	}
+}
+
+func TestGetOrLoadCanceled(t *testing.T) {
+	lruCache := cache.NewMemLruCache[string](10, time.Minute)
+	started := make(chan struct{})
+	var once sync.Once
+	loader := func(ctx context.Context, key string) (string, time.Duration, error) {
+		once.Do(func() { close(started) })
+		time.Sleep(100 * time.Millisecond)
+		if err := ctx.Err(); err != nil {
+			return "", 0, err
+		}
+		return "val:" + key, 0, nil
+	}
+
+	cancelCtx, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := cache.GetOrLoad[string](cancelCtx, lruCache, "a", time.Minute, loader)
+		firstErr <- err
+	}()
+	<-started
+
+	// 第二个调用方共用第一个调用方发起的加载，第一个调用方取消不影响它
+	second := make(chan string, 1)
+	go func() {
+		v, _ := cache.GetOrLoad[string](context.Background(), lruCache, "a", time.Minute, loader)
+		second <- v
+	}()
+	cancel()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled caller should return context.Canceled, got %v", err)
+	}
+	if v := <-second; v != "val:a" {
+		t.Errorf("other caller should get loaded value, got %q", v)
+	}
+}
+
+func TestLoadingCacheSharedDelta(t *testing.T) {
+	ctx := context.Background()
+	boltCache, err := cache.NewBBoltCache[string](&cache.BBoltCacheConfig{
+		DbPath: filepath.Join(t.TempDir(), "loading.db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	loader := func(ctx context.Context, key string) (string, time.Duration, error) {
+		time.Sleep(20 * time.Millisecond)
+		return "val:" + key, time.Minute, nil
+	}
+	lc, err := cache.NewLoadingCache[string](boltCache, loader, &cache.LoadingOptions{Beta: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := lc.Get(ctx, "a"); err != nil || v != "val:a" {
+		t.Fatalf("unexpected %q, %v", v, err)
+	}
+
+	// 加载耗时与值存在同一个存储中，其他进程也能读到
+	micros, _ := boltCache.(cache.CounterCache).GetCounter(ctx, "__load_delta:a")
+	if delta := time.Duration(micros) * time.Microsecond; delta < 20*time.Millisecond || delta > time.Second {
+		t.Errorf("unexpected stored delta %v", delta)
+	}
+}
+
+func TestGetOrLoadNil(t *testing.T) {
+	// V 为接口时加载到 nil 不能 panic
+	lruCache := cache.NewMemLruCache[any](10, time.Minute)
+	v, err := cache.GetOrLoad[any](context.Background(), lruCache, "a", time.Minute, func(ctx context.Context, key string) (any, time.Duration, error) {
+		return nil, 0, nil
+	})
+	if err != nil || v != nil {
+		t.Errorf("unexpected %v, %v", v, err)
+	}
+}
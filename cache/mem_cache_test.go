@@ -0,0 +1,75 @@
+package cache_test
+
+import (
+	"context"
+	"github.com/magic-lib/go-plat-cache/cache"
+	"testing"
+	"time"
+)
+
+func TestMemLruCacheTimeout(t *testing.T) {
+	ctx := context.Background()
+	lruCache := cache.NewMemLruCache[string](10, time.Hour)
+
+	_, _ = lruCache.Set(ctx, "short", "a", 50*time.Millisecond)
+	_, _ = lruCache.Set(ctx, "default", "b", 0)
+
+	time.Sleep(100 * time.Millisecond)
+
+	if v, _ := lruCache.Get(ctx, "short"); v != "" {
+		t.Errorf("short key should expired, got %q", v)
+	}
+	if v, _ := lruCache.Get(ctx, "default"); v != "b" {
+		t.Errorf("default key should exist, got %q", v)
+	}
+}
+
+func TestFastCacheTimeout(t *testing.T) {
+	ctx := context.Background()
+	fc := cache.NewFastCache[string](0)
+
+	_, _ = fc.Set(ctx, "short", "a", 50*time.Millisecond)
+	_, _ = fc.Set(ctx, "forever", "b", 0)
+
+	if v, _ := fc.Get(ctx, "short"); v != "a" {
+		t.Errorf("short key should exist, got %q", v)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if v, _ := fc.Get(ctx, "short"); v != "" {
+		t.Errorf("short key should expired, got %q", v)
+	}
+	if v, _ := fc.Get(ctx, "forever"); v != "b" {
+		t.Errorf("forever key should exist, got %q", v)
+	}
+}
+
+func TestSlidingCache(t *testing.T) {
+	ctx := context.Background()
+	sc, err := cache.NewSlidingCache[string](cache.NewMemLruCache[string](10, 0), 150*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = sc.Set(ctx, "key", "val", 0)
+
+	// 连续读取，每次都会续期，总时长超过 ttl 也不会过期
+	for i := 0; i < 4; i++ {
+		time.Sleep(80 * time.Millisecond)
+		if v, _ := sc.Get(ctx, "key"); v != "val" {
+			t.Fatalf("round %d: key should exist, got %q", i, v)
+		}
+	}
+
+	ttl, _ := sc.(cache.ExpiringCache).TTL(ctx, "key")
+	if ttl <= 0 || ttl > 150*time.Millisecond {
+		t.Errorf("unexpected ttl %v", ttl)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if v, _ := sc.Get(ctx, "key"); v != "" {
+		t.Errorf("key should expired, got %q", v)
+	}
+	if ttl, _ = sc.(cache.ExpiringCache).TTL(ctx, "key"); ttl != cache.TTLNotFound {
+		t.Errorf("expired key ttl should be TTLNotFound, got %v", ttl)
+	}
+}
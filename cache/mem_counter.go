@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// memCounterItem 计数器条目，过期时间在创建后不再变化
+type memCounterItem struct {
+	value     atomic.Int64
+	expiresAt int64 // 过期时间(unix nano), 0 永不过期
+}
+
+func (it *memCounterItem) isExpired(now time.Time) bool {
+	return it.expiresAt > 0 && now.UnixNano() > it.expiresAt
+}
+
+// memCounter 无锁的内存计数器，单实例限流、配额使用
+type memCounter struct {
+	items  sync.Map // key -> *memCounterItem
+	nsGens nsGenerationCache
+}
+
+// NewMemCounter 新建内存计数器，cleanupInterval>0 时定期清理过期的计数器
+func NewMemCounter(cleanupInterval time.Duration) CounterCache {
+	mc := &memCounter{}
+	if cleanupInterval > 0 {
+		go mc.cleanExpiredLoop(cleanupInterval)
+	}
+	return mc
+}
+
+// IncrBy 原子增加计数，不存在或已过期时从 0 开始计数
+func (mc *memCounter) IncrBy(_ context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	now := time.Now()
+	for {
+		if v, ok := mc.items.Load(key); ok {
+			item := v.(*memCounterItem)
+			if !item.isExpired(now) {
+				return item.value.Add(delta), nil
+			}
+			// 已过期，用新的计数器替换，替换失败说明被其他协程抢先，重新读取
+			if mc.items.CompareAndSwap(key, item, newMemCounterItem(delta, ttl, now)) {
+				return delta, nil
+			}
+			continue
+		}
+		if _, loaded := mc.items.LoadOrStore(key, newMemCounterItem(delta, ttl, now)); !loaded {
+			return delta, nil
+		}
+	}
+}
+
+// DecrBy 原子减少计数
+func (mc *memCounter) DecrBy(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	return mc.IncrBy(ctx, key, -delta, ttl)
+}
+
+// GetCounter 获取计数，不存在或已过期返回 0
+func (mc *memCounter) GetCounter(_ context.Context, key string) (int64, error) {
+	v, ok := mc.items.Load(key)
+	if !ok {
+		return 0, nil
+	}
+	item := v.(*memCounterItem)
+	if item.isExpired(time.Now()) {
+		return 0, nil
+	}
+	return item.value.Load(), nil
+}
+
+func (mc *memCounter) nsGenerationCache() *nsGenerationCache {
+	return &mc.nsGens
+}
+
+func newMemCounterItem(value int64, ttl time.Duration, now time.Time) *memCounterItem {
+	item := &memCounterItem{
+		expiresAt: expiresAtOf(ttl, now),
+	}
+	item.value.Store(value)
+	return item
+}
+
+// cleanExpiredLoop 定期清理过期的计数器
+func (mc *memCounter) cleanExpiredLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		now := time.Now()
+		mc.items.Range(func(key, value any) bool {
+			if value.(*memCounterItem).isExpired(now) {
+				mc.items.CompareAndDelete(key, value)
+			}
+			return true
+		})
+	}
+}
@@ -3,12 +3,21 @@ package cache
 import (
 	"context"
 	gCache "github.com/patrickmn/go-cache"
+	"strings"
+	"sync"
 	"time"
 )
 
+// memGoItem go-cache中存储的值，记录写入时的有效期
+type memGoItem[V any] struct {
+	value V
+	ttl   time.Duration // 写入时的有效期，<0 永不过期
+}
+
 type memGoCache[V any] struct {
 	defaultExpiration, cleanupInterval time.Duration
 	mCache                             *gCache.Cache
+	mu                                 sync.Mutex // 保证读改写的原子性
 }
 
 // NewMemGoCache 新建memGoCache
@@ -24,21 +33,109 @@ func NewMemGoCache[V any](defaultExpiration, cleanupInterval time.Duration) Comm
 func (co *memGoCache[V]) Get(_ context.Context, key string) (v V, err error) {
 	ret, ok := co.mCache.Get(key)
 	if ok {
-		if retVal, ok := ret.(V); ok {
-			return retVal, nil
+		if retVal, ok := ret.(*memGoItem[V]); ok {
+			return retVal.value, nil
 		}
 	}
 	return v, nil
 }
 
-// Set timeout为秒
+// Set timeout为0时使用默认过期时间，小于0永不过期
 func (co *memGoCache[V]) Set(_ context.Context, key string, val V, timeout time.Duration) (bool, error) {
-	co.mCache.Set(key, val, timeout)
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	co.set(key, val, timeout)
 	return true, nil
 }
 
 // Del 从缓存中删除一个key
 func (co *memGoCache[V]) Del(_ context.Context, key string) (bool, error) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
 	co.mCache.Delete(key)
 	return true, nil
 }
+
+// TTL 返回剩余有效期
+func (co *memGoCache[V]) TTL(_ context.Context, key string) (time.Duration, error) {
+	_, expiration, ok := co.mCache.GetWithExpiration(key)
+	if !ok {
+		return TTLNotFound, nil
+	}
+	if expiration.IsZero() {
+		return TTLNoExpire, nil
+	}
+	return ttlOf(expiration.UnixNano(), time.Now()), nil
+}
+
+// Expire 重新设置过期时间
+func (co *memGoCache[V]) Expire(_ context.Context, key string, ttl time.Duration) (bool, error) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	item, ok := co.getItem(key)
+	if !ok {
+		return false, nil
+	}
+	co.set(key, item.value, ttl)
+	return true, nil
+}
+
+// Touch 按写入时的时长续期
+func (co *memGoCache[V]) Touch(_ context.Context, key string) (bool, error) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	item, ok := co.getItem(key)
+	if !ok {
+		return false, nil
+	}
+	co.set(key, item.value, item.ttl)
+	return true, nil
+}
+
+func (co *memGoCache[V]) getItem(key string) (*memGoItem[V], bool) {
+	ret, ok := co.mCache.Get(key)
+	if !ok {
+		return nil, false
+	}
+	item, ok := ret.(*memGoItem[V])
+	return item, ok
+}
+
+// set 写入条目，调用方需持有锁
+func (co *memGoCache[V]) set(key string, val V, timeout time.Duration) {
+	if timeout == gCache.DefaultExpiration {
+		timeout = co.defaultExpiration
+	}
+	co.mCache.Set(key, &memGoItem[V]{
+		value: val,
+		ttl:   timeout,
+	}, timeout)
+}
+
+// Scan 按游标遍历以 prefix 开头的 key
+func (co *memGoCache[V]) Scan(_ context.Context, prefix string, cursor string, count int) ([]string, string, error) {
+	keys, next := scanSortedKeys(co.keys(), prefix, cursor, count)
+	return keys, next, nil
+}
+
+// DeletePrefix 删除以 prefix 开头的所有 key
+func (co *memGoCache[V]) DeletePrefix(_ context.Context, prefix string) (int64, error) {
+	var n int64
+	for _, key := range co.keys() {
+		if strings.HasPrefix(key, prefix) {
+			co.mCache.Delete(key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// keys 返回未过期的全部 key
+func (co *memGoCache[V]) keys() []string {
+	items := co.mCache.Items()
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,146 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memHashItem 一个 key 下的所有 field
+type memHashItem[V any] struct {
+	fields    map[string]V
+	expiresAt int64 // 过期时间(unix nano), 0 永不过期
+}
+
+func (it *memHashItem[V]) isExpired(now time.Time) bool {
+	return it.expiresAt > 0 && now.UnixNano() > it.expiresAt
+}
+
+// memHashCache 内存 hash，单实例或测试使用
+type memHashCache[V any] struct {
+	mu    sync.RWMutex
+	items map[string]*memHashItem[V]
+}
+
+// NewMemHashCache 新建内存 hash，ttl<=0 永不过期，cleanupInterval>0 时定期清理过期的 key
+func NewMemHashCache[V any](cleanupInterval time.Duration) HashCache[V] {
+	mh := &memHashCache[V]{
+		items: make(map[string]*memHashItem[V]),
+	}
+	if cleanupInterval > 0 {
+		go mh.cleanExpiredLoop(cleanupInterval)
+	}
+	return mh
+}
+
+// getItem 取未过期的 key，调用方需持有锁
+func (mh *memHashCache[V]) getItem(key string, now time.Time) (*memHashItem[V], bool) {
+	item, ok := mh.items[key]
+	if !ok || item.isExpired(now) {
+		return nil, false
+	}
+	return item, true
+}
+
+// HGet 取 field 的值，key 或 field 不存在时返回 ErrNotFound
+func (mh *memHashCache[V]) HGet(_ context.Context, key, field string) (V, error) {
+	mh.mu.RLock()
+	defer mh.mu.RUnlock()
+	if item, ok := mh.getItem(key, time.Now()); ok {
+		if v, ok := item.fields[field]; ok {
+			return v, nil
+		}
+	}
+	var zero V
+	return zero, ErrNotFound
+}
+
+// HSet 写入 field 并重置整个 key 的有效期
+func (mh *memHashCache[V]) HSet(ctx context.Context, key, field string, val V, ttl time.Duration) (bool, error) {
+	return mh.HMSet(ctx, key, map[string]V{field: val}, ttl)
+}
+
+// HMSet 批量写入 field，已过期的 key 先清空再写入
+func (mh *memHashCache[V]) HMSet(_ context.Context, key string, values map[string]V, ttl time.Duration) (bool, error) {
+	if len(values) == 0 {
+		return false, nil
+	}
+	mh.mu.Lock()
+	defer mh.mu.Unlock()
+	now := time.Now()
+	item, ok := mh.getItem(key, now)
+	if !ok {
+		item = &memHashItem[V]{fields: make(map[string]V, len(values))}
+		mh.items[key] = item
+	}
+	for field, val := range values {
+		item.fields[field] = val
+	}
+	item.expiresAt = expiresAtOf(ttl, now)
+	return true, nil
+}
+
+// HDel 删除 fields，fields 为空时删除整个 key
+func (mh *memHashCache[V]) HDel(_ context.Context, key string, fields ...string) (int64, error) {
+	mh.mu.Lock()
+	defer mh.mu.Unlock()
+	item, ok := mh.getItem(key, time.Now())
+	if !ok {
+		return 0, nil
+	}
+	if len(fields) == 0 {
+		delete(mh.items, key)
+		return int64(len(item.fields)), nil
+	}
+	var n int64
+	for _, field := range fields {
+		if _, ok = item.fields[field]; ok {
+			delete(item.fields, field)
+			n++
+		}
+	}
+	if len(item.fields) == 0 {
+		delete(mh.items, key)
+	}
+	return n, nil
+}
+
+// HGetAll 取出所有 field
+func (mh *memHashCache[V]) HGetAll(_ context.Context, key string) (map[string]V, error) {
+	mh.mu.RLock()
+	defer mh.mu.RUnlock()
+	values := make(map[string]V)
+	if item, ok := mh.getItem(key, time.Now()); ok {
+		for field, v := range item.fields {
+			values[field] = v
+		}
+	}
+	return values, nil
+}
+
+// HLen field 的数量
+func (mh *memHashCache[V]) HLen(_ context.Context, key string) (int64, error) {
+	mh.mu.RLock()
+	defer mh.mu.RUnlock()
+	if item, ok := mh.getItem(key, time.Now()); ok {
+		return int64(len(item.fields)), nil
+	}
+	return 0, nil
+}
+
+// cleanExpiredLoop 定期清理过期的 key
+func (mh *memHashCache[V]) cleanExpiredLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		now := time.Now()
+		mh.mu.Lock()
+		for key, item := range mh.items {
+			if item.isExpired(now) {
+				delete(mh.items, key)
+			}
+		}
+		mh.mu.Unlock()
+	}
+}
@@ -3,18 +3,34 @@ package cache
 import (
 	"context"
 	"github.com/hashicorp/golang-lru/v2/expirable"
+	"strings"
+	"sync"
 	"time"
 )
 
+// memLruItem lru中存储的值，含每个key自己的过期时间
+type memLruItem[V any] struct {
+	value     V
+	ttl       time.Duration // 写入时的有效期
+	expiresAt int64         // 过期时间(unix nano), 0 永不过期
+}
+
+// isExpired 判断是否已过期
+func (it *memLruItem[V]) isExpired(now time.Time) bool {
+	return it.expiresAt > 0 && now.UnixNano() > it.expiresAt
+}
+
 type memLruCache[V any] struct {
 	maxSize           int
 	defaultExpiration time.Duration
-	mCache            *expirable.LRU[string, V]
+	mCache            *expirable.LRU[string, *memLruItem[V]]
+	mu                sync.Mutex // 保证读改写的原子性
 }
 
-// NewMemLruCache 新建memGoCache
+// NewMemLruCache 新建memLruCache，expiration 为 Set 未指定 timeout 时的默认过期时间
 func NewMemLruCache[V any](maxSize int, expiration time.Duration) CommCache[V] {
-	lruCacheClient := expirable.NewLRU[string, V](maxSize, nil, expiration)
+	// 过期时间由每个条目自己控制，lru 本身只负责按数量淘汰
+	lruCacheClient := expirable.NewLRU[string, *memLruItem[V]](maxSize, nil, 0)
 	return &memLruCache[V]{
 		maxSize:           maxSize,
 		defaultExpiration: expiration,
@@ -24,19 +40,102 @@ func NewMemLruCache[V any](maxSize int, expiration time.Duration) CommCache[V] {
 
 // Get 从缓存中取得一个值
 func (co *memLruCache[V]) Get(_ context.Context, key string) (v V, err error) {
-	ret, ok := co.mCache.Get(key)
-	if ok {
-		return ret, nil
+	item, ok := co.mCache.Get(key)
+	if !ok {
+		return v, nil
 	}
-	return v, nil
+	if item.isExpired(time.Now()) {
+		co.mCache.Remove(key)
+		return v, nil
+	}
+	return item.value, nil
 }
 
-// Set timeout为秒
-func (co *memLruCache[V]) Set(_ context.Context, key string, val V, _ time.Duration) (bool, error) {
-	return co.mCache.Add(key, val), nil
+// Set timeout<=0 时使用默认过期时间
+func (co *memLruCache[V]) Set(_ context.Context, key string, val V, timeout time.Duration) (bool, error) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	co.add(key, val, timeout)
+	return true, nil
 }
 
 // Del 从缓存中删除一个key
 func (co *memLruCache[V]) Del(_ context.Context, key string) (bool, error) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
 	return co.mCache.Remove(key), nil
 }
+
+// TTL 返回剩余有效期
+func (co *memLruCache[V]) TTL(_ context.Context, key string) (time.Duration, error) {
+	item, ok := co.mCache.Peek(key)
+	if !ok {
+		return TTLNotFound, nil
+	}
+	return ttlOf(item.expiresAt, time.Now()), nil
+}
+
+// Expire 重新设置过期时间
+func (co *memLruCache[V]) Expire(_ context.Context, key string, ttl time.Duration) (bool, error) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	item, ok := co.mCache.Peek(key)
+	if !ok || item.isExpired(time.Now()) {
+		return false, nil
+	}
+	co.add(key, item.value, ttl)
+	return true, nil
+}
+
+// Touch 按写入时的时长续期
+func (co *memLruCache[V]) Touch(_ context.Context, key string) (bool, error) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	item, ok := co.mCache.Peek(key)
+	if !ok || item.isExpired(time.Now()) {
+		return false, nil
+	}
+	co.add(key, item.value, item.ttl)
+	return true, nil
+}
+
+// add 写入条目，调用方需持有锁
+func (co *memLruCache[V]) add(key string, val V, timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = co.defaultExpiration
+	}
+	co.mCache.Add(key, &memLruItem[V]{
+		value:     val,
+		ttl:       timeout,
+		expiresAt: expiresAtOf(timeout, time.Now()),
+	})
+}
+
+// Scan 按游标遍历以 prefix 开头的 key
+func (co *memLruCache[V]) Scan(_ context.Context, prefix string, cursor string, count int) ([]string, string, error) {
+	keys, next := scanSortedKeys(co.keys(), prefix, cursor, count)
+	return keys, next, nil
+}
+
+// DeletePrefix 删除以 prefix 开头的所有 key
+func (co *memLruCache[V]) DeletePrefix(_ context.Context, prefix string) (int64, error) {
+	var n int64
+	for _, key := range co.keys() {
+		if strings.HasPrefix(key, prefix) && co.mCache.Remove(key) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// keys 返回未过期的全部 key，不影响 lru 顺序
+func (co *memLruCache[V]) keys() []string {
+	now := time.Now()
+	keys := make([]string, 0, co.mCache.Len())
+	for _, key := range co.mCache.Keys() {
+		if item, ok := co.mCache.Peek(key); ok && !item.isExpired(now) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
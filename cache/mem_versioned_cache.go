@@ -0,0 +1,146 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memVersionedItem 带版本号的条目
+type memVersionedItem[V any] struct {
+	value     V
+	version   int64
+	expiresAt int64 // 过期时间(unix nano), 0 永不过期
+}
+
+func (it *memVersionedItem[V]) isExpired(now time.Time) bool {
+	return it.expiresAt > 0 && now.UnixNano() > it.expiresAt
+}
+
+// memVersionedCache 带版本号的内存缓存，用互斥锁保证比较和写入的原子性
+type memVersionedCache[V any] struct {
+	mu    sync.RWMutex
+	items map[string]*memVersionedItem[V]
+}
+
+// NewMemVersionedCache 新建带版本号的内存缓存，cleanupInterval>0 时定期清理过期数据
+func NewMemVersionedCache[V any](cleanupInterval time.Duration) CommCache[V] {
+	co := &memVersionedCache[V]{
+		items: make(map[string]*memVersionedItem[V]),
+	}
+	if cleanupInterval > 0 {
+		go co.cleanExpiredLoop(cleanupInterval)
+	}
+	return co
+}
+
+// Get 从缓存中取得一个值
+func (co *memVersionedCache[V]) Get(ctx context.Context, key string) (V, error) {
+	v, _, err := co.GetWithVersion(ctx, key)
+	return v, err
+}
+
+// Set 写入并将版本号加 1，timeout<=0 永不过期
+func (co *memVersionedCache[V]) Set(_ context.Context, key string, val V, timeout time.Duration) (bool, error) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	now := time.Now()
+	var current int64
+	if item, ok := co.items[key]; ok && !item.isExpired(now) {
+		current = item.version
+	}
+	co.items[key] = &memVersionedItem[V]{
+		value:     val,
+		version:   current + 1,
+		expiresAt: expiresAtOf(timeout, now),
+	}
+	return true, nil
+}
+
+// Del 从缓存中删除一个key
+func (co *memVersionedCache[V]) Del(_ context.Context, key string) (bool, error) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	_, ok := co.items[key]
+	delete(co.items, key)
+	return ok, nil
+}
+
+// GetWithVersion 取值及其版本号
+func (co *memVersionedCache[V]) GetWithVersion(_ context.Context, key string) (v V, version int64, err error) {
+	co.mu.RLock()
+	defer co.mu.RUnlock()
+	item, ok := co.items[key]
+	if !ok || item.isExpired(time.Now()) {
+		return v, 0, nil
+	}
+	return item.value, item.version, nil
+}
+
+// CompareAndSet 版本号一致时写入
+func (co *memVersionedCache[V]) CompareAndSet(_ context.Context, key string, expectedVersion int64, val V, ttl time.Duration) (int64, error) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	now := time.Now()
+	var current int64
+	if item, ok := co.items[key]; ok && !item.isExpired(now) {
+		current = item.version
+	}
+	if current != expectedVersion {
+		return current, &ErrVersionConflict{Key: key, Expected: expectedVersion, Actual: current}
+	}
+	co.items[key] = &memVersionedItem[V]{
+		value:     val,
+		version:   current + 1,
+		expiresAt: expiresAtOf(ttl, now),
+	}
+	return current + 1, nil
+}
+
+// cleanExpiredLoop 定期清理过期数据
+func (co *memVersionedCache[V]) cleanExpiredLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		now := time.Now()
+		co.mu.Lock()
+		for key, item := range co.items {
+			if item.isExpired(now) {
+				delete(co.items, key)
+			}
+		}
+		co.mu.Unlock()
+	}
+}
+
+// Scan 按游标遍历以 prefix 开头的 key
+func (co *memVersionedCache[V]) Scan(_ context.Context, prefix string, cursor string, count int) ([]string, string, error) {
+	co.mu.RLock()
+	now := time.Now()
+	keys := make([]string, 0, len(co.items))
+	for key, item := range co.items {
+		if !item.isExpired(now) {
+			keys = append(keys, key)
+		}
+	}
+	co.mu.RUnlock()
+
+	keys, next := scanSortedKeys(keys, prefix, cursor, count)
+	return keys, next, nil
+}
+
+// DeletePrefix 删除以 prefix 开头的所有 key
+func (co *memVersionedCache[V]) DeletePrefix(_ context.Context, prefix string) (int64, error) {
+	co.mu.Lock()
+	defer co.mu.Unlock()
+	var n int64
+	for key := range co.items {
+		if strings.HasPrefix(key, prefix) {
+			delete(co.items, key)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -12,7 +12,7 @@ import (
 	"sync"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 )
 
 var (
@@ -41,6 +41,7 @@ type mySQLCache[V any] struct {
 	// 缓存表名，可在初始化时指定
 	tableName string
 	namespace string
+	nsGens    nsGenerationCache
 }
 
 // NewMySQLCache 创建MySQL缓存实例
@@ -76,6 +77,7 @@ func NewMySQLCache[V any](cfg *MySQLCacheConfig) (CommCache[V], error) {
 			cache_key VARCHAR(255) NOT NULL,
 			cache_value JSON NOT NULL,
 			expire_time DATETIME DEFAULT NULL,
+			version BIGINT NOT NULL DEFAULT 0,
 			create_time DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
 			update_time DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
 			PRIMARY KEY (namespace,cache_key) USING BTREE,
@@ -87,6 +89,40 @@ func NewMySQLCache[V any](cfg *MySQLCacheConfig) (CommCache[V], error) {
 	if err != nil {
 		return nil, fmt.Errorf("创建缓存表失败: %v", err)
 	}
+	if err = ensureVersionColumn(cfg.SqlDB, cfg.TableName); err != nil {
+		return nil, err
+	}
+
+	// tag 索引表，记录 tag 下的 key，过期时间与 key 一致
+	createTagTableSQL := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			namespace VARCHAR(50) NOT NULL,
+			tag VARCHAR(255) NOT NULL,
+			cache_key VARCHAR(255) NOT NULL,
+			expire_time DATETIME NOT NULL,
+			PRIMARY KEY (namespace,tag,cache_key) USING BTREE,
+			KEY idx_expire_time (expire_time) USING BTREE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_bin;
+	`, getTagTableName(cfg.TableName))
+	if _, err = cfg.SqlDB.Exec(createTagTableSQL); err != nil {
+		return nil, fmt.Errorf("创建缓存 tag 表失败: %v", err)
+	}
+
+	// hash 表，同一个 key 下的 field 有效期一致
+	createHashTableSQL := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			namespace VARCHAR(50) NOT NULL,
+			cache_key VARCHAR(255) NOT NULL,
+			field VARCHAR(255) NOT NULL,
+			cache_value JSON NOT NULL,
+			expire_time DATETIME NOT NULL,
+			PRIMARY KEY (namespace,cache_key,field) USING BTREE,
+			KEY idx_expire_time (expire_time) USING BTREE
+		) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_bin;
+	`, getHashTableName(cfg.TableName))
+	if _, err = cfg.SqlDB.Exec(createHashTableSQL); err != nil {
+		return nil, fmt.Errorf("创建缓存 hash 表失败: %v", err)
+	}
 
 	mysqlCache := &mySQLCache[V]{
 		db:        cfg.SqlDB,
@@ -176,8 +212,8 @@ func (c *mySQLCache[V]) Set(ctx context.Context, key string, val V, timeout time
 		timeout = defaultMaxExpireTime
 	}
 
-	// 插入或更新缓存（UPSERT操作）
-	insertSQL := fmt.Sprintf(`INSERT INTO %s (namespace, cache_key, cache_value, expire_time) VALUES (?, ?, ?, NOW() + INTERVAL ? SECOND) ON DUPLICATE KEY UPDATE cache_value = VALUES(cache_value), expire_time = VALUES(expire_time), update_time = CURRENT_TIMESTAMP`, c.tableName)
+	// 插入或更新缓存（UPSERT操作），未过期时版本号加 1，version 需要先于 expire_time 赋值，判断时使用旧的过期时间
+	insertSQL := fmt.Sprintf(`INSERT INTO %s (namespace, cache_key, cache_value, expire_time, version) VALUES (?, ?, ?, NOW() + INTERVAL ? SECOND, 1) ON DUPLICATE KEY UPDATE version = IF(expire_time IS NOT NULL AND expire_time <= NOW(), 1, GREATEST(version, 1) + 1), cache_value = VALUES(cache_value), expire_time = VALUES(expire_time), update_time = CURRENT_TIMESTAMP`, c.tableName)
 
 	var args []interface{}
 	args = append(args, c.namespace, key, valueStr, int64(timeout.Seconds())) //这样做是为了做续期的功能，如果不续期，保留原值，则使用 Value(expire_time)
@@ -214,6 +250,23 @@ func (c *mySQLCache[V]) Del(ctx context.Context, key string) (bool, error) {
 	return rowsAffected > 0, nil
 }
 
+// ensureVersionColumn 老版本创建的缓存表没有 version 字段，需要补上
+func ensureVersionColumn(db *sql.DB, tableName string) error {
+	var count int
+	querySQL := `SELECT COUNT(*) FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = 'version'`
+	if err := db.QueryRow(querySQL, tableName).Scan(&count); err != nil {
+		return fmt.Errorf("查询缓存表字段失败: %v", err)
+	}
+	if count > 0 {
+		return nil
+	}
+	alterSQL := fmt.Sprintf("ALTER TABLE %s ADD COLUMN version BIGINT NOT NULL DEFAULT 0 AFTER expire_time", tableName)
+	if _, err := db.Exec(alterSQL); err != nil {
+		return fmt.Errorf("缓存表添加 version 字段失败: %v", err)
+	}
+	return nil
+}
+
 func (c *mySQLCache[V]) getCacheKey() string {
 	return fmt.Sprintf("%s/%s", c.dsn, c.tableName)
 }
@@ -244,7 +297,552 @@ func (c *mySQLCache[V]) startCleanupJob(interval time.Duration) {
 					// 实际应用中建议使用日志库记录错误
 					fmt.Printf("清理过期缓存失败: %v\n", err)
 				}
+				cleanTagSQL := fmt.Sprintf("DELETE FROM %s WHERE expire_time < NOW()", getTagTableName(c.tableName))
+				if _, err = c.db.Exec(cleanTagSQL); err != nil {
+					fmt.Printf("清理过期缓存 tag 失败: %v\n", err)
+				}
+				cleanHashSQL := fmt.Sprintf("DELETE FROM %s WHERE expire_time < NOW()", getHashTableName(c.tableName))
+				if _, err = c.db.Exec(cleanHashSQL); err != nil {
+					fmt.Printf("清理过期缓存 hash 失败: %v\n", err)
+				}
 			}
 		}
 	}()
 }
+
+// TTL 返回剩余有效期
+func (c *mySQLCache[V]) TTL(ctx context.Context, key string) (time.Duration, error) {
+	var (
+		expireBytes []byte
+		nowBytes    []byte
+	)
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	querySQL := fmt.Sprintf(`SELECT expire_time, NOW() as now FROM %s WHERE namespace=? AND cache_key = ? LIMIT 1`, c.tableName)
+	err := c.db.QueryRowContext(ctx, querySQL, c.namespace, key).Scan(&expireBytes, &nowBytes)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return TTLNotFound, nil
+		}
+		return TTLNotFound, fmt.Errorf("查询缓存失败: %v", err)
+	}
+	if expireBytes == nil { //表示没有设置过期时间
+		return TTLNoExpire, nil
+	}
+	expTime, err := conv.Convert[time.Time](string(expireBytes))
+	if err != nil {
+		return TTLNotFound, fmt.Errorf("时间转换失败: %v", err)
+	}
+	nowTime, err := conv.Convert[time.Time](string(nowBytes))
+	if err != nil {
+		return TTLNotFound, fmt.Errorf("时间转换失败: %v", err)
+	}
+	remain := expTime.Sub(nowTime)
+	if remain <= 0 {
+		return TTLNotFound, nil
+	}
+	return remain, nil
+}
+
+// Expire 重新设置过期时间，ttl<=0 时与 Set 一致使用默认时长
+func (c *mySQLCache[V]) Expire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if ttl <= 0 {
+		ttl = defaultMaxExpireTime
+	}
+	updateSQL := fmt.Sprintf(`UPDATE %s SET expire_time = NOW() + INTERVAL ? SECOND, update_time = CURRENT_TIMESTAMP WHERE namespace=? AND cache_key = ? AND (expire_time IS NULL OR expire_time > NOW())`, c.tableName)
+	return c.execExpire(ctx, key, updateSQL, int64(ttl.Seconds()), c.namespace, key)
+}
+
+// Touch 按写入时的时长续期，写入时长由 expire_time 与 update_time 的差值得到
+func (c *mySQLCache[V]) Touch(ctx context.Context, key string) (bool, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	// expire_time 需要先于 update_time 赋值，这样计算差值时使用的还是旧的 update_time
+	updateSQL := fmt.Sprintf(`UPDATE %s SET expire_time = NOW() + INTERVAL TIMESTAMPDIFF(SECOND, update_time, expire_time) SECOND, update_time = CURRENT_TIMESTAMP WHERE namespace=? AND cache_key = ? AND expire_time IS NOT NULL AND expire_time > NOW()`, c.tableName)
+	return c.execExpire(ctx, key, updateSQL, c.namespace, key)
+}
+
+// execExpire 执行续期语句，同一秒内重复续期时影响行数为 0，需要再确认 key 是否存在
+func (c *mySQLCache[V]) execExpire(ctx context.Context, key string, updateSQL string, args ...any) (bool, error) {
+	result, err := c.db.ExecContext(ctx, updateSQL, args...)
+	if err != nil {
+		return false, fmt.Errorf("设置过期时间失败: %v", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected > 0 {
+		return true, nil
+	}
+	ttl, err := c.TTL(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	return ttl != TTLNotFound, nil
+}
+
+// IncrBy 原子增加计数，已过期的 key 从 0 开始重新计数
+func (c *mySQLCache[V]) IncrBy(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	if ttl <= 0 {
+		ttl = defaultMaxExpireTime
+	}
+	return c.incrBy(ctx, key, delta, int64(ttl.Seconds()))
+}
+
+// incrPersistent 原子增加计数，新建的 key 不过期
+func (c *mySQLCache[V]) incrPersistent(ctx context.Context, key string, delta int64) (int64, error) {
+	return c.incrBy(ctx, key, delta, nil)
+}
+
+func (c *mySQLCache[V]) nsGenerationCache() *nsGenerationCache {
+	return &c.nsGens
+}
+
+// incrBy expireSeconds 为 nil 时 expire_time 为 NULL，永不过期
+func (c *mySQLCache[V]) incrBy(ctx context.Context, key string, delta int64, expireSeconds any) (int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	// cache_value 需要先于 expire_time 赋值，这样判断是否过期时使用的还是旧的 expire_time
+	upsertSQL := fmt.Sprintf(`INSERT INTO %s (namespace, cache_key, cache_value, expire_time) VALUES (?, ?, ?, NOW() + INTERVAL ? SECOND)
+		ON DUPLICATE KEY UPDATE
+		cache_value = IF(expire_time IS NOT NULL AND expire_time < NOW(), VALUES(cache_value), CAST(CAST(cache_value AS SIGNED) + ? AS JSON)),
+		expire_time = IF(expire_time IS NOT NULL AND expire_time < NOW(), VALUES(expire_time), expire_time),
+		update_time = CURRENT_TIMESTAMP`, c.tableName)
+	_, err = tx.ExecContext(ctx, upsertSQL, c.namespace, key, conv.String(delta), expireSeconds, delta)
+	if err != nil {
+		return 0, fmt.Errorf("更新计数失败: %v", err)
+	}
+
+	var valueStr string
+	querySQL := fmt.Sprintf(`SELECT cache_value FROM %s WHERE namespace=? AND cache_key = ? LIMIT 1`, c.tableName)
+	if err = tx.QueryRowContext(ctx, querySQL, c.namespace, key).Scan(&valueStr); err != nil {
+		return 0, fmt.Errorf("查询计数失败: %v", err)
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("提交事务失败: %v", err)
+	}
+	return conv.Convert[int64](valueStr)
+}
+
+// DecrBy 原子减少计数
+func (c *mySQLCache[V]) DecrBy(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	return c.IncrBy(ctx, key, -delta, ttl)
+}
+
+// GetCounter 获取计数，不存在或已过期返回 0
+func (c *mySQLCache[V]) GetCounter(ctx context.Context, key string) (int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var valueStr string
+	querySQL := fmt.Sprintf(`SELECT cache_value FROM %s WHERE namespace=? AND cache_key = ? AND (expire_time IS NULL OR expire_time > NOW()) LIMIT 1`, c.tableName)
+	err := c.db.QueryRowContext(ctx, querySQL, c.namespace, key).Scan(&valueStr)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("查询计数失败: %v", err)
+	}
+	return conv.Convert[int64](valueStr)
+}
+
+// GetWithVersion 取值及其版本号，计数器等不经过 Set 写入的行版本号按 1 处理
+func (c *mySQLCache[V]) GetWithVersion(ctx context.Context, key string) (V, int64, error) {
+	var (
+		valueStr string
+		version  int64
+		zero     V
+	)
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	querySQL := fmt.Sprintf(`SELECT cache_value, GREATEST(version, 1) FROM %s WHERE namespace=? AND cache_key = ? AND (expire_time IS NULL OR expire_time > NOW()) LIMIT 1`, c.tableName)
+	err := c.db.QueryRowContext(ctx, querySQL, c.namespace, key).Scan(&valueStr, &version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return zero, 0, nil
+		}
+		return zero, 0, fmt.Errorf("查询缓存失败: %v", err)
+	}
+	v, err := strToVal[V](valueStr)
+	return v, version, err
+}
+
+// CompareAndSet 用条件写入比较版本号，一致时写入并将版本号加 1。
+// 期望版本号为 0 时先清掉已过期的行再 INSERT，主键冲突表示已存在；否则带版本号条件 UPDATE，影响行数为 0 表示不一致
+func (c *mySQLCache[V]) CompareAndSet(ctx context.Context, key string, expectedVersion int64, val V, ttl time.Duration) (int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if ttl <= 0 {
+		ttl = defaultMaxExpireTime
+	}
+
+	var (
+		result sql.Result
+		err    error
+	)
+	if expectedVersion == 0 {
+		deleteSQL := fmt.Sprintf(`DELETE FROM %s WHERE namespace=? AND cache_key = ? AND expire_time IS NOT NULL AND expire_time <= NOW()`, c.tableName)
+		if _, err = c.db.ExecContext(ctx, deleteSQL, c.namespace, key); err != nil {
+			return 0, fmt.Errorf("清理过期缓存失败: %v", err)
+		}
+		insertSQL := fmt.Sprintf(`INSERT INTO %s (namespace, cache_key, cache_value, expire_time, version) VALUES (?, ?, ?, NOW() + INTERVAL ? SECOND, 1)`, c.tableName)
+		result, err = c.db.ExecContext(ctx, insertSQL, c.namespace, key, conv.String(val), int64(ttl.Seconds()))
+	} else {
+		updateSQL := fmt.Sprintf(`UPDATE %s SET cache_value = ?, expire_time = NOW() + INTERVAL ? SECOND, version = GREATEST(version, 1) + 1, update_time = CURRENT_TIMESTAMP WHERE namespace=? AND cache_key = ? AND GREATEST(version, 1) = ? AND (expire_time IS NULL OR expire_time > NOW())`, c.tableName)
+		result, err = c.db.ExecContext(ctx, updateSQL, conv.String(val), int64(ttl.Seconds()), c.namespace, key, expectedVersion)
+	}
+	if err != nil {
+		if !isMySQLConflictErr(err) {
+			return 0, fmt.Errorf("设置缓存失败: %v", err)
+		}
+		return c.versionConflict(ctx, key, expectedVersion)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if rowsAffected == 0 {
+		return c.versionConflict(ctx, key, expectedVersion)
+	}
+	return expectedVersion + 1, nil
+}
+
+// versionConflict 查询当前版本号并返回 *ErrVersionConflict
+func (c *mySQLCache[V]) versionConflict(ctx context.Context, key string, expectedVersion int64) (int64, error) {
+	_, current, err := c.GetWithVersion(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	return current, &ErrVersionConflict{Key: key, Expected: expectedVersion, Actual: current}
+}
+
+// isMySQLConflictErr 主键冲突、死锁和锁等待超时都表示有并发写入
+func isMySQLConflictErr(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+	switch mysqlErr.Number {
+	case 1062, 1205, 1213: // ER_DUP_ENTRY, ER_LOCK_WAIT_TIMEOUT, ER_LOCK_DEADLOCK
+		return true
+	}
+	return false
+}
+
+// Scan 按游标遍历以 prefix 开头的 key，按 cache_key 排序，游标为上一批的最后一个 key
+func (c *mySQLCache[V]) Scan(ctx context.Context, prefix string, cursor string, count int) ([]string, string, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if count <= 0 {
+		count = defaultScanCount
+	}
+	// 多取一条用于判断是否还有下一批
+	querySQL := fmt.Sprintf(`SELECT cache_key FROM %s WHERE namespace=? AND cache_key LIKE ? AND cache_key > ? AND (expire_time IS NULL OR expire_time > NOW()) ORDER BY cache_key LIMIT ?`, c.tableName)
+	rows, err := c.db.QueryContext(ctx, querySQL, c.namespace, escapeLike(prefix)+"%", cursor, count+1)
+	if err != nil {
+		return nil, "", fmt.Errorf("查询缓存失败: %v", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	keys := make([]string, 0, count)
+	for rows.Next() {
+		var key string
+		if err = rows.Scan(&key); err != nil {
+			return nil, "", fmt.Errorf("查询缓存失败: %v", err)
+		}
+		keys = append(keys, key)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("查询缓存失败: %v", err)
+	}
+	if len(keys) <= count {
+		return keys, "", nil
+	}
+	return keys[:count], keys[count-1], nil
+}
+
+// DeletePrefix 删除以 prefix 开头的所有 key
+func (c *mySQLCache[V]) DeletePrefix(ctx context.Context, prefix string) (int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	deleteSQL := fmt.Sprintf("DELETE FROM %s WHERE namespace=? AND cache_key LIKE ?", c.tableName)
+	result, err := c.db.ExecContext(ctx, deleteSQL, c.namespace, escapeLike(prefix)+"%")
+	if err != nil {
+		return 0, fmt.Errorf("删除缓存失败: %v", err)
+	}
+	return result.RowsAffected()
+}
+
+// escapeLike 转义 LIKE 中的通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// getTagTableName tag 索引表名
+func getTagTableName(tableName string) string {
+	return tableName + "_tag"
+}
+
+// AddTagKeys 记录 key 属于 tags，ttl<=0 时与 Set 一致使用默认时长
+func (c *mySQLCache[V]) AddTagKeys(ctx context.Context, key string, ttl time.Duration, tags ...string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if ttl <= 0 {
+		ttl = defaultMaxExpireTime
+	}
+	valueStr := make([]string, 0, len(tags))
+	args := make([]any, 0, len(tags)*4)
+	for _, tag := range tags {
+		valueStr = append(valueStr, "(?, ?, ?, NOW() + INTERVAL ? SECOND)")
+		args = append(args, c.namespace, tag, key, int64(ttl.Seconds()))
+	}
+	insertSQL := fmt.Sprintf(`INSERT INTO %s (namespace, tag, cache_key, expire_time) VALUES %s ON DUPLICATE KEY UPDATE expire_time = VALUES(expire_time)`,
+		getTagTableName(c.tableName), strings.Join(valueStr, ","))
+	if _, err := c.db.ExecContext(ctx, insertSQL, args...); err != nil {
+		return fmt.Errorf("设置缓存 tag 失败: %v", err)
+	}
+	return nil
+}
+
+// TagKeys 返回 tag 下未过期的 key
+func (c *mySQLCache[V]) TagKeys(ctx context.Context, tag string) ([]string, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	querySQL := fmt.Sprintf(`SELECT cache_key FROM %s WHERE namespace=? AND tag = ? AND expire_time > NOW()`, getTagTableName(c.tableName))
+	rows, err := c.db.QueryContext(ctx, querySQL, c.namespace, tag)
+	if err != nil {
+		return nil, fmt.Errorf("查询缓存 tag 失败: %v", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	keys := make([]string, 0)
+	for rows.Next() {
+		var key string
+		if err = rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("查询缓存 tag 失败: %v", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// DelTags 删除 tags 的索引
+func (c *mySQLCache[V]) DelTags(ctx context.Context, tags ...string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	whereStr := make([]string, 0, len(tags))
+	args := make([]any, 0, len(tags)+1)
+	args = append(args, c.namespace)
+	for _, tag := range tags {
+		whereStr = append(whereStr, "?")
+		args = append(args, tag)
+	}
+	deleteSQL := fmt.Sprintf("DELETE FROM %s WHERE namespace=? AND tag IN (%s)", getTagTableName(c.tableName), strings.Join(whereStr, ","))
+	if _, err := c.db.ExecContext(ctx, deleteSQL, args...); err != nil {
+		return fmt.Errorf("删除缓存 tag 失败: %v", err)
+	}
+	return nil
+}
+
+// DelTagKeys 从 tag 的索引中删除指定的 key
+func (c *mySQLCache[V]) DelTagKeys(ctx context.Context, tag string, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	whereStr := make([]string, 0, len(keys))
+	args := make([]any, 0, len(keys)+2)
+	args = append(args, c.namespace, tag)
+	for _, key := range keys {
+		whereStr = append(whereStr, "?")
+		args = append(args, key)
+	}
+	deleteSQL := fmt.Sprintf("DELETE FROM %s WHERE namespace=? AND tag = ? AND cache_key IN (%s)", getTagTableName(c.tableName), strings.Join(whereStr, ","))
+	if _, err := c.db.ExecContext(ctx, deleteSQL, args...); err != nil {
+		return fmt.Errorf("删除缓存 tag 失败: %v", err)
+	}
+	return nil
+}
+
+// getHashTableName hash 表名
+func getHashTableName(tableName string) string {
+	return tableName + "_hash"
+}
+
+// HGet 取 field 的值，key 或 field 不存在时返回 ErrNotFound
+func (c *mySQLCache[V]) HGet(ctx context.Context, key, field string) (V, error) {
+	var zero V
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var valueStr string
+	querySQL := fmt.Sprintf(`SELECT cache_value FROM %s WHERE namespace=? AND cache_key = ? AND field = ? AND expire_time > NOW() LIMIT 1`, getHashTableName(c.tableName))
+	err := c.db.QueryRowContext(ctx, querySQL, c.namespace, key, field).Scan(&valueStr)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return zero, ErrNotFound
+		}
+		return zero, fmt.Errorf("查询缓存 hash 失败: %v", err)
+	}
+	return strToVal[V](valueStr)
+}
+
+// HSet 写入 field 并重置整个 key 的有效期
+func (c *mySQLCache[V]) HSet(ctx context.Context, key, field string, val V, ttl time.Duration) (bool, error) {
+	return c.HMSet(ctx, key, map[string]V{field: val}, ttl)
+}
+
+// HMSet 在事务中先清理已过期的 field，再写入并重置整个 key 的有效期，ttl<=0 时使用默认时长
+func (c *mySQLCache[V]) HMSet(ctx context.Context, key string, values map[string]V, ttl time.Duration) (bool, error) {
+	if len(values) == 0 {
+		return false, nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if ttl <= 0 {
+		ttl = defaultMaxExpireTime
+	}
+	hashTable := getHashTableName(c.tableName)
+	seconds := int64(ttl.Seconds())
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("开启事务失败: %v", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	cleanSQL := fmt.Sprintf(`DELETE FROM %s WHERE namespace=? AND cache_key = ? AND expire_time <= NOW()`, hashTable)
+	if _, err = tx.ExecContext(ctx, cleanSQL, c.namespace, key); err != nil {
+		return false, fmt.Errorf("清理过期缓存 hash 失败: %v", err)
+	}
+
+	valueStr := make([]string, 0, len(values))
+	args := make([]any, 0, len(values)*5)
+	for field, val := range values {
+		valueStr = append(valueStr, "(?, ?, ?, ?, NOW() + INTERVAL ? SECOND)")
+		args = append(args, c.namespace, key, field, conv.String(val), seconds)
+	}
+	insertSQL := fmt.Sprintf(`INSERT INTO %s (namespace, cache_key, field, cache_value, expire_time) VALUES %s ON DUPLICATE KEY UPDATE cache_value = VALUES(cache_value), expire_time = VALUES(expire_time)`,
+		hashTable, strings.Join(valueStr, ","))
+	if _, err = tx.ExecContext(ctx, insertSQL, args...); err != nil {
+		return false, fmt.Errorf("设置缓存 hash 失败: %v", err)
+	}
+
+	expireSQL := fmt.Sprintf(`UPDATE %s SET expire_time = NOW() + INTERVAL ? SECOND WHERE namespace=? AND cache_key = ?`, hashTable)
+	if _, err = tx.ExecContext(ctx, expireSQL, seconds, c.namespace, key); err != nil {
+		return false, fmt.Errorf("设置缓存 hash 过期时间失败: %v", err)
+	}
+	if err = tx.Commit(); err != nil {
+		return false, fmt.Errorf("提交事务失败: %v", err)
+	}
+	return true, nil
+}
+
+// HDel 删除 fields，fields 为空时删除整个 key，已过期的 field 不计入删除数量
+func (c *mySQLCache[V]) HDel(ctx context.Context, key string, fields ...string) (int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	hashTable := getHashTableName(c.tableName)
+	whereStr := "namespace=? AND cache_key = ?"
+	args := []any{c.namespace, key}
+	if len(fields) > 0 {
+		placeholders := make([]string, 0, len(fields))
+		for _, field := range fields {
+			placeholders = append(placeholders, "?")
+			args = append(args, field)
+		}
+		whereStr += fmt.Sprintf(" AND field IN (%s)", strings.Join(placeholders, ","))
+	}
+
+	cleanSQL := fmt.Sprintf("DELETE FROM %s WHERE %s AND expire_time <= NOW()", hashTable, whereStr)
+	if _, err := c.db.ExecContext(ctx, cleanSQL, args...); err != nil {
+		return 0, fmt.Errorf("删除缓存 hash 失败: %v", err)
+	}
+	deleteSQL := fmt.Sprintf("DELETE FROM %s WHERE %s", hashTable, whereStr)
+	result, err := c.db.ExecContext(ctx, deleteSQL, args...)
+	if err != nil {
+		return 0, fmt.Errorf("删除缓存 hash 失败: %v", err)
+	}
+	return result.RowsAffected()
+}
+
+// HGetAll 取出所有未过期的 field
+func (c *mySQLCache[V]) HGetAll(ctx context.Context, key string) (map[string]V, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	querySQL := fmt.Sprintf(`SELECT field, cache_value FROM %s WHERE namespace=? AND cache_key = ? AND expire_time > NOW()`, getHashTableName(c.tableName))
+	rows, err := c.db.QueryContext(ctx, querySQL, c.namespace, key)
+	if err != nil {
+		return nil, fmt.Errorf("查询缓存 hash 失败: %v", err)
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	strValues := make(map[string]string)
+	for rows.Next() {
+		var field, valueStr string
+		if err = rows.Scan(&field, &valueStr); err != nil {
+			return nil, fmt.Errorf("查询缓存 hash 失败: %v", err)
+		}
+		strValues[field] = valueStr
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("查询缓存 hash 失败: %v", err)
+	}
+	return strMapToVal[V](strValues)
+}
+
+// HLen 未过期的 field 数量
+func (c *mySQLCache[V]) HLen(ctx context.Context, key string) (int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var n int64
+	querySQL := fmt.Sprintf(`SELECT COUNT(*) FROM %s WHERE namespace=? AND cache_key = ? AND expire_time > NOW()`, getHashTableName(c.tableName))
+	if err := c.db.QueryRowContext(ctx, querySQL, c.namespace, key).Scan(&n); err != nil {
+		return 0, fmt.Errorf("查询缓存 hash 失败: %v", err)
+	}
+	return n, nil
+}
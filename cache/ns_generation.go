@@ -0,0 +1,134 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var nsGenerationCacheTTL = time.Second // 本地缓存代数的时长，失效后才能看到其他实例的 NsInvalidate
+
+// nsGenerationCache 存储内的命名空间代数本地缓存，随存储一起释放
+type nsGenerationCache struct {
+	items sync.Map // ns -> *nsGenerationItem
+}
+
+func (gc *nsGenerationCache) load(ns string, now time.Time) (int64, bool) {
+	v, ok := gc.items.Load(ns)
+	if !ok {
+		return 0, false
+	}
+	item := v.(*nsGenerationItem)
+	if now.UnixNano() >= item.expiresAt {
+		return 0, false
+	}
+	return item.gen, true
+}
+
+func (gc *nsGenerationCache) store(ns string, gen int64, now time.Time) {
+	gc.items.Store(ns, &nsGenerationItem{
+		gen:       gen,
+		expiresAt: now.Add(nsGenerationCacheTTL).UnixNano(),
+	})
+}
+
+// nsGenerationHolder 持有代数本地缓存的存储，未实现时每次都从存储读取代数
+type nsGenerationHolder interface {
+	nsGenerationCache() *nsGenerationCache
+}
+
+// persistentCounter 支持不过期计数的存储，IncrBy 的 ttl<=0 会使用最长存储时间的存储需要实现
+type persistentCounter interface {
+	incrPersistent(ctx context.Context, key string, delta int64) (int64, error)
+}
+
+type nsGenerationItem struct {
+	gen       int64
+	expiresAt int64 // 过期时间(unix nano)
+}
+
+// SetNsGenerationCacheTTL 设置本地缓存命名空间代数的时长，越短越及时，但访问存储的次数越多
+func SetNsGenerationCacheTTL(ttl time.Duration) {
+	if ttl > 0 {
+		nsGenerationCacheTTL = ttl
+	}
+}
+
+// getNsGenCounterKey 代数计数器的 key，放在 {ns} 前缀之外，NsDeleteAll 和按前缀遍历都不会碰到，
+// 保留 {ns} 作为 hash tag，在 redis 集群中与命名空间下的 key 落在同一个节点
+func getNsGenCounterKey(ns string) string {
+	return "\x00gen" + getNsKey(ns, "")
+}
+
+// getNsGenKey 带代数的 key，代数为 0 时与 getNsKey 一致，兼容老数据
+func getNsGenKey(ns string, gen int64, key string) string {
+	if ns == "" || gen == 0 {
+		return getNsKey(ns, key)
+	}
+	return fmt.Sprintf("{%s}#g%d#%s", ns, gen, key)
+}
+
+// nsGeneration 获取命名空间当前的代数，优先使用存储内的本地缓存，存储未实现 CounterCache 时代数为 0
+func nsGeneration(ctx context.Context, co any, ns string) (int64, error) {
+	counter, ok := co.(CounterCache)
+	if ns == "" || !ok {
+		return 0, nil
+	}
+	holder, cached := co.(nsGenerationHolder)
+	now := time.Now()
+	if cached {
+		if gen, ok := holder.nsGenerationCache().load(ns, now); ok {
+			return gen, nil
+		}
+	}
+	gen, err := counter.GetCounter(getContext(ctx), getNsGenCounterKey(ns))
+	if err != nil {
+		return 0, err
+	}
+	if cached {
+		holder.nsGenerationCache().store(ns, gen, now)
+	}
+	return gen, nil
+}
+
+// nsKeyOf 获取命名空间下带代数的 key
+func nsKeyOf(ctx context.Context, co any, ns string, key string) (string, error) {
+	gen, err := nsGeneration(ctx, co, ns)
+	if err != nil {
+		return "", err
+	}
+	return getNsGenKey(ns, gen, key), nil
+}
+
+// NsInvalidate 使命名空间下的所有 key 失效，只增加代数，不扫描删除，老的 key 访问不到后随过期时间自动清理。
+// 存储未实现 CounterCache 时，若实现了 ScannableCache 则退化为按前缀删除
+func NsInvalidate(ctx context.Context, co any, ns string) error {
+	if ns == "" {
+		return fmt.Errorf("namespace is empty")
+	}
+	ctx = getContext(ctx)
+	counter, ok := co.(CounterCache)
+	if !ok {
+		if scanner, ok := co.(ScannableCache); ok {
+			_, err := NsDeleteAll(ctx, scanner, ns)
+			return err
+		}
+		return fmt.Errorf("%T not implement CounterCache or ScannableCache", co)
+	}
+	// 代数计数器不能过期，过期后代数回到 0，老数据会重新可见
+	var gen int64
+	var err error
+	if pc, ok := co.(persistentCounter); ok {
+		gen, err = pc.incrPersistent(ctx, getNsGenCounterKey(ns), 1)
+	} else {
+		gen, err = counter.IncrBy(ctx, getNsGenCounterKey(ns), 1, 0)
+	}
+	if err != nil {
+		return err
+	}
+	if holder, ok := co.(nsGenerationHolder); ok {
+		holder.nsGenerationCache().store(ns, gen, time.Now())
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package cache_test
+
+import (
+	"context"
+	"github.com/magic-lib/go-plat-cache/cache"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNsInvalidate(t *testing.T) {
+	ctx := context.Background()
+	boltCache, err := cache.NewBBoltCache[string](&cache.BBoltCacheConfig{
+		DbPath: filepath.Join(t.TempDir(), "ns.db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cacheList := map[string]cache.CommCache[string]{
+		"bolt":   boltCache,                                      // 按代数失效
+		"memLru": cache.NewMemLruCache[string](100, time.Minute), // 退化为按前缀删除
+	}
+	for name, one := range cacheList {
+		_, _ = cache.NsSet[string](ctx, one, "product", "123", "old", 0)
+		_, _ = cache.NsSet[string](ctx, one, "other", "123", "keep", 0)
+		if v, _ := cache.NsGet[string](ctx, one, "product", "123"); v != "old" {
+			t.Fatalf("%s: expected old, got %q", name, v)
+		}
+
+		if err = cache.NsInvalidate(ctx, one, "product"); err != nil {
+			t.Fatalf("%s: invalidate error: %v", name, err)
+		}
+		if v, _ := cache.NsGet[string](ctx, one, "product", "123"); v != "" {
+			t.Errorf("%s: key should be invalidated, got %q", name, v)
+		}
+		if v, _ := cache.NsGet[string](ctx, one, "other", "123"); v != "keep" {
+			t.Errorf("%s: other namespace should keep, got %q", name, v)
+		}
+
+		_, _ = cache.NsSet[string](ctx, one, "product", "123", "new", 0)
+		if v, _ := cache.NsGet[string](ctx, one, "product", "123"); v != "new" {
+			t.Errorf("%s: expected new, got %q", name, v)
+		}
+	}
+
+	// 代数计数器不在命名空间前缀下，遍历和按前缀删除都不会碰到
+	keys, _, _ := boltCache.(cache.ScannableCache).Scan(ctx, "{product}", "", 100)
+	if len(keys) != 2 {
+		t.Errorf("expected old and new keys, got %q", keys)
+	}
+	if n, _ := cache.NsDeleteAll(ctx, boltCache.(cache.ScannableCache), "product"); n != 2 {
+		t.Errorf("expected delete 2 keys, got %d", n)
+	}
+	_, _ = boltCache.Set(ctx, "{product}123", "old", 0)
+	if v, _ := cache.NsGet[string](ctx, boltCache, "product", "123"); v != "" {
+		t.Errorf("generation should survive NsDeleteAll, got %q", v)
+	}
+}
@@ -2,9 +2,11 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/magic-lib/go-plat-startupcfg/startupcfg"
 	"github.com/magic-lib/go-plat-utils/conv"
+	"github.com/redis/go-redis/v9"
 	"time"
 )
 
@@ -25,7 +27,7 @@ func SetDefaultRedisConfig(con *startupcfg.RedisConfig) {
 }
 
 // getRealRedisConfig 获取真实的redis配置
-func getRealRedisConfig(redisCfg ...*startupcfg.RedisConfig) *startupcfg.RedisConfig {
+func getRealRedisConfig(opt *RedisOptions, redisCfg ...*startupcfg.RedisConfig) *startupcfg.RedisConfig {
 	if redisCfg == nil {
 		redisCfg = make([]*startupcfg.RedisConfig, 0)
 	}
@@ -37,7 +39,7 @@ func getRealRedisConfig(redisCfg ...*startupcfg.RedisConfig) *startupcfg.RedisCo
 		if oneCfg == nil {
 			continue
 		}
-		redisCli := NewRedisClient(oneCfg)
+		redisCli := NewRedisClient(oneCfg, opt)
 		connected := redisCli.CheckConnect()
 		if connected {
 			return oneCfg
@@ -49,11 +51,16 @@ func getRealRedisConfig(redisCfg ...*startupcfg.RedisConfig) *startupcfg.RedisCo
 
 // NewRedisCache 新建
 func NewRedisCache[V any](redisCfg ...*startupcfg.RedisConfig) (CommCache[V], error) {
-	oneCfg := getRealRedisConfig(redisCfg...)
+	return NewRedisCacheWithOptions[V](nil, redisCfg...)
+}
+
+// NewRedisCacheWithOptions 按连接参数新建，opt 为空时使用 RedisConfig.Extend 中的配置或默认值
+func NewRedisCacheWithOptions[V any](opt *RedisOptions, redisCfg ...*startupcfg.RedisConfig) (CommCache[V], error) {
+	oneCfg := getRealRedisConfig(opt, redisCfg...)
 	if oneCfg != nil {
 		return &redisCache[V]{
 			redisCfg: oneCfg,
-			rc:       NewRedisClient(oneCfg),
+			rc:       NewRedisClient(oneCfg, opt),
 		}, nil
 	}
 	return nil, fmt.Errorf("redis NewRedisCache config error: %v", redisCfg)
@@ -78,3 +85,181 @@ func (co *redisCache[V]) Set(ctx context.Context, key string, val V, timeout tim
 func (co *redisCache[V]) Del(ctx context.Context, key string) (bool, error) {
 	return co.rc.Del(getContext(ctx), key)
 }
+
+// mGet 分片缓存批量取值，一次往返
+func (co *redisCache[V]) mGet(ctx context.Context, keys []string) (map[string]V, error) {
+	strValues, err := co.rc.MGet(getContext(ctx), keys...)
+	values := make(map[string]V, len(strValues))
+	for key, dataStr := range strValues {
+		v, convErr := strToVal[V](dataStr)
+		if convErr != nil {
+			continue
+		}
+		values[key] = v
+	}
+	return values, err
+}
+
+// mSet 分片缓存批量写入 keys 对应的值，一次往返
+func (co *redisCache[V]) mSet(ctx context.Context, values map[string]V, keys []string, timeout time.Duration) error {
+	strValues := make(map[string]string, len(keys))
+	for _, key := range keys {
+		strValues[key] = conv.String(values[key])
+	}
+	return co.rc.MSet(getContext(ctx), strValues, timeout)
+}
+
+// mDel 分片缓存批量删除，一次往返
+func (co *redisCache[V]) mDel(ctx context.Context, keys []string) (int64, error) {
+	return co.rc.MDel(getContext(ctx), keys...)
+}
+
+// PipelineStats 自动合并 pipeline 的统计，未开启时返回 nil
+func (co *redisCache[V]) PipelineStats() *RedisPipelineStats {
+	return co.rc.PipelineStats()
+}
+
+// TTL 返回剩余有效期
+func (co *redisCache[V]) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return co.rc.TTL(getContext(ctx), key)
+}
+
+// Expire 重新设置过期时间
+func (co *redisCache[V]) Expire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	return co.rc.Expire(getContext(ctx), key, ttl)
+}
+
+// Touch 按写入时的时长续期，需要开启 RedisOptions.WriteMeta
+func (co *redisCache[V]) Touch(ctx context.Context, key string) (bool, error) {
+	return co.rc.Touch(getContext(ctx), key)
+}
+
+// getEx 取值的同时续期，供 slidingCache 使用，减少一次网络请求
+func (co *redisCache[V]) getEx(ctx context.Context, key string, ttl time.Duration) (V, error) {
+	dataStr, err := co.rc.GetEx(getContext(ctx), key, ttl)
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+	return strToVal[V](dataStr)
+}
+
+// IncrBy 原子增加计数
+func (co *redisCache[V]) IncrBy(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	return co.rc.IncrBy(getContext(ctx), key, delta, ttl)
+}
+
+// DecrBy 原子减少计数
+func (co *redisCache[V]) DecrBy(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	return co.rc.IncrBy(getContext(ctx), key, -delta, ttl)
+}
+
+// incrPersistent 原子增加计数，新建的 key 不过期
+func (co *redisCache[V]) incrPersistent(ctx context.Context, key string, delta int64) (int64, error) {
+	return co.rc.incrPersistent(getContext(ctx), key, delta)
+}
+
+// nsGenerationCache 与 redisClient 共用，通过任一方 NsInvalidate 都能立即看到
+func (co *redisCache[V]) nsGenerationCache() *nsGenerationCache {
+	return co.rc.nsGenerationCache()
+}
+
+// GetCounter 获取计数
+func (co *redisCache[V]) GetCounter(ctx context.Context, key string) (int64, error) {
+	return co.rc.GetCounter(getContext(ctx), key)
+}
+
+// GetWithVersion 取值及其版本号，不存在时返回零值和版本号 0，需要开启 RedisOptions.WriteMeta
+func (co *redisCache[V]) GetWithVersion(ctx context.Context, key string) (V, int64, error) {
+	var zero V
+	dataStr, version, err := co.rc.GetWithVersion(getContext(ctx), key)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return zero, 0, nil
+		}
+		return zero, 0, err
+	}
+	v, err := strToVal[V](dataStr)
+	return v, version, err
+}
+
+// CompareAndSet 版本号一致时写入，需要开启 RedisOptions.WriteMeta
+func (co *redisCache[V]) CompareAndSet(ctx context.Context, key string, expectedVersion int64, val V, ttl time.Duration) (int64, error) {
+	return co.rc.CompareAndSet(getContext(ctx), key, expectedVersion, conv.String(val), ttl)
+}
+
+// Scan 按游标遍历以 prefix 开头的 key
+func (co *redisCache[V]) Scan(ctx context.Context, prefix string, cursor string, count int) ([]string, string, error) {
+	return co.rc.Scan(getContext(ctx), prefix, cursor, count)
+}
+
+// DeletePrefix 删除以 prefix 开头的所有 key
+func (co *redisCache[V]) DeletePrefix(ctx context.Context, prefix string) (int64, error) {
+	return co.rc.DeletePrefix(getContext(ctx), prefix)
+}
+
+// AddTagKeys 记录 key 属于 tags
+func (co *redisCache[V]) AddTagKeys(ctx context.Context, key string, ttl time.Duration, tags ...string) error {
+	return co.rc.AddTagKeys(getContext(ctx), key, ttl, tags...)
+}
+
+// TagKeys 返回 tag 下未过期的 key
+func (co *redisCache[V]) TagKeys(ctx context.Context, tag string) ([]string, error) {
+	return co.rc.TagKeys(getContext(ctx), tag)
+}
+
+// DelTags 删除 tags 的索引
+func (co *redisCache[V]) DelTags(ctx context.Context, tags ...string) error {
+	return co.rc.DelTags(getContext(ctx), tags...)
+}
+
+// DelTagKeys 从 tag 的索引中删除指定的 key
+func (co *redisCache[V]) DelTagKeys(ctx context.Context, tag string, keys ...string) error {
+	return co.rc.DelTagKeys(getContext(ctx), tag, keys...)
+}
+
+// HGet 取 field 的值
+func (co *redisCache[V]) HGet(ctx context.Context, key, field string) (V, error) {
+	dataStr, err := co.rc.HGet(getContext(ctx), key, field)
+	if err != nil {
+		var zero V
+		if errors.Is(err, redis.Nil) {
+			return zero, ErrNotFound
+		}
+		return zero, err
+	}
+	return strToVal[V](dataStr)
+}
+
+// HSet 写入 field 并重置整个 key 的有效期
+func (co *redisCache[V]) HSet(ctx context.Context, key, field string, val V, ttl time.Duration) (bool, error) {
+	return co.rc.HMSet(getContext(ctx), key, map[string]string{field: conv.String(val)}, ttl)
+}
+
+// HMSet 批量写入 field
+func (co *redisCache[V]) HMSet(ctx context.Context, key string, values map[string]V, ttl time.Duration) (bool, error) {
+	strValues := make(map[string]string, len(values))
+	for field, val := range values {
+		strValues[field] = conv.String(val)
+	}
+	return co.rc.HMSet(getContext(ctx), key, strValues, ttl)
+}
+
+// HDel 删除 fields，fields 为空时删除整个 key
+func (co *redisCache[V]) HDel(ctx context.Context, key string, fields ...string) (int64, error) {
+	return co.rc.HDelFields(getContext(ctx), key, fields...)
+}
+
+// HGetAll 取出所有 field
+func (co *redisCache[V]) HGetAll(ctx context.Context, key string) (map[string]V, error) {
+	strValues, err := co.rc.HGetAll(getContext(ctx), key)
+	if err != nil {
+		return nil, err
+	}
+	return strMapToVal[V](strValues)
+}
+
+// HLen field 的数量
+func (co *redisCache[V]) HLen(ctx context.Context, key string) (int64, error) {
+	return co.rc.HLen(getContext(ctx), key)
+}
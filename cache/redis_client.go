@@ -3,78 +3,218 @@ package cache
 import (
 	"context"
 	"fmt"
-	"github.com/go-redis/redis/v8"
 	"github.com/magic-lib/go-plat-startupcfg/startupcfg"
 	"github.com/magic-lib/go-plat-utils/logs"
+	"github.com/redis/go-redis/v9"
 	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 var (
-	minMaxTimeout     = 24 * time.Hour      //最小的最长时间
-	redisMaxTimeout   = 24 * 90 * time.Hour //redis最长存储时间点，避免无限期占用Redis空间
-	checkConnInterval = 20 * time.Second
+	// casScript 版本号一致时写入值，版本号加 1，返回 {是否成功, 版本号}。
+	// 值仍是普通字符串，版本号在附属 hash 中，存在但没有记录版本号的 key(如计数器)按 1 处理
+	casScript = redis.NewScript(`
+local cur = 0
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	cur = tonumber(redis.call('HGET', KEYS[2], 'ver') or '1')
+end
+if cur ~= tonumber(ARGV[1]) then
+	return {0, cur}
+end
+redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+redis.call('HSET', KEYS[2], 'ver', cur + 1, 'ttl', ARGV[3])
+redis.call('PEXPIRE', KEYS[2], ARGV[3])
+return {1, cur + 1}
+`)
+	// setScript 写入值并将版本号加 1，在附属 hash 中记录写入时的时长，供 Touch 使用
+	setScript = redis.NewScript(`
+local cur = 0
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	cur = tonumber(redis.call('HGET', KEYS[2], 'ver') or '1')
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+redis.call('HSET', KEYS[2], 'ver', cur + 1, 'ttl', ARGV[2])
+redis.call('PEXPIRE', KEYS[2], ARGV[2])
+return cur + 1
+`)
+	// expireScript 重新设置过期时间，并把新的时长记为写入时的时长
+	expireScript = redis.NewScript(`
+if redis.call('PEXPIRE', KEYS[1], ARGV[1]) == 0 then
+	return 0
+end
+redis.call('HSET', KEYS[2], 'ttl', ARGV[1])
+redis.call('PEXPIRE', KEYS[2], ARGV[1])
+return 1
+`)
+	// touchScript 按写入时的时长续期，没有记录时长的 key 不改变过期时间
+	touchScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+local ttl = redis.call('HGET', KEYS[2], 'ttl')
+if ttl then
+	redis.call('PEXPIRE', KEYS[1], ttl)
+	redis.call('PEXPIRE', KEYS[2], ttl)
+end
+return 1
+`)
+	// getExScript 取值的同时续期，与 expireScript 一样更新记录的时长
+	getExScript = redis.NewScript(`
+local v = redis.call('GET', KEYS[1])
+if not v then
+	return false
+end
+redis.call('PEXPIRE', KEYS[1], ARGV[1])
+redis.call('HSET', KEYS[2], 'ttl', ARGV[1])
+redis.call('PEXPIRE', KEYS[2], ARGV[1])
+return v
+`)
+	// incrByScript 增加计数，没有过期时间(本次新建)时同时设置过期时间，两步在同一个脚本中完成
+	incrByScript = redis.NewScript(`
+local n = redis.call('INCRBY', KEYS[1], ARGV[1])
+if redis.call('PTTL', KEYS[1]) == -1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return n
+`)
+	// getWithVersionScript 取值及版本号，不存在时版本号为 0
+	getWithVersionScript = redis.NewScript(`
+local v = redis.call('GET', KEYS[1])
+if not v then
+	return {false, 0}
+end
+return {v, tonumber(redis.call('HGET', KEYS[2], 'ver') or '1')}
+`)
 )
 
+var (
+	minMaxTimeout          = 24 * time.Hour      //最小的最长时间
+	defaultRedisMaxTimeout = 24 * 90 * time.Hour //redis默认最长存储时间点，避免无限期占用Redis空间
+	checkConnInterval      = 20 * time.Second
+)
+
+const redisMetaSuffix = "\x00meta" // 附属 hash 的后缀
+
 // redisClient 内部redis结构
 type redisClient struct {
-	redisCfg *startupcfg.RedisConfig
-	cli      *redis.Client
+	redisCfg   *startupcfg.RedisConfig
+	opt        *RedisOptions         // 连接参数
+	maxTimeout time.Duration         // 最长存储时间
+	cli        redis.UniversalClient // 单节点、集群或哨兵客户端
+	pipeline   *autoPipeline         // 自动合并 pipeline，未开启时为 nil
+	nsGens     nsGenerationCache     // 命名空间代数的本地缓存
 }
 
-// NewRedisClient 新建redis连接
-func NewRedisClient(redisCfg *startupcfg.RedisConfig) *redisClient {
-	return &redisClient{
-		redisCfg: redisCfg,
-		cli:      nil,
+// NewRedisClient 新建redis连接，opt 为空时使用 RedisConfig.Extend 中的配置或默认值
+func NewRedisClient(redisCfg *startupcfg.RedisConfig, opt ...*RedisOptions) *redisClient {
+	redisOpt := getRedisOptions(redisCfg, opt...)
+	rc := &redisClient{
+		redisCfg:   redisCfg,
+		opt:        redisOpt,
+		maxTimeout: redisOpt.MaxTimeout,
+		cli:        nil,
 	}
+	if redisOpt.AutoPipeline != nil {
+		rc.pipeline = newAutoPipeline(rc.BatchExec, redisOpt.AutoPipeline)
+	}
+	return rc
 }
 
-// SetMaxTimeout xxx
+// PipelineStats 自动合并 pipeline 的统计，未开启时返回 nil
+func (r *redisClient) PipelineStats() *RedisPipelineStats {
+	if r.pipeline == nil {
+		return nil
+	}
+	return r.pipeline.stats()
+}
+
+// SetMaxTimeout 设置当前客户端的最长存储时间，不影响其他客户端
 func (r *redisClient) SetMaxTimeout(timeout time.Duration) {
 	if timeout > minMaxTimeout { //必须大于一天，设置过短的时间点会出现问题
-		redisMaxTimeout = timeout
+		r.maxTimeout = timeout
 	}
 }
 
 // NsGet xxx
 func (r *redisClient) NsGet(ctx context.Context, ns string, key string) (string, error) {
-	return r.Get(ctx, getNsKey(ns, key))
+	nsKey, err := nsKeyOf(ctx, r, ns, key)
+	if err != nil {
+		return "", err
+	}
+	return r.Get(ctx, nsKey)
 }
 
 // NsSet xxx
 func (r *redisClient) NsSet(ctx context.Context, ns string, key, val string, timeout time.Duration) (bool, error) {
-	return r.Set(ctx, getNsKey(ns, key), val, timeout)
+	nsKey, err := nsKeyOf(ctx, r, ns, key)
+	if err != nil {
+		return false, err
+	}
+	return r.Set(ctx, nsKey, val, timeout)
 }
 
 // NsDel xxx
 func (r *redisClient) NsDel(ctx context.Context, ns string, key string) (bool, error) {
-	return r.Del(ctx, getNsKey(ns, key))
+	nsKey, err := nsKeyOf(ctx, r, ns, key)
+	if err != nil {
+		return false, err
+	}
+	return r.Del(ctx, nsKey)
 }
 
 // NsHGet xxx
 func (r *redisClient) NsHGet(ctx context.Context, ns string, key, field string) (string, error) {
-	return r.HGet(ctx, getNsKey(ns, key), field)
+	nsKey, err := nsKeyOf(ctx, r, ns, key)
+	if err != nil {
+		return "", err
+	}
+	return r.HGet(ctx, nsKey, field)
 }
 
 // NsHSet xxx
 func (r *redisClient) NsHSet(ctx context.Context, ns string, key, field, val string, timeout time.Duration) (bool, error) {
-	return r.HSet(ctx, getNsKey(ns, key), field, val, timeout)
+	nsKey, err := nsKeyOf(ctx, r, ns, key)
+	if err != nil {
+		return false, err
+	}
+	return r.HSet(ctx, nsKey, field, val, timeout)
 }
 
 // NsHDel xxx
 func (r *redisClient) NsHDel(ctx context.Context, ns string, key, field string) (bool, error) {
-	return r.HDel(ctx, getNsKey(ns, key), field)
+	nsKey, err := nsKeyOf(ctx, r, ns, key)
+	if err != nil {
+		return false, err
+	}
+	return r.HDel(ctx, nsKey, field)
 }
 
 // Get 从缓存中取得一个值，如果没有redis则从本地缓存
 func (r *redisClient) Get(ctx context.Context, key string) (string, error) {
-	c, err := r.getClient(ctx)
-	if err != nil {
-		return "", err
+	var (
+		rep string
+		err error
+	)
+	if r.pipeline != nil {
+		var cmd redis.Cmder
+		cmd, err = r.pipeline.do(ctx, func(ctx context.Context, pipe redis.Pipeliner) redis.Cmder {
+			return pipe.Get(ctx, key)
+		})
+		if cmd != nil {
+			rep, err = cmd.(*redis.StringCmd).Result()
+		}
+	} else {
+		var c redis.UniversalClient
+		if c, err = r.getClient(ctx); err != nil {
+			return "", err
+		}
+		rep, err = c.Get(ctx, key).Result()
 	}
-	var rep string
-	rep, err = c.Get(ctx, key).Result()
 	if err != nil {
 		//key查不到，为空即可，不要报错
 		if err == redis.Nil {
@@ -92,25 +232,52 @@ func (r *redisClient) Set(ctx context.Context, key, val string, timeout time.Dur
 		return false, err
 	}
 
-	if timeout <= 0 || timeout > redisMaxTimeout {
+	if timeout <= 0 || timeout > r.maxTimeout {
 		//设置一个有效的时间点
-		timeout = redisMaxTimeout
+		timeout = r.maxTimeout
 	}
 
-	err = c.Set(ctx, key, val, timeout).Err()
+	metaKey := r.metaKey(key)
+	switch {
+	case metaKey == "" && r.pipeline != nil:
+		_, err = r.pipeline.do(ctx, func(ctx context.Context, pipe redis.Pipeliner) redis.Cmder {
+			return pipe.Set(ctx, key, val, timeout)
+		})
+	case metaKey == "":
+		err = c.Set(ctx, key, val, timeout).Err()
+	case r.pipeline != nil:
+		keys := []string{key, metaKey}
+		_, err = r.pipeline.do(ctx, func(ctx context.Context, pipe redis.Pipeliner) redis.Cmder {
+			return setScript.EvalSha(ctx, pipe, keys, val, timeout.Milliseconds())
+		})
+		if isNoScript(err) {
+			// 脚本未加载(如 redis 重启后)，Run 会用 EVAL 重新加载
+			err = setScript.Run(ctx, c, keys, val, timeout.Milliseconds()).Err()
+		}
+	default:
+		err = setScript.Run(ctx, c, []string{key, metaKey}, val, timeout.Milliseconds()).Err()
+	}
 	if err != nil {
 		return false, err
 	}
 	return true, nil
 }
 
-// Del 从缓存中删除一个key
+// Del 从缓存中删除一个key，开启 WriteMeta 时同时删除附属 hash
 func (r *redisClient) Del(ctx context.Context, key string) (bool, error) {
-	c, err := r.getClient(ctx)
-	if err != nil {
-		return false, err
+	keys := r.withMetaKey(key)
+	var err error
+	if r.pipeline != nil {
+		_, err = r.pipeline.do(ctx, func(ctx context.Context, pipe redis.Pipeliner) redis.Cmder {
+			return pipe.Del(ctx, keys...)
+		})
+	} else {
+		var c redis.UniversalClient
+		if c, err = r.getClient(ctx); err != nil {
+			return false, err
+		}
+		err = c.Del(ctx, keys...).Err()
 	}
-	err = c.Del(ctx, key).Err()
 	if err != nil {
 		return false, err
 	}
@@ -124,9 +291,9 @@ func (r *redisClient) HSet(ctx context.Context, key, field string, value string,
 		return false, err
 	}
 
-	if timeout <= 0 || timeout > redisMaxTimeout {
+	if timeout <= 0 || timeout > r.maxTimeout {
 		//设置一个有效的时间点
-		timeout = redisMaxTimeout
+		timeout = r.maxTimeout
 	}
 
 	err = c.HSet(ctx, key, field, value).Err()
@@ -170,6 +337,566 @@ func (r *redisClient) HDel(ctx context.Context, key, field string) (bool, error)
 	return true, nil
 }
 
+// HMSet 批量写入 field，并在同一个事务中重置整个 key 的有效期
+func (r *redisClient) HMSet(ctx context.Context, key string, values map[string]string, timeout time.Duration) (bool, error) {
+	if len(values) == 0 {
+		return false, nil
+	}
+	c, err := r.getClient(ctx)
+	if err != nil {
+		return false, err
+	}
+	if timeout <= 0 || timeout > r.maxTimeout {
+		timeout = r.maxTimeout
+	}
+	_, err = c.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, values)
+		pipe.Expire(ctx, key, timeout)
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// HDelFields 删除多个 field，返回实际删除的数量，fields 为空时删除整个 key
+func (r *redisClient) HDelFields(ctx context.Context, key string, fields ...string) (int64, error) {
+	c, err := r.getClient(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(fields) > 0 {
+		return c.HDel(ctx, key, fields...).Result()
+	}
+	var lenCmd *redis.IntCmd
+	_, err = c.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		lenCmd = pipe.HLen(ctx, key)
+		pipe.Del(ctx, key)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return lenCmd.Val(), nil
+}
+
+// HGetAll 取出所有 field
+func (r *redisClient) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	c, err := r.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.HGetAll(ctx, key).Result()
+}
+
+// HLen field 的数量
+func (r *redisClient) HLen(ctx context.Context, key string) (int64, error) {
+	c, err := r.getClient(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return c.HLen(ctx, key).Result()
+}
+
+// TTL 返回剩余有效期，不存在返回 TTLNotFound，永不过期返回 TTLNoExpire
+func (r *redisClient) TTL(ctx context.Context, key string) (time.Duration, error) {
+	c, err := r.getClient(ctx)
+	if err != nil {
+		return TTLNotFound, err
+	}
+	// PTTL 对不存在和永不过期的 key 分别返回 -2、-1，与 TTLNotFound、TTLNoExpire 一致
+	return c.PTTL(ctx, key).Result()
+}
+
+// Expire 重新设置过期时间
+func (r *redisClient) Expire(ctx context.Context, key string, timeout time.Duration) (bool, error) {
+	c, err := r.getClient(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if timeout <= 0 || timeout > r.maxTimeout {
+		//设置一个有效的时间点
+		timeout = r.maxTimeout
+	}
+	metaKey := r.metaKey(key)
+	if metaKey == "" {
+		return c.PExpire(ctx, key, timeout).Result()
+	}
+	n, err := expireScript.Run(ctx, c, []string{key, metaKey}, timeout.Milliseconds()).Int64()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// Touch 按 Set 或 Expire 时的时长续期，时长记录在附属 hash 中，需要开启 WriteMeta。
+// 没有记录的 key(如计数器、无法与附属 hash 放在同一 slot 的 key)不改变过期时间
+func (r *redisClient) Touch(ctx context.Context, key string) (bool, error) {
+	if !r.opt.WriteMeta {
+		return false, fmt.Errorf("redis Touch needs RedisOptions.WriteMeta to record the write-time ttl")
+	}
+	c, err := r.getClient(ctx)
+	if err != nil {
+		return false, err
+	}
+	metaKey := r.metaKey(key)
+	if metaKey == "" {
+		n, err := c.Exists(ctx, key).Result()
+		return n > 0, err
+	}
+	n, err := touchScript.Run(ctx, c, []string{key, metaKey}).Int64()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// GetEx 取值的同时重新设置过期时间
+func (r *redisClient) GetEx(ctx context.Context, key string, timeout time.Duration) (string, error) {
+	c, err := r.getClient(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if timeout <= 0 || timeout > r.maxTimeout {
+		//设置一个有效的时间点
+		timeout = r.maxTimeout
+	}
+	var rep string
+	if metaKey := r.metaKey(key); metaKey != "" {
+		rep, err = getExScript.Run(ctx, c, []string{key, metaKey}, timeout.Milliseconds()).Text()
+	} else {
+		rep, err = c.GetEx(ctx, key, timeout).Result()
+	}
+	if err != nil {
+		if err == redis.Nil {
+			log.Println("[redis-client] error: result not found")
+		}
+		return "", err
+	}
+	return rep, nil
+}
+
+// IncrBy 原子增加计数，新建的 key 设置过期时间 timeout
+func (r *redisClient) IncrBy(ctx context.Context, key string, delta int64, timeout time.Duration) (int64, error) {
+	if timeout <= 0 || timeout > r.maxTimeout {
+		//设置一个有效的时间点
+		timeout = r.maxTimeout
+	}
+	c, err := r.getClient(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return incrByScript.Run(ctx, c, []string{key}, delta, timeout.Milliseconds()).Int64()
+}
+
+// DecrBy 原子减少计数
+func (r *redisClient) DecrBy(ctx context.Context, key string, delta int64, timeout time.Duration) (int64, error) {
+	return r.IncrBy(ctx, key, -delta, timeout)
+}
+
+// incrPersistent 原子增加计数，新建的 key 不过期
+func (r *redisClient) incrPersistent(ctx context.Context, key string, delta int64) (int64, error) {
+	c, err := r.getClient(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return c.IncrBy(ctx, key, delta).Result()
+}
+
+func (r *redisClient) nsGenerationCache() *nsGenerationCache {
+	return &r.nsGens
+}
+
+// GetCounter 获取计数，不存在返回 0
+func (r *redisClient) GetCounter(ctx context.Context, key string) (int64, error) {
+	c, err := r.getClient(ctx)
+	if err != nil {
+		return 0, err
+	}
+	n, err := c.Get(ctx, key).Int64()
+	if err != nil {
+		if err == redis.Nil {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return n, nil
+}
+
+// GetWithVersion 取值及其版本号，key 不存在返回 redis.Nil，需要开启 WriteMeta
+func (r *redisClient) GetWithVersion(ctx context.Context, key string) (string, int64, error) {
+	metaKey, err := r.versionMetaKey(key)
+	if err != nil {
+		return "", 0, err
+	}
+	c, err := r.getClient(ctx)
+	if err != nil {
+		return "", 0, err
+	}
+	ret, err := getWithVersionScript.Run(ctx, c, []string{key, metaKey}).Slice()
+	if err != nil {
+		return "", 0, err
+	}
+	if len(ret) != 2 || ret[0] == nil {
+		return "", 0, redis.Nil
+	}
+	val, _ := ret[0].(string)
+	version, _ := ret[1].(int64)
+	return val, version, nil
+}
+
+// CompareAndSet 版本号一致时写入，比较和写入在同一个脚本中完成，保证原子性，需要开启 WriteMeta
+func (r *redisClient) CompareAndSet(ctx context.Context, key string, expectedVersion int64, val string, timeout time.Duration) (int64, error) {
+	metaKey, err := r.versionMetaKey(key)
+	if err != nil {
+		return 0, err
+	}
+	c, err := r.getClient(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	if timeout <= 0 || timeout > r.maxTimeout {
+		//设置一个有效的时间点
+		timeout = r.maxTimeout
+	}
+	ret, err := casScript.Run(ctx, c, []string{key, metaKey}, expectedVersion, val, timeout.Milliseconds()).Int64Slice()
+	if err != nil {
+		return 0, err
+	}
+	if len(ret) != 2 {
+		return 0, fmt.Errorf("redis CompareAndSet unexpected result: %v", ret)
+	}
+	if ret[0] != 1 {
+		return ret[1], &ErrVersionConflict{Key: key, Expected: expectedVersion, Actual: ret[1]}
+	}
+	return ret[1], nil
+}
+
+// Scan 按游标遍历以 prefix 开头的 key，游标为 redis SCAN 返回的游标。
+// 集群模式下 prefix 包含完整的 hash tag(如 {ns})时只需遍历所在节点，否则遍历所有主节点，游标为上一批的最后一个 key
+func (r *redisClient) Scan(ctx context.Context, prefix string, cursor string, count int) ([]string, string, error) {
+	c, err := r.getClient(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	if count <= 0 {
+		count = defaultScanCount
+	}
+	var node redis.Cmdable = c
+	if cc, ok := c.(*redis.ClusterClient); ok {
+		if redisHashTag(prefix) == "" {
+			return scanCluster(ctx, cc, prefix, cursor, count)
+		}
+		if node, err = cc.MasterForKey(ctx, prefix); err != nil {
+			return nil, "", err
+		}
+	}
+	var cur uint64
+	if cursor != "" {
+		if cur, err = strconv.ParseUint(cursor, 10, 64); err != nil {
+			return nil, "", fmt.Errorf("redis Scan invalid cursor: %s", cursor)
+		}
+	}
+	keys, next, err := node.Scan(ctx, cur, escapeGlob(prefix)+"*", int64(count)).Result()
+	if err != nil {
+		return nil, "", err
+	}
+	keys = skipRedisMetaKeys(keys)
+	if next == 0 {
+		return keys, "", nil
+	}
+	return keys, strconv.FormatUint(next, 10), nil
+}
+
+// scanCluster 遍历所有主节点取出以 prefix 开头的 key，按字典序分批返回
+func scanCluster(ctx context.Context, cc *redis.ClusterClient, prefix string, cursor string, count int) ([]string, string, error) {
+	var mu sync.Mutex
+	allKeys := make([]string, 0)
+	err := cc.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+		iter := node.Scan(ctx, 0, escapeGlob(prefix)+"*", defaultScanCount).Iterator()
+		keys := make([]string, 0)
+		for iter.Next(ctx) {
+			if !isRedisMetaKey(iter.Val()) {
+				keys = append(keys, iter.Val())
+			}
+		}
+		mu.Lock()
+		allKeys = append(allKeys, keys...)
+		mu.Unlock()
+		return iter.Err()
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	sort.Strings(allKeys)
+	keys, next := scanSortedKeys(allKeys, prefix, cursor, count)
+	return keys, next, nil
+}
+
+// DeletePrefix 用 SCAN 分批找出以 prefix 开头的 key 并 UNLINK，不会阻塞 redis。
+// 集群模式下 prefix 包含完整的 hash tag 时只需处理所在节点，否则逐个主节点处理
+func (r *redisClient) DeletePrefix(ctx context.Context, prefix string) (int64, error) {
+	c, err := r.getClient(ctx)
+	if err != nil {
+		return 0, err
+	}
+	cc, ok := c.(*redis.ClusterClient)
+	if !ok {
+		return deletePrefixOnNode(ctx, c, prefix, false)
+	}
+	if redisHashTag(prefix) != "" {
+		node, err := cc.MasterForKey(ctx, prefix)
+		if err != nil {
+			return 0, err
+		}
+		return deletePrefixOnNode(ctx, node, prefix, true)
+	}
+	var total int64
+	err = cc.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+		n, err := deletePrefixOnNode(ctx, node, prefix, true)
+		atomic.AddInt64(&total, n)
+		return err
+	})
+	return total, err
+}
+
+// deletePrefixOnNode 在单个节点上删除以 prefix 开头的 key 及其附属 hash，返回删除的 key 数，不含附属 hash
+func deletePrefixOnNode(ctx context.Context, c redis.Cmdable, prefix string, cluster bool) (int64, error) {
+	var total int64
+	iter := c.Scan(ctx, 0, escapeGlob(prefix)+"*", defaultScanCount).Iterator()
+	keys := make([]string, 0, defaultScanCount)
+	for iter.Next(ctx) {
+		if isRedisMetaKey(iter.Val()) {
+			continue
+		}
+		keys = append(keys, iter.Val())
+		if len(keys) < defaultScanCount {
+			continue
+		}
+		n, err := unlinkWithMetaKeys(ctx, c, keys, cluster)
+		if err != nil {
+			return total, err
+		}
+		total += n
+		keys = keys[:0]
+	}
+	if err := iter.Err(); err != nil {
+		return total, err
+	}
+	if len(keys) > 0 {
+		n, err := unlinkWithMetaKeys(ctx, c, keys, cluster)
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// unlinkWithMetaKeys 删除 key 及其附属 hash，返回删除的 key 数
+func unlinkWithMetaKeys(ctx context.Context, c redis.Cmdable, keys []string, cluster bool) (int64, error) {
+	n, err := unlinkKeys(ctx, c, keys, cluster)
+	if err != nil {
+		return n, err
+	}
+	metaKeys := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if metaKey := redisMetaKey(key); metaKey != "" {
+			metaKeys = append(metaKeys, metaKey)
+		}
+	}
+	if len(metaKeys) > 0 {
+		_, err = unlinkKeys(ctx, c, metaKeys, cluster)
+	}
+	return n, err
+}
+
+// unlinkKeys 删除多个 key，集群模式下按 hash tag 分组，同一组的 key 在同一个 slot，避免 CROSSSLOT 错误
+func unlinkKeys(ctx context.Context, c redis.Cmdable, keys []string, cluster bool) (int64, error) {
+	if !cluster {
+		return c.Unlink(ctx, keys...).Result()
+	}
+	groups := make(map[string][]string)
+	for _, key := range keys {
+		slotKey := redisHashTag(key)
+		if slotKey == "" {
+			slotKey = key
+		}
+		groups[slotKey] = append(groups[slotKey], key)
+	}
+	pipe := c.Pipeline()
+	cmdList := make([]*redis.IntCmd, 0, len(groups))
+	for _, group := range groups {
+		cmdList = append(cmdList, pipe.Unlink(ctx, group...))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, cmd := range cmdList {
+		total += cmd.Val()
+	}
+	return total, nil
+}
+
+// AddTagKeys 用有序集合记录 tag 下的 key，分数为 key 的过期时间(毫秒)，写入时顺带清理已过期的条目
+func (r *redisClient) AddTagKeys(ctx context.Context, key string, timeout time.Duration, tags ...string) error {
+	if timeout <= 0 || timeout > r.maxTimeout {
+		//设置一个有效的时间点
+		timeout = r.maxTimeout
+	}
+	now := time.Now()
+	expiresAt := float64(now.Add(timeout).UnixMilli())
+	_, err := r.BatchExec(ctx, func(ctx context.Context, pipe redis.Pipeliner) []redis.Cmder {
+		cmdList := make([]redis.Cmder, 0, len(tags)*3)
+		for _, tag := range tags {
+			tagKey := getTagKey(tag)
+			cmdList = append(cmdList,
+				pipe.ZAdd(ctx, tagKey, redis.Z{Score: expiresAt, Member: key}),
+				pipe.ZRemRangeByScore(ctx, tagKey, "-inf", strconv.FormatInt(now.UnixMilli(), 10)),
+				pipe.PExpire(ctx, tagKey, r.maxTimeout),
+			)
+		}
+		return cmdList
+	})
+	return err
+}
+
+// TagKeys 返回 tag 下未过期的 key
+func (r *redisClient) TagKeys(ctx context.Context, tag string) ([]string, error) {
+	c, err := r.getClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return c.ZRangeByScore(ctx, getTagKey(tag), &redis.ZRangeBy{
+		Min: "(" + strconv.FormatInt(time.Now().UnixMilli(), 10),
+		Max: "+inf",
+	}).Result()
+}
+
+// DelTags 删除 tags 的索引
+func (r *redisClient) DelTags(ctx context.Context, tags ...string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	c, err := r.getClient(ctx)
+	if err != nil {
+		return err
+	}
+	tagKeys := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tagKeys = append(tagKeys, getTagKey(tag))
+	}
+	_, isCluster := c.(*redis.ClusterClient)
+	_, err = unlinkKeys(ctx, c, tagKeys, isCluster)
+	return err
+}
+
+// DelTagKeys 从 tag 的有序集合中删除指定的 key
+func (r *redisClient) DelTagKeys(ctx context.Context, tag string, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	c, err := r.getClient(ctx)
+	if err != nil {
+		return err
+	}
+	members := make([]any, 0, len(keys))
+	for _, key := range keys {
+		members = append(members, key)
+	}
+	return c.ZRem(ctx, getTagKey(tag), members...).Err()
+}
+
+// getTagKey tag 索引的 key
+func getTagKey(tag string) string {
+	return "__tag:" + tag
+}
+
+// redisHashTag 返回 key 中的 hash tag，即第一个 { 与其后第一个 } 之间的非空内容，没有则返回空。
+// 集群模式下 hash tag 相同的 key 在同一个 slot，getNsKey 生成的 {ns}key 同一命名空间的 key 都在同一个 slot
+func redisHashTag(key string) string {
+	start := strings.IndexByte(key, '{')
+	if start < 0 {
+		return ""
+	}
+	end := strings.IndexByte(key[start+1:], '}')
+	if end <= 0 {
+		return ""
+	}
+	return key[start+1 : start+1+end]
+}
+
+// redisMetaKey key 的附属 hash，记录版本号和写入时的时长，与 key 在同一个 slot。
+// key 没有 hash tag 时把整个 key 作为 hash tag，集群中计算出的 slot 与 key 相同；
+// 含有 } 却没有有效 hash tag 的 key(如 a}b、x{}y)无法构造同 slot 的名字，返回空
+func redisMetaKey(key string) string {
+	if redisHashTag(key) != "" {
+		return key + redisMetaSuffix
+	}
+	if strings.ContainsRune(key, '}') {
+		return ""
+	}
+	return "{" + key + "}" + redisMetaSuffix
+}
+
+// metaKey 开启 WriteMeta 时 key 的附属 hash，未开启或无法构造时为空，按普通 key 读写
+func (r *redisClient) metaKey(key string) string {
+	if !r.opt.WriteMeta {
+		return ""
+	}
+	return redisMetaKey(key)
+}
+
+// withMetaKey key 及其附属 hash，用于删除
+func (r *redisClient) withMetaKey(key string) []string {
+	if metaKey := r.metaKey(key); metaKey != "" {
+		return []string{key, metaKey}
+	}
+	return []string{key}
+}
+
+// versionMetaKey 版本号所在的附属 hash，没有时返回错误，避免 Set 不更新版本号导致 CompareAndSet 覆盖别人的写入
+func (r *redisClient) versionMetaKey(key string) (string, error) {
+	if !r.opt.WriteMeta {
+		return "", fmt.Errorf("redis version needs RedisOptions.WriteMeta")
+	}
+	metaKey := redisMetaKey(key)
+	if metaKey == "" {
+		return "", fmt.Errorf("redis version: key %q has no valid hash tag to share a slot with its meta", key)
+	}
+	return metaKey, nil
+}
+
+// isNoScript 脚本未加载，EVALSHA 返回 NOSCRIPT
+func isNoScript(err error) bool {
+	return err != nil && redis.HasErrorPrefix(err, "NOSCRIPT")
+}
+
+// isRedisMetaKey 是否是附属 hash，Scan 时不返回给调用方
+func isRedisMetaKey(key string) bool {
+	return strings.HasSuffix(key, redisMetaSuffix)
+}
+
+func skipRedisMetaKeys(keys []string) []string {
+	ret := keys[:0]
+	for _, key := range keys {
+		if !isRedisMetaKey(key) {
+			ret = append(ret, key)
+		}
+	}
+	return ret
+}
+
+// escapeGlob 转义 redis MATCH 中的通配符
+func escapeGlob(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`).Replace(s)
+}
+
 // BatchExec 批量执行
 func (r *redisClient) BatchExec(ctx context.Context, f func(ctx context.Context, pipe redis.Pipeliner) []redis.Cmder) ([]redis.Cmder, error) {
 	c, err := r.getClient(ctx)
@@ -185,6 +912,109 @@ func (r *redisClient) BatchExec(ctx context.Context, f func(ctx context.Context,
 	return cmdList, nil
 }
 
+// MGet 用一个 pipeline 批量取值，只返回存在的 key
+func (r *redisClient) MGet(ctx context.Context, keys ...string) (map[string]string, error) {
+	values := make(map[string]string, len(keys))
+	if len(keys) == 0 {
+		return values, nil
+	}
+	c, err := r.getClient(ctx)
+	if err != nil {
+		return values, err
+	}
+	pipe := c.Pipeline()
+	cmdList := make([]*redis.StringCmd, 0, len(keys))
+	for _, key := range keys {
+		cmdList = append(cmdList, pipe.Get(ctx, key))
+	}
+	// 不存在的 key 也会让 Exec 返回 redis.Nil，按每条命令的结果判断
+	_, _ = pipe.Exec(ctx)
+	var retErr error
+	for i, cmd := range cmdList {
+		v, err := cmd.Result()
+		if err != nil {
+			if err != redis.Nil && retErr == nil {
+				retErr = err
+			}
+			continue
+		}
+		values[keys[i]] = v
+	}
+	return values, retErr
+}
+
+// MSet 用一个 pipeline 批量写入，开启 WriteMeta 时与 Set 一样记录写入时的时长和版本号
+func (r *redisClient) MSet(ctx context.Context, values map[string]string, timeout time.Duration) error {
+	if len(values) == 0 {
+		return nil
+	}
+	if timeout <= 0 || timeout > r.maxTimeout {
+		//设置一个有效的时间点
+		timeout = r.maxTimeout
+	}
+	c, err := r.getClient(ctx)
+	if err != nil {
+		return err
+	}
+	pipe := c.Pipeline()
+	keyList := make([]string, 0, len(values))
+	cmdList := make([]redis.Cmder, 0, len(values))
+	for key, val := range values {
+		keyList = append(keyList, key)
+		if metaKey := r.metaKey(key); metaKey != "" {
+			// pipeline 中只发送脚本的 sha，不发送脚本内容
+			cmdList = append(cmdList, setScript.EvalSha(ctx, pipe, []string{key, metaKey}, val, timeout.Milliseconds()))
+		} else {
+			cmdList = append(cmdList, pipe.Set(ctx, key, val, timeout))
+		}
+	}
+	_, _ = pipe.Exec(ctx)
+	var retErr error
+	for i, cmd := range cmdList {
+		err := cmd.Err()
+		if isNoScript(err) {
+			// 脚本未加载时逐个用 Run 重试，Run 会用 EVAL 重新加载
+			key := keyList[i]
+			err = setScript.Run(ctx, c, []string{key, r.metaKey(key)}, values[key], timeout.Milliseconds()).Err()
+		}
+		if err != nil && retErr == nil {
+			retErr = err
+		}
+	}
+	return retErr
+}
+
+// MDel 用一个 pipeline 批量删除，返回实际删除的 key 数量
+func (r *redisClient) MDel(ctx context.Context, keys ...string) (int64, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	cmdList, err := r.BatchExec(ctx, func(ctx context.Context, pipe redis.Pipeliner) []redis.Cmder {
+		cmdList := make([]redis.Cmder, 0, len(keys))
+		for _, key := range keys {
+			cmdList = append(cmdList, pipe.Exists(ctx, key), pipe.Del(ctx, r.withMetaKey(key)...))
+		}
+		return cmdList
+	})
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	for i := 0; i < len(cmdList); i += 2 {
+		n += cmdList[i].(*redis.IntCmd).Val()
+	}
+	return n, nil
+}
+
+// RunScript 执行 lua 脚本，优先使用 EVALSHA，脚本未加载时自动 EVAL
+func (r *redisClient) RunScript(ctx context.Context, script *redis.Script, keys []string, args ...any) (any, error) {
+	c, err := r.getClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("redis getClient error: %w", err)
+	}
+	return script.Run(ctx, c, keys, args...).Result()
+}
+
 func (r *redisClient) CheckConnect() bool {
 	_, err := r.getOneRedis()
 	if err == nil {
@@ -193,7 +1023,7 @@ func (r *redisClient) CheckConnect() bool {
 	return false
 }
 
-func (r *redisClient) getClient(_ context.Context) (*redis.Client, error) {
+func (r *redisClient) getClient(_ context.Context) (redis.UniversalClient, error) {
 	cli, err := r.getOneRedis()
 	if cli != nil && err == nil {
 		return cli, nil
@@ -212,15 +1042,14 @@ func (r *redisClient) getClient(_ context.Context) (*redis.Client, error) {
 	return nil, err
 }
 
-func (r *redisClient) getOneRedis() (*redis.Client, error) {
+func (r *redisClient) getOneRedis() (redis.UniversalClient, error) {
 	manager := NewRedisClientManager(checkConnInterval)
-	rc := manager.Get(r.redisCfg)
-	if rc != nil && rc.cli != nil {
+	cli := manager.Get(r.redisCfg, r.opt)
+	if cli != nil {
 		if defaultRedisCfg == nil {
 			SetDefaultRedisConfig(r.redisCfg)
 		}
-		//r.cli = rc.cli
-		return rc.cli, nil
+		return cli, nil
 	}
 
 	return nil, fmt.Errorf("conn cant connect")
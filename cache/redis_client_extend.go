@@ -2,22 +2,34 @@ package cache
 
 import (
 	"context"
-	"crypto/tls"
 	"fmt"
-	"github.com/go-redis/redis/v8"
 	"github.com/magic-lib/go-plat-startupcfg/startupcfg"
 	"github.com/magic-lib/go-plat-utils/conv"
 	"github.com/magic-lib/go-plat-utils/goroutines"
+	"github.com/redis/go-redis/v9"
 	"runtime"
+	"strings"
 	"sync"
 	"time"
 )
 
+const (
+	redisTypeNode     = "node"     // 单节点
+	redisTypeCluster  = "cluster"  // 集群，Address 为逗号分隔的种子节点
+	redisTypeSentinel = "sentinel" // 哨兵，Address 格式为 masterName@host1:port,host2:port
+
+	redisAddrSep   = ","
+	redisMasterSep = "@"
+)
+
 var (
 	onceError sync.Once
 
 	defaultPingTimeout = 3 * time.Second
+)
 
+// 连接池的默认参数，可通过 RedisOptions 按连接修改
+const (
 	poolMaxSize = 100
 	poolMinSize = 10
 
@@ -28,10 +40,9 @@ var (
 	// 默认值为 ReadTimeout 加上1秒
 	poolIdleTimeout = 5 * time.Minute //Redis 连接在空闲状态下的最长存活时间，超过该时间的连接将被关闭。如果指定的值小于服务器上
 	// 的超时时间，则客户端在检查连接空闲时会关闭连接，以防止服务器出现连接超时。默认为5分钟。将其设为-1可以禁用连接空闲超时检查
-	poolIdleCheckFrequency = time.Minute //空闲连接检查频率。默认为1分钟。将其设为-1可以禁用连接空闲超时检查器，但是仍然会
 )
 
-func checkConnection(conn *redis.Client, pingTimeout time.Duration) error {
+func checkConnection(conn redis.UniversalClient, pingTimeout time.Duration) error {
 	if conn == nil {
 		return fmt.Errorf("conn is nil")
 	}
@@ -47,10 +58,16 @@ func checkConnection(conn *redis.Client, pingTimeout time.Duration) error {
 	return conn.Ping(newCtx).Err()
 }
 
-func getRedisFromCfg(redisCfg *startupcfg.RedisConfig) (*redis.Client, error) {
-	dialOpt := getRedisOption(redisCfg, getPoolSize())
-	newClient := redis.NewClient(dialOpt)
-	err := checkConnection(newClient, redisCfg.PingTimeout)
+func getRedisFromCfg(redisCfg *startupcfg.RedisConfig, opt *RedisOptions) (redis.UniversalClient, error) {
+	dialOpt, err := getRedisOption(redisCfg, opt)
+	if err != nil {
+		return nil, err
+	}
+	newClient, err := newUniversalClient(redisCfg, dialOpt)
+	if err != nil {
+		return nil, err
+	}
+	err = checkConnection(newClient, redisCfg.PingTimeout)
 	if err != nil {
 		_ = newClient.Close()
 		return nil, err
@@ -58,44 +75,91 @@ func getRedisFromCfg(redisCfg *startupcfg.RedisConfig) (*redis.Client, error) {
 	return newClient, nil
 }
 
-func getRedisOption(redisCfg startupcfg.Database, poolSize int) *redis.Options {
-	dialOpt := &redis.Options{}
+// newUniversalClient 根据配置的 Type 创建单节点、集群或哨兵客户端
+func newUniversalClient(redisCfg *startupcfg.RedisConfig, dialOpt *redis.UniversalOptions) (redis.UniversalClient, error) {
+	switch getRedisType(redisCfg) {
+	case redisTypeNode:
+		simpleOpt := dialOpt.Simple()
+		simpleOpt.Network = redisCfg.ProtocolName()
+		return redis.NewClient(simpleOpt), nil
+	case redisTypeCluster:
+		return redis.NewClusterClient(dialOpt.Cluster()), nil
+	case redisTypeSentinel:
+		if dialOpt.MasterName == "" {
+			return nil, fmt.Errorf("redis sentinel master name is empty, address should be masterName@host:port")
+		}
+		return redis.NewFailoverClient(dialOpt.Failover()), nil
+	}
+	return nil, fmt.Errorf("redis type '%s' is not supported", redisCfg.Type)
+}
+
+// getRedisType 配置的 redis 类型，默认为单节点
+func getRedisType(redisCfg *startupcfg.RedisConfig) string {
+	redisType := strings.ToLower(strings.TrimSpace(redisCfg.Type))
+	if redisType == "" {
+		return redisTypeNode
+	}
+	return redisType
+}
+
+// splitRedisAddrs 解析地址，哨兵模式下返回 master 名称，多个地址去重并保持顺序
+func splitRedisAddrs(redisType string, address string) (string, []string) {
+	masterName := ""
+	if redisType == redisTypeSentinel {
+		if i := strings.Index(address, redisMasterSep); i >= 0 {
+			masterName = strings.TrimSpace(address[:i])
+			address = address[i+len(redisMasterSep):]
+		}
+	}
+	addrs := make([]string, 0)
+	unique := make(map[string]struct{})
+	for _, one := range strings.Split(address, redisAddrSep) {
+		one = strings.TrimSpace(one)
+		if one == "" {
+			continue
+		}
+		if _, ok := unique[one]; ok {
+			continue
+		}
+		unique[one] = struct{}{}
+		addrs = append(addrs, one)
+	}
+	return masterName, addrs
+}
+
+func getRedisOption(redisCfg *startupcfg.RedisConfig, opt *RedisOptions) (*redis.UniversalOptions, error) {
+	dialOpt := &redis.UniversalOptions{}
 	if dataInt, err1 := conv.Convert[int](redisCfg.DatabaseName()); err1 == nil {
-		dialOpt.DB = dataInt
+		dialOpt.DB = dataInt //集群模式下忽略
 	}
 	dialOpt.Username = redisCfg.User()
 	dialOpt.Password = redisCfg.Password()
+	dialOpt.MasterName, dialOpt.Addrs = splitRedisAddrs(getRedisType(redisCfg), redisCfg.ServerAddress())
 
-	if oneTls, ok := redisCfg.Extend("tls"); ok {
-		tlsBool, err1 := conv.Convert[bool](oneTls)
-		if err1 == nil && tlsBool {
-			tlsConfig := &tls.Config{
-				InsecureSkipVerify: true,
-			}
-			if tlsConfig.ServerName == "" {
-				tlsConfig.ServerName = redisCfg.ServerAddress()
-			}
-			dialOpt.TLSConfig = tlsConfig
-		}
+	firstAddr := ""
+	if len(dialOpt.Addrs) > 0 {
+		firstAddr = dialOpt.Addrs[0]
 	}
+	tlsConfig, err := opt.TLS.tlsConfig(firstAddr)
+	if err != nil {
+		return nil, err
+	}
+	dialOpt.TLSConfig = tlsConfig
 
-	dialOpt.Addr = redisCfg.ServerAddress()
-	dialOpt.Network = redisCfg.ProtocolName()
+	dialOpt.DialTimeout = opt.DialTimeout
+	dialOpt.ReadTimeout = opt.ReadTimeout
+	dialOpt.WriteTimeout = opt.WriteTimeout
+	dialOpt.MaxRetries = opt.MaxRetries
 
 	{ // 连接池的配置
-		dialOpt.PoolFIFO = true                 //Redis 连接池是否使用 FIFO 先进先出的连接池类型，默认为 true
-		dialOpt.PoolSize = poolSize             //连接池中最多能同时存放的 Redis 连接数，即最大连接数
-		dialOpt.MinIdleConns = poolMinIdleConns //连接池中最小的空闲连接数，可以通过此属性提供更快的连接分配，默认为0
-		dialOpt.MaxConnAge = poolMaxConnAge     //Redis 连接的最大寿命，在连接池中的连接达到最大寿命时，客户端会将连接归还到连接池中，
-		// 从而避免连接长时间占用资源。默认为不限制连接寿命
-		dialOpt.PoolTimeout = poolPoolTimeout //当连接池中所有连接均被占用时，客户端调用连接池中连接的 Get() 方法会等待的最长时间。
-		// 默认值为 ReadTimeout 加上1秒
-		dialOpt.IdleTimeout = poolIdleTimeout //Redis 连接在空闲状态下的最长存活时间，超过该时间的连接将被关闭。如果指定的值小于服务器上
-		// 的超时时间，则客户端在检查连接空闲时会关闭连接，以防止服务器出现连接超时。默认为5分钟。将其设为-1可以禁用连接空闲超时检查
-		dialOpt.IdleCheckFrequency = poolIdleCheckFrequency //空闲连接检查频率。默认为1分钟。将其设为-1可以禁用连接空闲超时检查器，但是仍然会
-		// 根据 IdleTimeout 的值关闭空闲连接。
-	}
-	return dialOpt
+		dialOpt.PoolFIFO = true                       //Redis 连接池是否使用 FIFO 先进先出的连接池类型，默认为 true
+		dialOpt.PoolSize = opt.PoolSize               //连接池中最多能同时存放的 Redis 连接数，即最大连接数
+		dialOpt.MinIdleConns = opt.MinIdleConns       //连接池中最小的空闲连接数
+		dialOpt.ConnMaxLifetime = opt.ConnMaxLifetime //Redis 连接的最大寿命
+		dialOpt.PoolTimeout = opt.PoolTimeout         //当连接池中所有连接均被占用时，获取连接等待的最长时间
+		dialOpt.ConnMaxIdleTime = opt.ConnMaxIdleTime //Redis 连接在空闲状态下的最长存活时间
+	}
+	return dialOpt, nil
 }
 
 func getPoolSize() int {
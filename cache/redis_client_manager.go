@@ -1,21 +1,56 @@
 package cache
 
 import (
+	"fmt"
 	"github.com/magic-lib/go-plat-startupcfg/startupcfg"
 	cmap "github.com/orcaman/concurrent-map/v2"
+	"github.com/redis/go-redis/v9"
 	"sync"
 	"time"
 )
 
 var (
-	redisMap       = cmap.New[*redisClient]()
+	redisMap       = cmap.New[*managedRedis]()
 	once           sync.Once
 	defaultManager *redisClientManager
 )
 
+// redisClientManager 全局 redis 连接管理，redisCache、JetCache、布隆过滤器、分布式锁等相同配置共用同一个连接池，
+// 后台定时为还没有连上的配置重新连接
 type redisClientManager struct {
 }
 
+// managedRedis 全局连接管理中的一个连接，客户端建立后不再替换也不会关闭，
+// 断开的连接由 go-redis 连接池自动重连，调用方持有的客户端一直可用
+type managedRedis struct {
+	redisCfg *startupcfg.RedisConfig
+	opt      *RedisOptions
+	mu       sync.Mutex // 保证同一个配置只建立一个客户端
+	cli      redis.UniversalClient
+}
+
+// client 已建立的客户端，还没有连上时返回 nil
+func (m *managedRedis) client() redis.UniversalClient {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cli
+}
+
+// connect 还没有客户端时建立连接，并发调用时只有一个会去连接
+func (m *managedRedis) connect() (redis.UniversalClient, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.cli != nil {
+		return m.cli, nil
+	}
+	newClient, err := getRedisFromCfg(m.redisCfg, m.opt)
+	if err != nil {
+		return nil, err
+	}
+	m.cli = newClient
+	return m.cli, nil
+}
+
 func NewRedisClientManager(interval time.Duration) *redisClientManager {
 	once.Do(func() {
 		go monitorRedisConnections(interval)
@@ -27,85 +62,75 @@ func NewRedisClientManager(interval time.Duration) *redisClientManager {
 	return defaultManager
 }
 
-// add 向全局客户端列表添加 Redis 客户端
-func (r *redisClientManager) add(redisCfg *startupcfg.RedisConfig) bool {
+// GetRedisClient 从全局连接管理中取得 redis 客户端，相同配置和连接参数共用同一个连接池，不要自行 Close
+func GetRedisClient(redisCfg *startupcfg.RedisConfig, opt ...*RedisOptions) (redis.UniversalClient, error) {
 	if redisCfg == nil {
-		return false
+		return nil, fmt.Errorf("redis config is nil")
 	}
+	return NewRedisClient(redisCfg, opt...).getOneRedis()
+}
+
+// RedisPoolStats 返回全局连接管理中各连接池的统计，key 为连接类型和服务器地址
+func RedisPoolStats() map[string]*redis.PoolStats {
+	stats := make(map[string]*redis.PoolStats)
+	for _, m := range redisMap.Items() {
+		cli := m.client()
+		if cli == nil {
+			continue
+		}
+		stats[getRedisType(m.redisCfg)+"://"+m.redisCfg.ServerAddress()] = cli.PoolStats()
+	}
+	return stats
+}
+
+// redisCfgKey 连接的唯一标识，连接类型或连接参数不同的同一地址分开管理
+func redisCfgKey(redisCfg *startupcfg.RedisConfig, opt *RedisOptions) string {
 	redisConnStr := redisCfg.DatasourceName()
 	if redisConnStr == "" {
-		return false
+		return ""
 	}
-	if redisMap.Has(redisConnStr) {
-		return false
-	}
-	newRedisClient := &redisClient{
-		redisCfg: redisCfg,
-		cli:      nil,
-	}
-	newClient, err := getRedisFromCfg(redisCfg)
-	if err == nil {
-		newRedisClient.cli = newClient
-	}
-	redisMap.Set(redisConnStr, newRedisClient)
-	return true
+	return getRedisType(redisCfg) + "|" + redisConnStr + "|" + opt.connKey()
 }
 
-// Get 向全局客户端列表添加 Redis 客户端
-func (r *redisClientManager) Get(redisCfg *startupcfg.RedisConfig) *redisClient {
+// Get 从全局客户端列表取得 Redis 客户端，没有则新建，连接失败时返回 nil
+func (r *redisClientManager) Get(redisCfg *startupcfg.RedisConfig, opt *RedisOptions) redis.UniversalClient {
 	if redisCfg == nil {
 		return nil
 	}
-	redisConnStr := redisCfg.DatasourceName()
+	if opt == nil {
+		opt = getRedisOptions(redisCfg)
+	}
+	redisConnStr := redisCfgKey(redisCfg, opt)
 	if redisConnStr == "" {
 		return nil
 	}
-	if newRedisClient, ok := redisMap.Get(redisConnStr); ok {
-		if newRedisClient.cli == nil {
-			newClient, err := getRedisFromCfg(redisCfg)
-			if err != nil {
-				return nil
-			}
-			newRedisClient = &redisClient{
-				redisCfg: redisCfg,
-				cli:      newClient,
-			}
-			redisMap.Set(redisConnStr, newRedisClient)
-		}
-		return newRedisClient
+	// 先占位再连接，并发获取同一个配置时不会建立多个客户端
+	redisMap.SetIfAbsent(redisConnStr, &managedRedis{
+		redisCfg: redisCfg,
+		opt:      opt,
+	})
+	m, ok := redisMap.Get(redisConnStr)
+	if !ok {
+		return nil
 	}
-
-	if !r.add(redisCfg) {
+	cli, err := m.connect()
+	if err != nil {
 		return nil
 	}
-	return r.Get(redisCfg)
+	return cli
 }
 
-// monitorRedisConnections 定时监测所有 Redis 连接状态
+// monitorRedisConnections 定时为还没有连上的配置重新连接，已建立的客户端由连接池自动重连
 func monitorRedisConnections(interval time.Duration) {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
-	for {
-		select {
-		case <-ticker.C:
-			for _, rc := range redisMap.Items() {
-				if rc.cli != nil {
-					err := checkConnection(rc.cli, rc.redisCfg.PingTimeout)
-					if err == nil {
-						continue
-					}
-				}
-				newClient, err := getRedisFromCfg(rc.redisCfg)
-				if err != nil {
-					// TODO 连接失败，20s后尝试重新连接
-					continue
-				}
-				if rc.cli != nil {
-					_ = rc.cli.Close() //老的连接需要释放掉
-				}
-				rc.cli = newClient
+	for range ticker.C {
+		for _, m := range redisMap.Items() {
+			if m.client() != nil {
+				continue
 			}
+			_, _ = m.connect()
 		}
 	}
 }
@@ -0,0 +1,35 @@
+package cache
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRedisMetaKey(t *testing.T) {
+	// 附属 hash 必须与 key 在同一个 slot，无法构造时不使用附属 hash
+	cases := map[string]string{
+		"k":      "{k}" + redisMetaSuffix,
+		"{t}k":   "{t}k" + redisMetaSuffix,
+		"x{y":    "{x{y}" + redisMetaSuffix,
+		"a}b":    "",
+		"x{}y":   "",
+		"x{}y{t": "",
+	}
+	for key, want := range cases {
+		if got := redisMetaKey(key); got != want {
+			t.Errorf("redisMetaKey(%q) = %q, want %q", key, got, want)
+		}
+	}
+
+	// 未开启 WriteMeta 时不写附属 hash，依赖它的方法直接返回错误
+	rc := NewRedisClient(nil)
+	if keys := rc.withMetaKey("k"); len(keys) != 1 {
+		t.Errorf("meta key should not be used by default, got %v", keys)
+	}
+	if _, err := rc.Touch(context.Background(), "k"); err == nil {
+		t.Error("Touch should fail without WriteMeta")
+	}
+	if _, _, err := rc.GetWithVersion(context.Background(), "k"); err == nil {
+		t.Error("GetWithVersion should fail without WriteMeta")
+	}
+}
@@ -0,0 +1,209 @@
+package cache
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/magic-lib/go-plat-startupcfg/startupcfg"
+	"github.com/magic-lib/go-plat-utils/conv"
+	"net"
+	"os"
+	"time"
+)
+
+// RedisOptions redis 连接参数，按连接生效，零值字段使用 RedisConfig.Extend 中的配置或默认值
+type RedisOptions struct {
+	PoolSize        int              // 连接池最大连接数，默认按 CPU 数取 10~100
+	MinIdleConns    int              // 连接池最小空闲连接数，默认 30
+	ConnMaxIdleTime time.Duration    // 空闲连接的最长存活时间，默认 5 分钟
+	ConnMaxLifetime time.Duration    // 连接的最长寿命，默认 3 小时
+	PoolTimeout     time.Duration    // 连接池满时获取连接的最长等待时间，默认 ReadTimeout+1s
+	DialTimeout     time.Duration    // 建立连接超时，默认 5s
+	ReadTimeout     time.Duration    // 读超时，默认 3s
+	WriteTimeout    time.Duration    // 写超时，默认同 ReadTimeout
+	MaxRetries      int              // 失败重试次数，-1 不重试，默认 3
+	MaxTimeout      time.Duration    // 最长存储时间，timeout<=0 或超过时使用，避免无限期占用 redis 空间，默认 90 天，不能小于 1 天
+	TLS             *RedisTLSOptions // 非空时开启 TLS，RedisConfig.TLS 为 true 时使用默认参数开启
+
+	AutoPipeline *RedisPipelineOptions // 非空时 Get/Set/Del 自动合并 pipeline，只作用于客户端，不影响连接池
+	WriteMeta    bool                  // 写入时在附属 hash 中记录时长和版本号，Touch、GetWithVersion、CompareAndSet 需要开启，每个 key 多占用一个 key
+}
+
+// RedisTLSOptions TLS 参数，默认用系统根证书校验服务端证书
+type RedisTLSOptions struct {
+	CAFile             string // CA 证书文件，为空使用系统根证书
+	CertFile           string // 客户端证书文件，双向认证时使用
+	KeyFile            string // 客户端私钥文件
+	ServerName         string // 校验的服务端名称，为空使用地址中的主机名
+	InsecureSkipVerify bool   // 跳过证书校验，仅用于测试环境
+}
+
+const (
+	defaultRedisMaxRetries = 3
+
+	// RedisConfig.Extend 中读取的连接参数
+	extendRedisPoolSize        startupcfg.ExtendField = "pool_size"
+	extendRedisMinIdleConns    startupcfg.ExtendField = "min_idle_conns"
+	extendRedisConnMaxIdleTime startupcfg.ExtendField = "conn_max_idle_time"
+	extendRedisConnMaxLifetime startupcfg.ExtendField = "conn_max_lifetime"
+	extendRedisPoolTimeout     startupcfg.ExtendField = "pool_timeout"
+	extendRedisDialTimeout     startupcfg.ExtendField = "dial_timeout"
+	extendRedisReadTimeout     startupcfg.ExtendField = "read_timeout"
+	extendRedisWriteTimeout    startupcfg.ExtendField = "write_timeout"
+	extendRedisMaxRetries      startupcfg.ExtendField = "max_retries"
+	extendRedisMaxTimeout      startupcfg.ExtendField = "max_timeout"
+	extendRedisTLS             startupcfg.ExtendField = "tls"
+	extendRedisTLSCAFile       startupcfg.ExtendField = "tls_ca_file"
+	extendRedisTLSServerName   startupcfg.ExtendField = "tls_server_name"
+	extendRedisTLSInsecure     startupcfg.ExtendField = "tls_insecure_skip_verify"
+)
+
+// getRedisOptions 合并传入的参数、RedisConfig.Extend 中的配置和默认值，不修改传入的参数
+func getRedisOptions(redisCfg *startupcfg.RedisConfig, opts ...*RedisOptions) *RedisOptions {
+	opt := &RedisOptions{}
+	for _, one := range opts {
+		if one != nil {
+			*opt = *one
+			break
+		}
+	}
+	if redisCfg != nil {
+		extendInt(redisCfg, extendRedisPoolSize, &opt.PoolSize)
+		extendInt(redisCfg, extendRedisMinIdleConns, &opt.MinIdleConns)
+		extendInt(redisCfg, extendRedisMaxRetries, &opt.MaxRetries)
+		extendDuration(redisCfg, extendRedisConnMaxIdleTime, &opt.ConnMaxIdleTime)
+		extendDuration(redisCfg, extendRedisConnMaxLifetime, &opt.ConnMaxLifetime)
+		extendDuration(redisCfg, extendRedisPoolTimeout, &opt.PoolTimeout)
+		extendDuration(redisCfg, extendRedisDialTimeout, &opt.DialTimeout)
+		extendDuration(redisCfg, extendRedisReadTimeout, &opt.ReadTimeout)
+		extendDuration(redisCfg, extendRedisWriteTimeout, &opt.WriteTimeout)
+		extendDuration(redisCfg, extendRedisMaxTimeout, &opt.MaxTimeout)
+		opt.TLS = getRedisTLSOptions(redisCfg, opt.TLS)
+	}
+
+	if opt.PoolSize <= 0 {
+		opt.PoolSize = getPoolSize()
+	}
+	if opt.MinIdleConns <= 0 {
+		opt.MinIdleConns = poolMinIdleConns
+	}
+	if opt.ConnMaxIdleTime == 0 {
+		opt.ConnMaxIdleTime = poolIdleTimeout
+	}
+	if opt.ConnMaxLifetime == 0 {
+		opt.ConnMaxLifetime = poolMaxConnAge
+	}
+	if opt.PoolTimeout == 0 {
+		opt.PoolTimeout = poolPoolTimeout
+	}
+	if opt.MaxRetries == 0 {
+		opt.MaxRetries = defaultRedisMaxRetries
+	}
+	if opt.MaxTimeout < minMaxTimeout { //必须大于一天，设置过短的时间点会出现问题
+		opt.MaxTimeout = defaultRedisMaxTimeout
+	}
+	return opt
+}
+
+// getRedisTLSOptions 传入的 TLS 参数优先，否则按 RedisConfig 中的 tls 配置开启
+func getRedisTLSOptions(redisCfg *startupcfg.RedisConfig, tlsOpt *RedisTLSOptions) *RedisTLSOptions {
+	if tlsOpt != nil {
+		return tlsOpt
+	}
+	enabled := false
+	if oneTls, ok := redisCfg.Extend(extendRedisTLS); ok {
+		enabled, _ = conv.Convert[bool](oneTls)
+	}
+	if !enabled {
+		return nil
+	}
+	tlsOpt = &RedisTLSOptions{}
+	if v, ok := redisCfg.Extend(extendRedisTLSCAFile); ok {
+		tlsOpt.CAFile = conv.String(v)
+	}
+	if v, ok := redisCfg.Extend(extendRedisTLSServerName); ok {
+		tlsOpt.ServerName = conv.String(v)
+	}
+	if v, ok := redisCfg.Extend(extendRedisTLSInsecure); ok {
+		tlsOpt.InsecureSkipVerify, _ = conv.Convert[bool](v)
+	}
+	return tlsOpt
+}
+
+// tlsConfig 生成 tls.Config，addr 用于默认的 ServerName
+func (o *RedisTLSOptions) tlsConfig(addr string) (*tls.Config, error) {
+	if o == nil {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         o.ServerName,
+		InsecureSkipVerify: o.InsecureSkipVerify,
+	}
+	if tlsConfig.ServerName == "" && addr != "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		tlsConfig.ServerName = host
+	}
+	if o.CAFile != "" {
+		caPem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("redis read ca file error: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPem) {
+			return nil, fmt.Errorf("redis ca file %s has no valid certificate", o.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if o.CertFile != "" || o.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("redis load client certificate error: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// connKey 连接参数的标识，参数不同的同一配置使用不同的连接池
+func (o *RedisOptions) connKey() string {
+	tlsKey := ""
+	if o.TLS != nil {
+		tlsKey = fmt.Sprintf("%+v", *o.TLS)
+	}
+	return fmt.Sprintf("%d|%d|%s|%s|%s|%s|%s|%s|%d|%s", o.PoolSize, o.MinIdleConns, o.ConnMaxIdleTime,
+		o.ConnMaxLifetime, o.PoolTimeout, o.DialTimeout, o.ReadTimeout, o.WriteTimeout, o.MaxRetries, tlsKey)
+}
+
+func extendInt(redisCfg *startupcfg.RedisConfig, name startupcfg.ExtendField, dst *int) {
+	if *dst != 0 {
+		return
+	}
+	if v, ok := redisCfg.Extend(name); ok {
+		if n, err := conv.Convert[int](v); err == nil {
+			*dst = n
+		}
+	}
+}
+
+// extendDuration 支持 time.Duration 和 "5s" 形式的字符串
+func extendDuration(redisCfg *startupcfg.RedisConfig, name startupcfg.ExtendField, dst *time.Duration) {
+	if *dst != 0 {
+		return
+	}
+	v, ok := redisCfg.Extend(name)
+	if !ok {
+		return
+	}
+	switch d := v.(type) {
+	case time.Duration:
+		*dst = d
+	case string:
+		if one, err := time.ParseDuration(d); err == nil {
+			*dst = one
+		}
+	}
+}
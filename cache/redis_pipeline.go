@@ -0,0 +1,170 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultPipelineWindow   = 500 * time.Microsecond
+	defaultPipelineMaxBatch = 100
+)
+
+// RedisPipelineOptions 自动合并 pipeline 的参数，零值字段使用默认值
+type RedisPipelineOptions struct {
+	Window   time.Duration // 第一个命令到达后最多等待多久再发送，默认 500µs
+	MaxBatch int           // 攒够多少个命令立即发送，默认 100
+}
+
+// RedisPipelineStats 自动合并 pipeline 的统计
+type RedisPipelineStats struct {
+	Batches      int64         // 发送的 pipeline 数
+	Commands     int64         // 合并的命令数
+	MaxBatchSize int64         // 单个 pipeline 最多的命令数
+	AvgBatchSize float64       // 平均每个 pipeline 的命令数
+	AvgLatency   time.Duration // pipeline 平均执行耗时
+}
+
+// pipelineCall 等待合并执行的一个命令
+type pipelineCall struct {
+	build func(ctx context.Context, pipe redis.Pipeliner) redis.Cmder
+	cmd   redis.Cmder
+	err   error
+	done  chan struct{}
+}
+
+// batchExecFunc 执行一个 pipeline，与 redisClient.BatchExec 一致
+type batchExecFunc func(ctx context.Context, f func(ctx context.Context, pipe redis.Pipeliner) []redis.Cmder) ([]redis.Cmder, error)
+
+// autoPipeline 把并发的单 key 命令在 Window 内或攒够 MaxBatch 个后合并成一个 pipeline，通过 BatchExec 执行
+type autoPipeline struct {
+	batchExec batchExecFunc
+	window    time.Duration
+	maxBatch  int
+
+	mu      sync.Mutex
+	pending []*pipelineCall
+	timer   *time.Timer
+
+	batches      atomic.Int64
+	commands     atomic.Int64
+	maxBatchSize atomic.Int64
+	latency      atomic.Int64 // 累计执行耗时(nano)
+}
+
+func newAutoPipeline(batchExec batchExecFunc, opt *RedisPipelineOptions) *autoPipeline {
+	ap := &autoPipeline{
+		batchExec: batchExec,
+		window:    opt.Window,
+		maxBatch:  opt.MaxBatch,
+	}
+	if ap.window <= 0 {
+		ap.window = defaultPipelineWindow
+	}
+	if ap.maxBatch <= 0 {
+		ap.maxBatch = defaultPipelineMaxBatch
+	}
+	return ap
+}
+
+// do 加入待发送队列并等待结果，ctx 结束时不再等待，命令仍会随 pipeline 发送
+func (ap *autoPipeline) do(ctx context.Context, build func(ctx context.Context, pipe redis.Pipeliner) redis.Cmder) (redis.Cmder, error) {
+	call := &pipelineCall{
+		build: build,
+		done:  make(chan struct{}),
+	}
+	ap.mu.Lock()
+	ap.pending = append(ap.pending, call)
+	var batch []*pipelineCall
+	if len(ap.pending) >= ap.maxBatch {
+		batch = ap.take()
+	} else if len(ap.pending) == 1 {
+		ap.timer = time.AfterFunc(ap.window, ap.flush)
+	}
+	ap.mu.Unlock()
+
+	if batch != nil {
+		ap.exec(context.WithoutCancel(ctx), batch)
+	}
+	select {
+	case <-call.done:
+		return call.cmd, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// take 取出所有待发送的命令，调用方需持有锁
+func (ap *autoPipeline) take() []*pipelineCall {
+	if ap.timer != nil {
+		ap.timer.Stop()
+		ap.timer = nil
+	}
+	batch := ap.pending
+	ap.pending = nil
+	return batch
+}
+
+// flush 等待时间到后发送
+func (ap *autoPipeline) flush() {
+	ap.mu.Lock()
+	batch := ap.take()
+	ap.mu.Unlock()
+	if len(batch) > 0 {
+		ap.exec(context.Background(), batch)
+	}
+}
+
+// exec 发送一个 pipeline，每个命令的结果单独返回给调用方
+func (ap *autoPipeline) exec(ctx context.Context, batch []*pipelineCall) {
+	start := time.Now()
+	_, err := ap.batchExec(ctx, func(ctx context.Context, pipe redis.Pipeliner) []redis.Cmder {
+		cmdList := make([]redis.Cmder, 0, len(batch))
+		for _, call := range batch {
+			call.cmd = call.build(ctx, pipe)
+			cmdList = append(cmdList, call.cmd)
+		}
+		return cmdList
+	})
+	for _, call := range batch {
+		if call.cmd == nil {
+			// 没有取得连接，命令未发送
+			call.err = err
+			if call.err == nil {
+				call.err = fmt.Errorf("redis auto pipeline: command not executed")
+			}
+		} else {
+			call.err = call.cmd.Err()
+		}
+		close(call.done)
+	}
+
+	size := int64(len(batch))
+	ap.batches.Add(1)
+	ap.commands.Add(size)
+	ap.latency.Add(int64(time.Since(start)))
+	for {
+		old := ap.maxBatchSize.Load()
+		if size <= old || ap.maxBatchSize.CompareAndSwap(old, size) {
+			break
+		}
+	}
+}
+
+// stats 统计
+func (ap *autoPipeline) stats() *RedisPipelineStats {
+	st := &RedisPipelineStats{
+		Batches:      ap.batches.Load(),
+		Commands:     ap.commands.Load(),
+		MaxBatchSize: ap.maxBatchSize.Load(),
+	}
+	if st.Batches > 0 {
+		st.AvgBatchSize = float64(st.Commands) / float64(st.Batches)
+		st.AvgLatency = time.Duration(ap.latency.Load() / st.Batches)
+	}
+	return st
+}
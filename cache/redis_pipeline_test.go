@@ -0,0 +1,192 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"github.com/redis/go-redis/v9"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeBatchExec 不连接 redis，按 key 设置 GET 的结果：missing 返回 redis.Nil，bad 返回错误，其他返回 key 本身。
+// 与 BatchExec 一样，有命令出错时返回错误
+type fakeBatchExec struct {
+	client  *redis.Client
+	block   chan struct{} // 不为空时执行前等待
+	batches atomic.Int32
+	sizes   []int
+	mu      sync.Mutex
+}
+
+func newFakeBatchExec() *fakeBatchExec {
+	return &fakeBatchExec{client: redis.NewClient(&redis.Options{Addr: "127.0.0.1:0"})}
+}
+
+func (fe *fakeBatchExec) exec(ctx context.Context, f func(ctx context.Context, pipe redis.Pipeliner) []redis.Cmder) ([]redis.Cmder, error) {
+	if fe.block != nil {
+		<-fe.block
+	}
+	cmdList := f(ctx, fe.client.Pipeline())
+	fe.batches.Add(1)
+	fe.mu.Lock()
+	fe.sizes = append(fe.sizes, len(cmdList))
+	fe.mu.Unlock()
+
+	var firstErr error
+	for _, cmd := range cmdList {
+		getCmd := cmd.(*redis.StringCmd)
+		switch key := getCmd.Args()[1].(string); key {
+		case "missing":
+			getCmd.SetErr(redis.Nil)
+		case "bad":
+			getCmd.SetErr(errors.New("WRONGTYPE"))
+		default:
+			getCmd.SetVal(key)
+		}
+		if firstErr == nil {
+			firstErr = getCmd.Err()
+		}
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return cmdList, nil
+}
+
+func pipelineGet(ctx context.Context, ap *autoPipeline, key string) (string, error) {
+	cmd, err := ap.do(ctx, func(ctx context.Context, pipe redis.Pipeliner) redis.Cmder {
+		return pipe.Get(ctx, key)
+	})
+	if err != nil {
+		return "", err
+	}
+	return cmd.(*redis.StringCmd).Val(), nil
+}
+
+func TestAutoPipelineCoalesce(t *testing.T) {
+	fe := newFakeBatchExec()
+	ap := newAutoPipeline(fe.exec, &RedisPipelineOptions{Window: 20 * time.Millisecond, MaxBatch: 100})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := pipelineGet(context.Background(), ap, "k"); err != nil || v != "k" {
+				t.Errorf("unexpected %q, %v", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := fe.batches.Load(); n != 1 {
+		t.Errorf("expected 1 batch, got %d", n)
+	}
+
+	st := ap.stats()
+	if st.Batches != 1 || st.Commands != 10 || st.MaxBatchSize != 10 || st.AvgBatchSize != 10 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+}
+
+func TestAutoPipelineMaxBatch(t *testing.T) {
+	fe := newFakeBatchExec()
+	ap := newAutoPipeline(fe.exec, &RedisPipelineOptions{Window: time.Hour, MaxBatch: 3})
+
+	done := make(chan struct{})
+	go func() {
+		var wg sync.WaitGroup
+		for i := 0; i < 6; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _ = pipelineGet(context.Background(), ap, "k")
+			}()
+		}
+		wg.Wait()
+		close(done)
+	}()
+
+	// 窗口为一小时，只有攒够 MaxBatch 时才会发送
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("batch should be flushed when MaxBatch is reached")
+	}
+	if n := fe.batches.Load(); n != 2 {
+		t.Errorf("expected 2 batches, got %d", n)
+	}
+	if st := ap.stats(); st.Commands != 6 || st.MaxBatchSize != 3 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+}
+
+func TestAutoPipelineErrors(t *testing.T) {
+	fe := newFakeBatchExec()
+	ap := newAutoPipeline(fe.exec, &RedisPipelineOptions{Window: 20 * time.Millisecond, MaxBatch: 3})
+
+	// 同一个 pipeline 中每个命令的错误单独返回，redis.Nil 不影响其他命令
+	results := make(map[string]error)
+	values := make(map[string]string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, key := range []string{"ok", "missing", "bad"} {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			v, err := pipelineGet(context.Background(), ap, key)
+			mu.Lock()
+			results[key], values[key] = err, v
+			mu.Unlock()
+		}(key)
+	}
+	wg.Wait()
+	if fe.batches.Load() != 1 {
+		t.Fatalf("expected a mixed batch, got %d batches", fe.batches.Load())
+	}
+	if results["ok"] != nil || values["ok"] != "ok" {
+		t.Errorf("ok got %q, %v", values["ok"], results["ok"])
+	}
+	if !errors.Is(results["missing"], redis.Nil) {
+		t.Errorf("missing should get redis.Nil, got %v", results["missing"])
+	}
+	if results["bad"] == nil || errors.Is(results["bad"], redis.Nil) {
+		t.Errorf("bad should get its own error, got %v", results["bad"])
+	}
+
+	// 没有取得连接时所有命令都返回连接错误
+	connErr := errors.New("conn cant connect")
+	ap = newAutoPipeline(func(ctx context.Context, f func(ctx context.Context, pipe redis.Pipeliner) []redis.Cmder) ([]redis.Cmder, error) {
+		return nil, connErr
+	}, &RedisPipelineOptions{Window: time.Millisecond})
+	if _, err := pipelineGet(context.Background(), ap, "ok"); !errors.Is(err, connErr) {
+		t.Errorf("expected connection error, got %v", err)
+	}
+}
+
+func TestAutoPipelineCanceled(t *testing.T) {
+	fe := newFakeBatchExec()
+	fe.block = make(chan struct{})
+	ap := newAutoPipeline(fe.exec, &RedisPipelineOptions{Window: time.Millisecond})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := pipelineGet(ctx, ap, "k"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	if cost := time.Since(start); cost > time.Second {
+		t.Errorf("canceled call should return at once, cost %v", cost)
+	}
+
+	// 取消的命令仍随 pipeline 发送
+	close(fe.block)
+	deadline := time.Now().Add(time.Second)
+	for ap.stats().Batches == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if st := ap.stats(); st.Batches != 1 || st.Commands != 1 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+}
@@ -0,0 +1,70 @@
+package cache_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/magic-lib/go-plat-cache/cache"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestScannableCache(t *testing.T) {
+	ctx := context.Background()
+	boltCache, err := cache.NewBBoltCache[string](&cache.BBoltCacheConfig{
+		DbPath: filepath.Join(t.TempDir(), "scan.db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cacheList := map[string]cache.CommCache[string]{
+		"memGo":  cache.NewMemGoCache[string](time.Minute, time.Minute),
+		"memLru": cache.NewMemLruCache[string](100, time.Minute),
+		"bolt":   boltCache,
+	}
+	for name, one := range cacheList {
+		for i := 0; i < 5; i++ {
+			_, _ = cache.NsSet[string](ctx, one, "user:42", fmt.Sprintf("k%d", i), "v", 0)
+		}
+		_, _ = one.Set(ctx, "other", "v", 0)
+		_, _ = cache.NsSet[string](ctx, one, "user:42", "expired", "v", 10*time.Millisecond)
+		time.Sleep(20 * time.Millisecond)
+
+		sc := one.(cache.ScannableCache)
+		all := make([]string, 0)
+		cursor := ""
+		for {
+			keys, next, err := sc.Scan(ctx, "{user:42}", cursor, 2)
+			if err != nil {
+				t.Fatalf("%s: scan error: %v", name, err)
+			}
+			all = append(all, keys...)
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+		if len(all) != 5 {
+			t.Errorf("%s: expected 5 keys, got %v", name, all)
+		}
+
+		if rc, ok := one.(cache.RangeableCache[string]); ok {
+			ranged := 0
+			_ = rc.Range(ctx, "{user:42}", func(key string, val string, ttl time.Duration) bool {
+				ranged++
+				return true
+			})
+			if ranged != 5 {
+				t.Errorf("%s: expected range 5 entries, got %d", name, ranged)
+			}
+		}
+
+		n, err := cache.NsDeleteAll(ctx, sc, "user:42")
+		if err != nil || n != 5 {
+			t.Errorf("%s: expected delete 5 keys, got %d, %v", name, n, err)
+		}
+		if v, _ := one.Get(ctx, "other"); v != "v" {
+			t.Errorf("%s: other key should not be deleted", name)
+		}
+	}
+}
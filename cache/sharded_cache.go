@@ -0,0 +1,318 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hashicorp/go-multierror"
+	"github.com/magic-lib/go-plat-startupcfg/startupcfg"
+	"github.com/redis/go-redis/v9"
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const defaultShardReplicas = 160 // 每个节点的虚拟节点数
+
+// batchNode 支持批量读写的节点，一次往返处理分到该节点的所有 key
+type batchNode[V any] interface {
+	mGet(ctx context.Context, keys []string) (map[string]V, error)
+	mSet(ctx context.Context, values map[string]V, keys []string, timeout time.Duration) error
+	mDel(ctx context.Context, keys []string) (int64, error)
+}
+
+// shardedCache 客户端一致性哈希分片，节点之间相互独立，不需要 redis 集群
+type shardedCache[V any] struct {
+	replicas int
+
+	mu     sync.RWMutex
+	ring   []uint32          // 排好序的虚拟节点哈希值
+	owners map[uint32]string // 虚拟节点哈希值对应的节点名称
+	nodes  map[string]CommCache[V]
+}
+
+// NewShardedCache 新建分片缓存，nodes 的 key 为节点名称，名称决定哈希环上的位置，重启后需保持不变。
+// replicas 为每个节点的虚拟节点数，<=0 时使用默认值 160
+func NewShardedCache[V any](nodes map[string]CommCache[V], replicas int) ShardedCache[V] {
+	if replicas <= 0 {
+		replicas = defaultShardReplicas
+	}
+	sc := &shardedCache[V]{
+		replicas: replicas,
+		owners:   make(map[uint32]string),
+		nodes:    make(map[string]CommCache[V]),
+	}
+	for name, node := range nodes {
+		sc.AddNode(name, node)
+	}
+	return sc
+}
+
+// NewRedisShardedCache 把多个独立的 redis 作为分片，节点名称为服务器地址
+func NewRedisShardedCache[V any](opt *RedisOptions, redisCfgs ...*startupcfg.RedisConfig) (ShardedCache[V], error) {
+	nodes := make(map[string]CommCache[V])
+	for _, redisCfg := range redisCfgs {
+		if redisCfg == nil {
+			continue
+		}
+		name := redisCfg.ServerAddress()
+		if _, ok := nodes[name]; ok {
+			return nil, fmt.Errorf("redis shard %s is duplicated", name)
+		}
+		nodes[name] = &redisCache[V]{
+			redisCfg: redisCfg,
+			rc:       NewRedisClient(redisCfg, opt),
+		}
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("redis shard config is empty")
+	}
+	return NewShardedCache[V](nodes, 0), nil
+}
+
+// shardHash key 带 hash tag 时只对 tag 计算哈希，与 getNsKey 生成的 {ns}key 一致
+func shardHash(key string) uint32 {
+	if tag := redisHashTag(key); tag != "" {
+		key = tag
+	}
+	return crc32.ChecksumIEEE([]byte(key))
+}
+
+// AddNode 添加节点
+func (sc *shardedCache[V]) AddNode(name string, node CommCache[V]) {
+	if node == nil {
+		return
+	}
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if _, ok := sc.nodes[name]; ok {
+		sc.nodes[name] = node
+		return
+	}
+	sc.nodes[name] = node
+	sc.rebuildRing()
+}
+
+// RemoveNode 删除节点，重建哈希环，之前因哈希冲突让给该节点的虚拟节点归还给其他节点
+func (sc *shardedCache[V]) RemoveNode(name string) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if _, ok := sc.nodes[name]; !ok {
+		return
+	}
+	delete(sc.nodes, name)
+	sc.rebuildRing()
+}
+
+// rebuildRing 按节点名称重新计算哈希环，哈希冲突时保留名称较小的节点，与节点加入的顺序无关，调用方需持有锁
+func (sc *shardedCache[V]) rebuildRing() {
+	names := make([]string, 0, len(sc.nodes))
+	for name := range sc.nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sc.owners = make(map[uint32]string, len(names)*sc.replicas)
+	sc.ring = make([]uint32, 0, len(names)*sc.replicas)
+	for _, name := range names {
+		for i := 0; i < sc.replicas; i++ {
+			h := crc32.ChecksumIEEE([]byte(name + "#" + strconv.Itoa(i)))
+			if _, ok := sc.owners[h]; ok {
+				continue
+			}
+			sc.owners[h] = name
+			sc.ring = append(sc.ring, h)
+		}
+	}
+	sort.Slice(sc.ring, func(i, j int) bool { return sc.ring[i] < sc.ring[j] })
+}
+
+// NodeFor key 所在的节点名称
+func (sc *shardedCache[V]) NodeFor(key string) string {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	return sc.nodeFor(key)
+}
+
+// nodeFor 顺时针找到第一个虚拟节点，调用方需持有锁
+func (sc *shardedCache[V]) nodeFor(key string) string {
+	if len(sc.ring) == 0 {
+		return ""
+	}
+	h := shardHash(key)
+	i := sort.Search(len(sc.ring), func(i int) bool { return sc.ring[i] >= h })
+	if i == len(sc.ring) {
+		i = 0
+	}
+	return sc.owners[sc.ring[i]]
+}
+
+// getNode key 所在的节点
+func (sc *shardedCache[V]) getNode(key string) (CommCache[V], error) {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	node, ok := sc.nodes[sc.nodeFor(key)]
+	if !ok {
+		return nil, fmt.Errorf("sharded cache has no node")
+	}
+	return node, nil
+}
+
+// groupKeys 按节点分组
+func (sc *shardedCache[V]) groupKeys(keys []string) (map[string][]string, map[string]CommCache[V], error) {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	if len(sc.ring) == 0 {
+		return nil, nil, fmt.Errorf("sharded cache has no node")
+	}
+	groups := make(map[string][]string)
+	nodes := make(map[string]CommCache[V])
+	for _, key := range keys {
+		name := sc.nodeFor(key)
+		groups[name] = append(groups[name], key)
+		nodes[name] = sc.nodes[name]
+	}
+	return groups, nodes, nil
+}
+
+// fanOut 每个节点一个协程执行 fn，汇总错误
+func (sc *shardedCache[V]) fanOut(keys []string, fn func(node CommCache[V], keys []string) error) error {
+	groups, nodes, err := sc.groupKeys(keys)
+	if err != nil {
+		return err
+	}
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		retErr error
+	)
+	for name, nodeKeys := range groups {
+		wg.Add(1)
+		go func(name string, node CommCache[V], nodeKeys []string) {
+			defer wg.Done()
+			if err := fn(node, nodeKeys); err != nil {
+				mu.Lock()
+				retErr = multierror.Append(retErr, fmt.Errorf("shard %s: %w", name, err))
+				mu.Unlock()
+			}
+		}(name, nodes[name], nodeKeys)
+	}
+	wg.Wait()
+	return retErr
+}
+
+// Get 从 key 所在的节点取值
+func (sc *shardedCache[V]) Get(ctx context.Context, key string) (V, error) {
+	node, err := sc.getNode(key)
+	if err != nil {
+		var zero V
+		return zero, err
+	}
+	return node.Get(ctx, key)
+}
+
+// Set 写入 key 所在的节点
+func (sc *shardedCache[V]) Set(ctx context.Context, key string, val V, timeout time.Duration) (bool, error) {
+	node, err := sc.getNode(key)
+	if err != nil {
+		return false, err
+	}
+	return node.Set(ctx, key, val, timeout)
+}
+
+// Del 从 key 所在的节点删除
+func (sc *shardedCache[V]) Del(ctx context.Context, key string) (bool, error) {
+	node, err := sc.getNode(key)
+	if err != nil {
+		return false, err
+	}
+	return node.Del(ctx, key)
+}
+
+// MGet 批量取值，key 不存在不算错误，支持批量读写的节点(如 redis)每个节点一次往返
+func (sc *shardedCache[V]) MGet(ctx context.Context, keys ...string) (map[string]V, error) {
+	var mu sync.Mutex
+	values := make(map[string]V, len(keys))
+	err := sc.fanOut(keys, func(node CommCache[V], nodeKeys []string) error {
+		if bn, ok := node.(batchNode[V]); ok {
+			got, err := bn.mGet(ctx, nodeKeys)
+			mu.Lock()
+			for key, v := range got {
+				if !isZeroValue(v) {
+					values[key] = v
+				}
+			}
+			mu.Unlock()
+			return err
+		}
+		var retErr error
+		for _, key := range nodeKeys {
+			v, err := node.Get(ctx, key)
+			if err != nil {
+				if !errors.Is(err, redis.Nil) && !errors.Is(err, ErrNotFound) {
+					retErr = multierror.Append(retErr, err)
+				}
+				continue
+			}
+			if isZeroValue(v) {
+				continue
+			}
+			mu.Lock()
+			values[key] = v
+			mu.Unlock()
+		}
+		return retErr
+	})
+	return values, err
+}
+
+// MSet 批量写入
+func (sc *shardedCache[V]) MSet(ctx context.Context, values map[string]V, timeout time.Duration) error {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	return sc.fanOut(keys, func(node CommCache[V], nodeKeys []string) error {
+		if bn, ok := node.(batchNode[V]); ok {
+			return bn.mSet(ctx, values, nodeKeys, timeout)
+		}
+		var retErr error
+		for _, key := range nodeKeys {
+			if _, err := node.Set(ctx, key, values[key], timeout); err != nil {
+				retErr = multierror.Append(retErr, err)
+			}
+		}
+		return retErr
+	})
+}
+
+// MDel 批量删除
+func (sc *shardedCache[V]) MDel(ctx context.Context, keys ...string) (int64, error) {
+	var mu sync.Mutex
+	var n int64
+	err := sc.fanOut(keys, func(node CommCache[V], nodeKeys []string) error {
+		if bn, ok := node.(batchNode[V]); ok {
+			deleted, err := bn.mDel(ctx, nodeKeys)
+			mu.Lock()
+			n += deleted
+			mu.Unlock()
+			return err
+		}
+		var retErr error
+		for _, key := range nodeKeys {
+			ok, err := node.Del(ctx, key)
+			if err != nil {
+				retErr = multierror.Append(retErr, err)
+				continue
+			}
+			if ok {
+				mu.Lock()
+				n++
+				mu.Unlock()
+			}
+		}
+		return retErr
+	})
+	return n, err
+}
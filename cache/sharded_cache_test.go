@@ -0,0 +1,70 @@
+package cache_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/magic-lib/go-plat-cache/cache"
+	"testing"
+	"time"
+)
+
+func TestShardedCache(t *testing.T) {
+	ctx := context.Background()
+	nodes := map[string]cache.CommCache[string]{}
+	for i := 0; i < 3; i++ {
+		nodes[fmt.Sprintf("node%d", i)] = cache.NewMemGoCache[string](time.Minute, time.Minute)
+	}
+	sc := cache.NewShardedCache[string](nodes, 0)
+
+	values := make(map[string]string)
+	keys := make([]string, 0)
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("k%d", i)
+		values[key] = key
+		keys = append(keys, key)
+	}
+	if err := sc.MSet(ctx, values, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	got, err := sc.MGet(ctx, keys...)
+	if err != nil || len(got) != len(keys) {
+		t.Fatalf("MGet got %d values, %v", len(got), err)
+	}
+
+	// 同一个命名空间的 key 在同一个节点
+	if sc.NodeFor("{user:1}a") != sc.NodeFor("{user:1}b") {
+		t.Fatal("keys with the same hash tag should be on the same node")
+	}
+
+	// 添加节点后只有落到新节点上的 key 重新映射
+	before := make(map[string]string)
+	for _, key := range keys {
+		before[key] = sc.NodeFor(key)
+	}
+	sc.AddNode("node3", cache.NewMemGoCache[string](time.Minute, time.Minute))
+	moved := 0
+	for _, key := range keys {
+		node := sc.NodeFor(key)
+		if node == before[key] {
+			continue
+		}
+		if node != "node3" {
+			t.Fatalf("key %s moved from %s to %s", key, before[key], node)
+		}
+		moved++
+	}
+	if moved == 0 || moved > len(keys)/2 {
+		t.Fatalf("unexpected moved keys %d", moved)
+	}
+
+	// 删除节点后恢复原来的映射
+	sc.RemoveNode("node3")
+	for _, key := range keys {
+		if sc.NodeFor(key) != before[key] {
+			t.Fatalf("key %s should map back to %s", key, before[key])
+		}
+	}
+	if n, _ := sc.MDel(ctx, keys...); n != int64(len(keys)) {
+		t.Fatalf("MDel got %d", n)
+	}
+}
@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// slidingGetter 支持读取时同时续期的缓存，如 redis 的 GETEX
+type slidingGetter[V any] interface {
+	getEx(ctx context.Context, key string, ttl time.Duration) (V, error)
+}
+
+// slidingCache 滑动过期，每次读取都会延长有效期
+type slidingCache[V any] struct {
+	cCache   CommCache[V]
+	expiring ExpiringCache
+	ttl      time.Duration
+}
+
+// NewSlidingCache 新建滑动过期的缓存，cCache 需要实现 ExpiringCache。
+// ttl>0 时每次读取都续期为 ttl，否则按写入时的时长续期(Touch)
+func NewSlidingCache[V any](cCache CommCache[V], ttl time.Duration) (CommCache[V], error) {
+	if cCache == nil {
+		return nil, fmt.Errorf("sliding cache: cache is nil")
+	}
+	expiring, ok := cCache.(ExpiringCache)
+	if !ok {
+		return nil, fmt.Errorf("sliding cache: %T not implement ExpiringCache", cCache)
+	}
+	return &slidingCache[V]{
+		cCache:   cCache,
+		expiring: expiring,
+		ttl:      ttl,
+	}, nil
+}
+
+// Get 取值并续期
+func (co *slidingCache[V]) Get(ctx context.Context, key string) (V, error) {
+	if co.ttl > 0 {
+		if getter, ok := co.cCache.(slidingGetter[V]); ok {
+			return getter.getEx(ctx, key, co.ttl)
+		}
+	}
+	v, err := co.cCache.Get(ctx, key)
+	if err != nil {
+		return v, err
+	}
+	if _, err = co.Touch(ctx, key); err != nil {
+		return v, err
+	}
+	return v, nil
+}
+
+// Set timeout<=0 时使用滑动时长
+func (co *slidingCache[V]) Set(ctx context.Context, key string, val V, timeout time.Duration) (bool, error) {
+	if timeout <= 0 {
+		timeout = co.ttl
+	}
+	return co.cCache.Set(ctx, key, val, timeout)
+}
+
+// Del 从缓存中删除一个key
+func (co *slidingCache[V]) Del(ctx context.Context, key string) (bool, error) {
+	return co.cCache.Del(ctx, key)
+}
+
+// TTL 返回剩余有效期
+func (co *slidingCache[V]) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return co.expiring.TTL(ctx, key)
+}
+
+// Expire 重新设置过期时间
+func (co *slidingCache[V]) Expire(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	return co.expiring.Expire(ctx, key, ttl)
+}
+
+// Touch 续期，ttl>0 时续期为 ttl，否则按写入时的时长续期
+func (co *slidingCache[V]) Touch(ctx context.Context, key string) (bool, error) {
+	if co.ttl > 0 {
+		return co.expiring.Expire(ctx, key, co.ttl)
+	}
+	return co.expiring.Touch(ctx, key)
+}
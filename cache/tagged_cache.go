@@ -0,0 +1,200 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// taggedCache 给 key 打 tag，按 tag 批量失效
+type taggedCache[V any] struct {
+	cCache CommCache[V]
+	index  TagIndex
+}
+
+// NewTaggedCache 新建支持 tag 失效的缓存。未指定 index 时，cCache 实现了 TagIndex 则索引与数据存在一起，
+// 否则使用本地内存索引
+func NewTaggedCache[V any](cCache CommCache[V], index ...TagIndex) (TaggedCache[V], error) {
+	if cCache == nil {
+		return nil, fmt.Errorf("tagged cache: cache is nil")
+	}
+	co := &taggedCache[V]{
+		cCache: cCache,
+	}
+	for _, one := range index {
+		if one != nil {
+			co.index = one
+			break
+		}
+	}
+	if co.index == nil {
+		if one, ok := cCache.(TagIndex); ok {
+			co.index = one
+		} else {
+			co.index = NewMemTagIndex(checkInterval)
+		}
+	}
+	return co, nil
+}
+
+// Get 从缓存中取得一个值
+func (co *taggedCache[V]) Get(ctx context.Context, key string) (V, error) {
+	return co.cCache.Get(ctx, key)
+}
+
+// Set 写入，不打 tag
+func (co *taggedCache[V]) Set(ctx context.Context, key string, val V, timeout time.Duration) (bool, error) {
+	return co.cCache.Set(ctx, key, val, timeout)
+}
+
+// Del 从缓存中删除一个key，索引中的 key 在 tag 失效或过期时清理
+func (co *taggedCache[V]) Del(ctx context.Context, key string) (bool, error) {
+	return co.cCache.Del(ctx, key)
+}
+
+// SetWithTags 写入并给 key 打上 tags
+func (co *taggedCache[V]) SetWithTags(ctx context.Context, key string, val V, ttl time.Duration, tags ...string) (bool, error) {
+	ok, err := co.cCache.Set(ctx, key, val, ttl)
+	if err != nil || len(tags) == 0 {
+		return ok, err
+	}
+	if err = co.index.AddTagKeys(ctx, key, ttl, tags...); err != nil {
+		return ok, err
+	}
+	return ok, nil
+}
+
+// InvalidateTags 删除 tags 下的所有 key，索引中只移除读到并删除成功的 key，
+// 期间并发 SetWithTags 新加入的 key 仍保留在索引中
+func (co *taggedCache[V]) InvalidateTags(ctx context.Context, tags ...string) (int64, error) {
+	deleted := make(map[string]struct{})
+	for _, tag := range tags {
+		keys, err := co.index.TagKeys(ctx, tag)
+		if err != nil {
+			return int64(len(deleted)), err
+		}
+		removed := make([]string, 0, len(keys))
+		for _, key := range keys {
+			if _, ok := deleted[key]; !ok {
+				if _, err = co.cCache.Del(ctx, key); err != nil {
+					log.Printf("tagged cache InvalidateTags del %s: %v", key, err)
+					continue
+				}
+				deleted[key] = struct{}{}
+			}
+			removed = append(removed, key)
+		}
+		if len(removed) == 0 {
+			continue
+		}
+		if err = co.index.DelTagKeys(ctx, tag, removed...); err != nil {
+			return int64(len(deleted)), err
+		}
+	}
+	return int64(len(deleted)), nil
+}
+
+// memTagIndex 本地内存的 tag 索引
+type memTagIndex struct {
+	mu   sync.Mutex
+	tags map[string]map[string]int64 // tag -> key -> 过期时间(unix nano)
+}
+
+// NewMemTagIndex 新建本地内存的 tag 索引，cleanupInterval>0 时定期清理过期的索引条目
+func NewMemTagIndex(cleanupInterval time.Duration) TagIndex {
+	idx := &memTagIndex{
+		tags: make(map[string]map[string]int64),
+	}
+	if cleanupInterval > 0 {
+		go idx.cleanExpiredLoop(cleanupInterval)
+	}
+	return idx
+}
+
+// AddTagKeys 记录 key 属于 tags，ttl<=0 时按 defaultMaxExpireTime 保留
+func (idx *memTagIndex) AddTagKeys(_ context.Context, key string, ttl time.Duration, tags ...string) error {
+	if ttl <= 0 {
+		ttl = defaultMaxExpireTime
+	}
+	expiresAt := expiresAtOf(ttl, time.Now())
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for _, tag := range tags {
+		keys, ok := idx.tags[tag]
+		if !ok {
+			keys = make(map[string]int64)
+			idx.tags[tag] = keys
+		}
+		keys[key] = expiresAt
+	}
+	return nil
+}
+
+// TagKeys 返回 tag 下未过期的 key
+func (idx *memTagIndex) TagKeys(_ context.Context, tag string) ([]string, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	now := time.Now().UnixNano()
+	keyList := make([]string, 0, len(idx.tags[tag]))
+	for key, expiresAt := range idx.tags[tag] {
+		if expiresAt > now {
+			keyList = append(keyList, key)
+		}
+	}
+	return keyList, nil
+}
+
+// DelTags 删除 tags 的索引
+func (idx *memTagIndex) DelTags(_ context.Context, tags ...string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for _, tag := range tags {
+		delete(idx.tags, tag)
+	}
+	return nil
+}
+
+// DelTagKeys 从 tag 的索引中删除指定的 key
+func (idx *memTagIndex) DelTagKeys(_ context.Context, tag string, keys ...string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	tagKeys, ok := idx.tags[tag]
+	if !ok {
+		return nil
+	}
+	for _, key := range keys {
+		delete(tagKeys, key)
+	}
+	if len(tagKeys) == 0 {
+		delete(idx.tags, tag)
+	}
+	return nil
+}
+
+// cleanExpiredLoop 定期清理过期的索引条目
+func (idx *memTagIndex) cleanExpiredLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		idx.cleanExpired()
+	}
+}
+
+func (idx *memTagIndex) cleanExpired() {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	now := time.Now().UnixNano()
+	for tag, keys := range idx.tags {
+		for key, expiresAt := range keys {
+			if expiresAt <= now {
+				delete(keys, key)
+			}
+		}
+		if len(keys) == 0 {
+			delete(idx.tags, tag)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package cache_test
+
+import (
+	"context"
+	"github.com/magic-lib/go-plat-cache/cache"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// racingTagIndex 读取 tag 时模拟并发写入一个新的 key
+type racingTagIndex struct {
+	cache.TagIndex
+	raced bool
+}
+
+func (idx *racingTagIndex) TagKeys(ctx context.Context, tag string) ([]string, error) {
+	keys, err := idx.TagIndex.TagKeys(ctx, tag)
+	if !idx.raced {
+		idx.raced = true
+		_ = idx.TagIndex.AddTagKeys(ctx, "page:new", time.Minute, tag)
+	}
+	return keys, err
+}
+
+func TestTaggedCacheConcurrentSet(t *testing.T) {
+	ctx := context.Background()
+	idx := &racingTagIndex{TagIndex: cache.NewMemTagIndex(0)}
+	tc, err := cache.NewTaggedCache[string](cache.NewMemLruCache[string](100, time.Minute), idx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = tc.SetWithTags(ctx, "page:1", "a", time.Minute, "product:123")
+
+	if n, err := tc.InvalidateTags(ctx, "product:123"); err != nil || n != 1 {
+		t.Fatalf("expected invalidate 1 key, got %d, %v", n, err)
+	}
+	// 失效期间新加入的 key 不能从索引中丢失
+	keys, _ := idx.TagKeys(ctx, "product:123")
+	if len(keys) != 1 || keys[0] != "page:new" {
+		t.Errorf("expected page:new kept in index, got %v", keys)
+	}
+}
+
+func TestTaggedCache(t *testing.T) {
+	ctx := context.Background()
+	boltCache, err := cache.NewBBoltCache[string](&cache.BBoltCacheConfig{
+		DbPath: filepath.Join(t.TempDir(), "tag.db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cacheList := map[string]cache.CommCache[string]{
+		"bolt":   boltCache,                                      // 索引存在 bolt 中
+		"memLru": cache.NewMemLruCache[string](100, time.Minute), // 本地内存索引
+	}
+	for name, one := range cacheList {
+		tc, err := cache.NewTaggedCache[string](one)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = tc.SetWithTags(ctx, "page:1", "a", time.Minute, "product:123", "category:1")
+		_, _ = tc.SetWithTags(ctx, "page:2", "b", time.Minute, "product:123")
+		_, _ = tc.SetWithTags(ctx, "page:3", "c", time.Minute, "category:1")
+
+		n, err := tc.InvalidateTags(ctx, "product:123")
+		if err != nil || n != 2 {
+			t.Errorf("%s: expected invalidate 2 keys, got %d, %v", name, n, err)
+		}
+		if v, _ := tc.Get(ctx, "page:1"); v != "" {
+			t.Errorf("%s: page:1 should be invalidated, got %q", name, v)
+		}
+		if v, _ := tc.Get(ctx, "page:3"); v != "c" {
+			t.Errorf("%s: page:3 should keep, got %q", name, v)
+		}
+	}
+}
@@ -4,8 +4,13 @@ import (
 	"fmt"
 	"github.com/magic-lib/go-plat-utils/conv"
 	"reflect"
+	"sort"
+	"strings"
+	"time"
 )
 
+const defaultScanCount = 100 // Scan 未指定 count 时每批返回的数量
+
 func strToVal[V any](valueStr string) (V, error) {
 	var value V
 	newValuePtr := conv.NewPtrByType(reflect.TypeOf(value))
@@ -21,3 +26,60 @@ func strToVal[V any](valueStr string) (V, error) {
 	}
 	return value, nil
 }
+
+// expiresAtOf 根据有效期计算过期时间点(unix nano)，timeout<=0 返回 0 表示永不过期
+func expiresAtOf(timeout time.Duration, now time.Time) int64 {
+	if timeout <= 0 {
+		return 0
+	}
+	return now.Add(timeout).UnixNano()
+}
+
+// ttlOf 根据过期时间点(unix nano)计算剩余有效期
+func ttlOf(expiresAt int64, now time.Time) time.Duration {
+	if expiresAt <= 0 {
+		return TTLNoExpire
+	}
+	remain := time.Duration(expiresAt - now.UnixNano())
+	if remain <= 0 {
+		return TTLNotFound
+	}
+	return remain
+}
+
+// scanSortedKeys 从全部 key 中按字典序取出以 prefix 开头且大于 cursor 的 count 个，
+// 用于不支持原生游标的缓存，游标即上一批的最后一个 key
+func scanSortedKeys(keys []string, prefix, cursor string, count int) ([]string, string) {
+	if count <= 0 {
+		count = defaultScanCount
+	}
+	matched := make([]string, 0)
+	for _, key := range keys {
+		if strings.HasPrefix(key, prefix) && key > cursor {
+			matched = append(matched, key)
+		}
+	}
+	sort.Strings(matched)
+	if len(matched) <= count {
+		return matched, ""
+	}
+	return matched[:count], matched[count-1]
+}
+
+// isZeroValue 判断是否为零值，CommCache 的 Get 在 key 不存在时大多返回零值
+func isZeroValue[V any](v V) bool {
+	return reflect.ValueOf(&v).Elem().IsZero()
+}
+
+// strMapToVal 反序列化 hash 中的所有值
+func strMapToVal[V any](strValues map[string]string) (map[string]V, error) {
+	values := make(map[string]V, len(strValues))
+	for field, valueStr := range strValues {
+		v, err := strToVal[V](valueStr)
+		if err != nil {
+			return nil, err
+		}
+		values[field] = v
+	}
+	return values, nil
+}
@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"context"
+	"hash/fnv"
+	"math/rand/v2"
+	"time"
+)
+
+// TTLJitter 给有效期加上随机抖动，避免同一批写入的 key 同时过期。
+// 抖动范围为 [ttl-偏移, ttl+偏移]，Percent 和 Range 同时设置时取较大的偏移
+type TTLJitter struct {
+	Percent       float64       // 按比例抖动，0.1 表示偏移 ttl 的 10%
+	Range         time.Duration // 按固定时长抖动
+	Deterministic bool          // 同一个 key 的抖动固定，多个实例写入同一个 key 时过期时间一致
+}
+
+// Apply 返回抖动后的有效期，ttl<=0 时保持不变，使用各缓存的默认规则
+func (j *TTLJitter) Apply(key string, ttl time.Duration) time.Duration {
+	if j == nil || ttl <= 0 {
+		return ttl
+	}
+	offset := time.Duration(float64(ttl) * j.Percent)
+	if j.Range > offset {
+		offset = j.Range
+	}
+	if offset <= 0 {
+		return ttl
+	}
+	// factor 取值范围 [-1, 1)
+	factor := 2*j.fraction(key) - 1
+	newTTL := ttl + time.Duration(factor*float64(offset))
+	if newTTL <= 0 {
+		// 偏移大于 ttl 时，保证不会变成永不过期或默认时长
+		return time.Millisecond
+	}
+	return newTTL
+}
+
+// fraction 返回 [0,1) 之间的值，Deterministic 时由 key 的哈希决定
+func (j *TTLJitter) fraction(key string) float64 {
+	if !j.Deterministic {
+		return rand.Float64()
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return float64(h.Sum64()>>11) / (1 << 53)
+}
+
+// jitterCache 写入时给有效期加上随机抖动
+type jitterCache[V any] struct {
+	cCache CommCache[V]
+	jitter *TTLJitter
+}
+
+// NewJitterCache 新建写入时有效期随机抖动的缓存
+func NewJitterCache[V any](cCache CommCache[V], jitter TTLJitter) CommCache[V] {
+	return &jitterCache[V]{
+		cCache: cCache,
+		jitter: &jitter,
+	}
+}
+
+// Get 从缓存中取得一个值
+func (co *jitterCache[V]) Get(ctx context.Context, key string) (V, error) {
+	return co.cCache.Get(ctx, key)
+}
+
+// Set timeout>0 时加上抖动
+func (co *jitterCache[V]) Set(ctx context.Context, key string, val V, timeout time.Duration) (bool, error) {
+	return co.cCache.Set(ctx, key, val, co.jitter.Apply(key, timeout))
+}
+
+// Del 从缓存中删除一个key
+func (co *jitterCache[V]) Del(ctx context.Context, key string) (bool, error) {
+	return co.cCache.Del(ctx, key)
+}
@@ -0,0 +1,31 @@
+package cache_test
+
+import (
+	"github.com/magic-lib/go-plat-cache/cache"
+	"testing"
+	"time"
+)
+
+func TestTTLJitter(t *testing.T) {
+	jitter := &cache.TTLJitter{Percent: 0.1}
+	ttl := 100 * time.Second
+	spread := make(map[time.Duration]struct{})
+	for i := 0; i < 100; i++ {
+		one := jitter.Apply("key", ttl)
+		if one < 90*time.Second || one > 110*time.Second {
+			t.Fatalf("jitter out of range: %v", one)
+		}
+		spread[one] = struct{}{}
+	}
+	if len(spread) < 2 {
+		t.Error("jitter should spread ttl")
+	}
+
+	deterministic := &cache.TTLJitter{Range: 5 * time.Second, Deterministic: true}
+	if deterministic.Apply("key", ttl) != deterministic.Apply("key", ttl) {
+		t.Error("deterministic jitter should be stable for the same key")
+	}
+	if jitter.Apply("key", 0) != 0 {
+		t.Error("ttl<=0 should keep unchanged")
+	}
+}
@@ -0,0 +1,58 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"github.com/magic-lib/go-plat-cache/cache"
+	"path/filepath"
+	"testing"
+)
+
+func TestMemVersionedCache(t *testing.T) {
+	testVersionedCache(t, cache.NewMemVersionedCache[string](0))
+}
+
+func TestBoltVersionedCache(t *testing.T) {
+	boltCache, err := cache.NewBBoltCache[string](&cache.BBoltCacheConfig{
+		DbPath: filepath.Join(t.TempDir(), "versioned.db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testVersionedCache(t, boltCache)
+}
+
+func testVersionedCache(t *testing.T, one cache.CommCache[string]) {
+	ctx := context.Background()
+	vc := one.(cache.VersionedCache[string])
+
+	ver, err := vc.CompareAndSet(ctx, "key", 0, "a", 0)
+	if err != nil || ver != 1 {
+		t.Fatalf("first cas should succeed, ver=%d err=%v", ver, err)
+	}
+
+	_, err = vc.CompareAndSet(ctx, "key", 0, "b", 0)
+	var conflict *cache.ErrVersionConflict
+	if !errors.As(err, &conflict) || conflict.Actual != 1 {
+		t.Fatalf("stale cas should conflict, got %v", err)
+	}
+
+	if v, ver, _ := vc.GetWithVersion(ctx, "key"); v != "a" || ver != 1 {
+		t.Errorf("unexpected value %q version %d", v, ver)
+	}
+
+	// Set 也会把版本号加 1，之前读到版本号的 CompareAndSet 会冲突
+	_, _ = one.Set(ctx, "key", "c", 0)
+	if _, ver, _ = vc.GetWithVersion(ctx, "key"); ver != 2 {
+		t.Errorf("version should be bumped by Set, got %d", ver)
+	}
+	if _, err = vc.CompareAndSet(ctx, "key", 1, "d", 0); !errors.As(err, &conflict) || conflict.Actual != 2 {
+		t.Errorf("cas after Set should conflict, got %v", err)
+	}
+
+	// 不存在的 key 被 Set 写入后，期望版本号为 0 的 CompareAndSet 不能覆盖
+	_, _ = one.Set(ctx, "other", "x", 0)
+	if _, err = vc.CompareAndSet(ctx, "other", 0, "y", 0); !errors.As(err, &conflict) || conflict.Actual != 1 {
+		t.Errorf("cas(0) after Set should conflict, got %v", err)
+	}
+}
@@ -0,0 +1,213 @@
+package delayqueue
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	bolt "go.etcd.io/bbolt"
+	"time"
+)
+
+// BoltDB 可取得底层 bbolt 实例，cache.NewBBoltCache 返回的 *cache.BBoltCache 已实现
+type BoltDB interface {
+	DB() (*bolt.DB, error)
+}
+
+// boltJob bbolt 中存储的任务，Due 为当前在时间索引中的到期时间
+type boltJob struct {
+	Job
+	Due int64 `json:"due"` // unix nano
+}
+
+// boltQueue 基于 bbolt 的延时队列，只适用于单节点。
+// 时间索引 bucket 的 key 为 8 字节大端到期时间加任务 id，按 key 顺序即按到期时间顺序
+type boltQueue struct {
+	cfg        *Config
+	db         *bolt.DB
+	dueBucket  []byte
+	jobBucket  []byte
+	deadBucket []byte
+}
+
+// NewBoltQueue 新建 bbolt 延时队列
+func NewBoltQueue(store BoltDB, cfg *Config) (Queue, error) {
+	if store == nil {
+		return nil, fmt.Errorf("delayqueue bolt store is nil")
+	}
+	newCfg, err := checkConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	db, err := store.DB()
+	if err != nil {
+		return nil, err
+	}
+	q := &boltQueue{
+		cfg:        newCfg,
+		db:         db,
+		dueBucket:  []byte("delayqueue:" + newCfg.Name + "__due"),
+		jobBucket:  []byte("delayqueue:" + newCfg.Name + "__jobs"),
+		deadBucket: []byte("delayqueue:" + newCfg.Name + "__dead"),
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{q.dueBucket, q.jobBucket, q.deadBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return fmt.Errorf("create bucket %s failed: %w", name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// dueKey 时间索引的 key
+func dueKey(due int64, id string) []byte {
+	k := make([]byte, 8, 8+len(id))
+	binary.BigEndian.PutUint64(k, uint64(due))
+	return append(k, id...)
+}
+
+// Enqueue 添加任务
+func (q *boltQueue) Enqueue(_ context.Context, payload []byte, runAt time.Time) (string, error) {
+	bj := &boltJob{
+		Job: Job{
+			ID:      newJobID(q.cfg.Clock()),
+			Payload: payload,
+			RunAt:   runAt,
+		},
+		Due: runAt.UnixNano(),
+	}
+	err := q.db.Update(func(tx *bolt.Tx) error {
+		return q.putJob(tx, bj)
+	})
+	if err != nil {
+		return "", err
+	}
+	return bj.ID, nil
+}
+
+// Dequeue 在一个写事务中取出最早到期的任务，并把到期时间改为可见性超时时间
+func (q *boltQueue) Dequeue(_ context.Context) (*Job, error) {
+	now := q.cfg.Clock()
+	var job *Job
+	err := q.db.Update(func(tx *bolt.Tx) error {
+		due := tx.Bucket(q.dueBucket)
+		for {
+			k, _ := due.Cursor().First()
+			if k == nil || int64(binary.BigEndian.Uint64(k[:8])) > now.UnixNano() {
+				return nil
+			}
+			key := append([]byte(nil), k...)
+			if err := due.Delete(key); err != nil {
+				return err
+			}
+			bj, err := q.getJob(tx, string(key[8:]))
+			if err != nil {
+				return err
+			}
+			if bj == nil {
+				continue
+			}
+			bj.Attempts++
+			if bj.Attempts > q.cfg.MaxAttempts {
+				if err = q.moveToDead(tx, bj); err != nil {
+					return err
+				}
+				continue
+			}
+			bj.Due = now.Add(q.cfg.VisibilityTimeout).UnixNano()
+			if err = q.putJob(tx, bj); err != nil {
+				return err
+			}
+			one := bj.Job
+			job = &one
+			return nil
+		}
+	})
+	return job, err
+}
+
+// Ack 任务处理成功
+func (q *boltQueue) Ack(_ context.Context, job *Job) error {
+	return q.db.Update(func(tx *bolt.Tx) error {
+		bj, err := q.getOwnedJob(tx, job)
+		if err != nil {
+			return err
+		}
+		if err = tx.Bucket(q.dueBucket).Delete(dueKey(bj.Due, bj.ID)); err != nil {
+			return err
+		}
+		return tx.Bucket(q.jobBucket).Delete([]byte(bj.ID))
+	})
+}
+
+// Nack 任务处理失败，按退避时间重新投递，超过 MaxAttempts 后移入死信
+func (q *boltQueue) Nack(_ context.Context, job *Job) error {
+	now := q.cfg.Clock()
+	return q.db.Update(func(tx *bolt.Tx) error {
+		bj, err := q.getOwnedJob(tx, job)
+		if err != nil {
+			return err
+		}
+		if err = tx.Bucket(q.dueBucket).Delete(dueKey(bj.Due, bj.ID)); err != nil {
+			return err
+		}
+		if bj.Attempts >= q.cfg.MaxAttempts {
+			return q.moveToDead(tx, bj)
+		}
+		bj.Due = now.Add(q.cfg.Backoff(bj.Attempts)).UnixNano()
+		return q.putJob(tx, bj)
+	})
+}
+
+// getOwnedJob 取出投递次数与 job 一致的任务，不一致说明已被重新投递
+func (q *boltQueue) getOwnedJob(tx *bolt.Tx, job *Job) (*boltJob, error) {
+	bj, err := q.getJob(tx, job.ID)
+	if err != nil {
+		return nil, err
+	}
+	if bj == nil || bj.Attempts != job.Attempts {
+		return nil, ErrJobNotOwned
+	}
+	return bj, nil
+}
+
+func (q *boltQueue) getJob(tx *bolt.Tx, id string) (*boltJob, error) {
+	data := tx.Bucket(q.jobBucket).Get([]byte(id))
+	if data == nil {
+		return nil, nil
+	}
+	bj := new(boltJob)
+	if err := json.Unmarshal(data, bj); err != nil {
+		return nil, fmt.Errorf("unmarshal job %s failed: %w", id, err)
+	}
+	return bj, nil
+}
+
+// putJob 保存任务并写入时间索引
+func (q *boltQueue) putJob(tx *bolt.Tx, bj *boltJob) error {
+	data, err := json.Marshal(bj)
+	if err != nil {
+		return err
+	}
+	if err = tx.Bucket(q.jobBucket).Put([]byte(bj.ID), data); err != nil {
+		return err
+	}
+	return tx.Bucket(q.dueBucket).Put(dueKey(bj.Due, bj.ID), []byte(bj.ID))
+}
+
+// moveToDead 移入死信，保留任务数据便于排查
+func (q *boltQueue) moveToDead(tx *bolt.Tx, bj *boltJob) error {
+	data, err := json.Marshal(bj.Job)
+	if err != nil {
+		return err
+	}
+	if err = tx.Bucket(q.deadBucket).Put([]byte(bj.ID), data); err != nil {
+		return err
+	}
+	return tx.Bucket(q.jobBucket).Delete([]byte(bj.ID))
+}
@@ -0,0 +1,190 @@
+package delayqueue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Job 延时任务
+type Job struct {
+	ID       string    `json:"id"`
+	Payload  []byte    `json:"payload"`
+	RunAt    time.Time `json:"run_at"`   // 计划执行时间
+	Attempts int       `json:"attempts"` // 第几次投递，从 1 开始，Ack/Nack 时用于确认任务仍归当前消费者所有
+}
+
+// Queue 延时队列，至少投递一次：取出的任务在 VisibilityTimeout 内没有 Ack/Nack 会被重新投递
+type Queue interface {
+	// Enqueue 添加任务，runAt 之后可被取出，返回任务 id
+	Enqueue(ctx context.Context, payload []byte, runAt time.Time) (string, error)
+	// Dequeue 取出一个到期的任务，没有到期任务时返回 nil
+	Dequeue(ctx context.Context) (*Job, error)
+	// Ack 任务处理成功，从队列中删除
+	Ack(ctx context.Context, job *Job) error
+	// Nack 任务处理失败，按退避时间重新投递，超过 MaxAttempts 后移入死信
+	Nack(ctx context.Context, job *Job) error
+}
+
+// ErrJobNotOwned 任务已超时被重新投递或已被删除，当前的 Ack/Nack 无效
+var ErrJobNotOwned = errors.New("delayqueue: job is not owned by this consumer")
+
+const (
+	defaultVisibilityTimeout = 30 * time.Second
+	defaultMaxAttempts       = 5
+	defaultBackoffBase       = time.Second
+	defaultBackoffMax        = 10 * time.Minute
+	defaultPollInterval      = time.Second
+)
+
+// Config 队列配置
+type Config struct {
+	Name              string                           // 队列名称，同名队列共享任务
+	VisibilityTimeout time.Duration                    // 取出后多久未确认则重新投递，默认 30s
+	MaxAttempts       int                              // 最多投递次数，超过后移入死信，默认 5
+	Backoff           func(attempts int) time.Duration // Nack 后的重试间隔，默认从 1s 开始指数增长，最长 10min
+	Clock             func() time.Time                 // 时钟，默认 time.Now，测试时可替换
+}
+
+// checkConfig 检查配置并设置默认值
+func checkConfig(cfg *Config) (*Config, error) {
+	if cfg == nil || cfg.Name == "" {
+		return nil, fmt.Errorf("delayqueue name is empty")
+	}
+	newCfg := *cfg
+	if newCfg.VisibilityTimeout <= 0 {
+		newCfg.VisibilityTimeout = defaultVisibilityTimeout
+	}
+	if newCfg.MaxAttempts <= 0 {
+		newCfg.MaxAttempts = defaultMaxAttempts
+	}
+	if newCfg.Backoff == nil {
+		newCfg.Backoff = ExponentialBackoff(defaultBackoffBase, defaultBackoffMax)
+	}
+	if newCfg.Clock == nil {
+		newCfg.Clock = time.Now
+	}
+	return &newCfg, nil
+}
+
+// ExponentialBackoff 指数退避，第 n 次失败后等待 base*2^(n-1)，最长 max
+func ExponentialBackoff(base, max time.Duration) func(attempts int) time.Duration {
+	return func(attempts int) time.Duration {
+		if attempts < 1 {
+			attempts = 1
+		}
+		delay := base
+		for i := 1; i < attempts && delay < max; i++ {
+			delay *= 2
+		}
+		if delay > max {
+			delay = max
+		}
+		return delay
+	}
+}
+
+// newJobID 时间前缀加随机数，同一时间的任务按 id 排序时大致保持入队顺序
+func newJobID(now time.Time) string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return strconv.FormatInt(now.UnixNano(), 36) + hex.EncodeToString(b)
+}
+
+// Handler 任务处理函数，返回 nil 时 Ack，否则 Nack
+type Handler func(ctx context.Context, job *Job) error
+
+// WorkerConfig 消费者配置
+type WorkerConfig struct {
+	Concurrency  int                       // 并发处理数，默认 1
+	PollInterval time.Duration             // 没有到期任务时的轮询间隔，默认 1s
+	OnError      func(job *Job, err error) // 处理失败或队列出错时回调，队列出错时 job 为 nil
+}
+
+// Worker 轮询队列并处理任务
+type Worker struct {
+	queue   Queue
+	handler Handler
+	cfg     WorkerConfig
+}
+
+// NewWorker 新建消费者
+func NewWorker(queue Queue, handler Handler, cfg *WorkerConfig) *Worker {
+	w := &Worker{
+		queue:   queue,
+		handler: handler,
+	}
+	if cfg != nil {
+		w.cfg = *cfg
+	}
+	if w.cfg.Concurrency <= 0 {
+		w.cfg.Concurrency = 1
+	}
+	if w.cfg.PollInterval <= 0 {
+		w.cfg.PollInterval = defaultPollInterval
+	}
+	return w
+}
+
+// Run 开始消费，阻塞直到 ctx 结束
+func (w *Worker) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < w.cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+func (w *Worker) loop(ctx context.Context) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		job, err := w.queue.Dequeue(ctx)
+		if err != nil || job == nil {
+			if err != nil {
+				w.onError(nil, err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(w.cfg.PollInterval):
+			}
+			continue
+		}
+		if err = w.handle(ctx, job); err != nil {
+			w.onError(job, err)
+			err = w.queue.Nack(ctx, job)
+		} else {
+			err = w.queue.Ack(ctx, job)
+		}
+		if err != nil {
+			w.onError(job, err)
+		}
+	}
+}
+
+// handle 执行任务，panic 视为失败
+func (w *Worker) handle(ctx context.Context, job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("delayqueue handler panic: %v", r)
+		}
+	}()
+	return w.handler(ctx, job)
+}
+
+func (w *Worker) onError(job *Job, err error) {
+	if w.cfg.OnError != nil {
+		w.cfg.OnError(job, err)
+	}
+}
@@ -0,0 +1,123 @@
+package delayqueue_test
+
+import (
+	"context"
+	"errors"
+	"github.com/magic-lib/go-plat-cache/cache"
+	"github.com/magic-lib/go-plat-cache/delayqueue"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock 手动推进的时钟
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newBoltQueue(t *testing.T, clock *fakeClock, maxAttempts int) delayqueue.Queue {
+	boltCache, err := cache.NewBBoltCache[string](&cache.BBoltCacheConfig{
+		DbPath: filepath.Join(t.TempDir(), "queue.db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, err := delayqueue.NewBoltQueue(boltCache.(delayqueue.BoltDB), &delayqueue.Config{
+		Name:              "jobs",
+		VisibilityTimeout: time.Minute,
+		MaxAttempts:       maxAttempts,
+		Backoff:           delayqueue.ExponentialBackoff(time.Second, time.Minute),
+		Clock:             clock.Now,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+func TestBoltQueue(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	q := newBoltQueue(t, clock, 5)
+
+	id, err := q.Enqueue(ctx, []byte("send mail"), clock.Now().Add(10*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job, _ := q.Dequeue(ctx); job != nil {
+		t.Fatalf("job should not be due yet: %+v", job)
+	}
+
+	clock.Advance(10 * time.Minute)
+	first, err := q.Dequeue(ctx)
+	if err != nil || first == nil || first.ID != id || string(first.Payload) != "send mail" || first.Attempts != 1 {
+		t.Fatalf("dequeue got %+v, %v", first, err)
+	}
+	if job, _ := q.Dequeue(ctx); job != nil {
+		t.Fatalf("in-flight job should be invisible: %+v", job)
+	}
+
+	// 超过可见性超时未确认，重新投递，旧的确认无效
+	clock.Advance(time.Minute)
+	second, _ := q.Dequeue(ctx)
+	if second == nil || second.Attempts != 2 {
+		t.Fatalf("job should be redelivered, got %+v", second)
+	}
+	if err = q.Ack(ctx, first); !errors.Is(err, delayqueue.ErrJobNotOwned) {
+		t.Fatalf("stale ack should fail, got %v", err)
+	}
+
+	// Nack 后按退避时间重试
+	if err = q.Nack(ctx, second); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Second)
+	if job, _ := q.Dequeue(ctx); job != nil {
+		t.Fatalf("job should wait for backoff: %+v", job)
+	}
+	clock.Advance(time.Second)
+	third, _ := q.Dequeue(ctx)
+	if third == nil || third.Attempts != 3 {
+		t.Fatalf("job should be retried after backoff, got %+v", third)
+	}
+	if err = q.Ack(ctx, third); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Hour)
+	if job, _ := q.Dequeue(ctx); job != nil {
+		t.Fatalf("acked job should be removed: %+v", job)
+	}
+}
+
+func TestBoltQueueMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	q := newBoltQueue(t, clock, 2)
+
+	_, _ = q.Enqueue(ctx, []byte("x"), clock.Now())
+	for i := 1; i <= 2; i++ {
+		job, _ := q.Dequeue(ctx)
+		if job == nil || job.Attempts != i {
+			t.Fatalf("attempt %d got %+v", i, job)
+		}
+		_ = q.Nack(ctx, job)
+		clock.Advance(time.Hour)
+	}
+	if job, _ := q.Dequeue(ctx); job != nil {
+		t.Fatalf("job should be moved to dead letter: %+v", job)
+	}
+}
@@ -0,0 +1,186 @@
+package delayqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/magic-lib/go-plat-cache/cache"
+	"github.com/magic-lib/go-plat-startupcfg/startupcfg"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// 所有 key 使用同一个 hash tag，保证集群下脚本访问的 key 在同一个 slot
+// KEYS: 1 待执行的有序集合(score 为到期毫秒) 2 任务数据 hash 3 投递次数 hash 4 死信有序集合
+var (
+	// enqueueScript ARGV: id, 任务数据, 到期毫秒
+	enqueueScript = redis.NewScript(`
+redis.call('HSET', KEYS[2], ARGV[1], ARGV[2])
+redis.call('HSET', KEYS[3], ARGV[1], 0)
+redis.call('ZADD', KEYS[1], ARGV[3], ARGV[1])
+return 1
+`)
+
+	// dequeueScript ARGV: 当前毫秒, 可见性超时毫秒, 最多投递次数。
+	// 取出后把 score 改为超时时间，超时未确认的任务会再次到期被取出
+	dequeueScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+for i = 1, 10 do
+	local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', now, 'LIMIT', 0, 1)
+	if #ids == 0 then
+		return false
+	end
+	local id = ids[1]
+	local data = redis.call('HGET', KEYS[2], id)
+	if not data then
+		redis.call('ZREM', KEYS[1], id)
+		redis.call('HDEL', KEYS[3], id)
+	else
+		local attempts = redis.call('HINCRBY', KEYS[3], id, 1)
+		if attempts > tonumber(ARGV[3]) then
+			redis.call('ZREM', KEYS[1], id)
+			redis.call('ZADD', KEYS[4], now, id)
+		else
+			redis.call('ZADD', KEYS[1], now + tonumber(ARGV[2]), id)
+			return {data, attempts}
+		end
+	end
+end
+return false
+`)
+
+	// ackScript ARGV: id, 投递次数
+	ackScript = redis.NewScript(`
+if redis.call('HGET', KEYS[3], ARGV[1]) ~= ARGV[2] then
+	return 0
+end
+redis.call('ZREM', KEYS[1], ARGV[1])
+redis.call('HDEL', KEYS[2], ARGV[1])
+redis.call('HDEL', KEYS[3], ARGV[1])
+return 1
+`)
+
+	// nackScript ARGV: id, 投递次数, 重试毫秒, 是否移入死信
+	nackScript = redis.NewScript(`
+if redis.call('HGET', KEYS[3], ARGV[1]) ~= ARGV[2] then
+	return 0
+end
+if ARGV[4] == '1' then
+	redis.call('ZREM', KEYS[1], ARGV[1])
+	redis.call('ZADD', KEYS[4], ARGV[3], ARGV[1])
+else
+	redis.call('ZADD', KEYS[1], ARGV[3], ARGV[1])
+end
+return 1
+`)
+)
+
+// ScriptRunner 执行 lua 脚本，cache.NewRedisClient 返回的客户端已实现
+type ScriptRunner interface {
+	RunScript(ctx context.Context, script *redis.Script, keys []string, args ...any) (any, error)
+}
+
+// redisQueue 基于 redis 有序集合的延时队列，时间使用 Clock 传入，多实例时需保证时钟同步
+type redisQueue struct {
+	cfg    *Config
+	runner ScriptRunner
+	keys   []string
+}
+
+// NewRedisQueue 新建 redis 延时队列
+func NewRedisQueue(runner ScriptRunner, cfg *Config) (Queue, error) {
+	if runner == nil {
+		return nil, fmt.Errorf("delayqueue script runner is nil")
+	}
+	newCfg, err := checkConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	prefix := "delayqueue:{" + newCfg.Name + "}:"
+	return &redisQueue{
+		cfg:    newCfg,
+		runner: runner,
+		keys:   []string{prefix + "queue", prefix + "jobs", prefix + "attempts", prefix + "dead"},
+	}, nil
+}
+
+// NewRedisQueueFromConfig 使用全局连接管理中的 redis 连接新建延时队列
+func NewRedisQueueFromConfig(redisCfg *startupcfg.RedisConfig, cfg *Config, opt ...*cache.RedisOptions) (Queue, error) {
+	if redisCfg == nil {
+		return nil, fmt.Errorf("redis config is nil")
+	}
+	return NewRedisQueue(cache.NewRedisClient(redisCfg, opt...), cfg)
+}
+
+// Enqueue 添加任务
+func (q *redisQueue) Enqueue(ctx context.Context, payload []byte, runAt time.Time) (string, error) {
+	job := &Job{
+		ID:      newJobID(q.cfg.Clock()),
+		Payload: payload,
+		RunAt:   runAt,
+	}
+	data, err := json.Marshal(job)
+	if err != nil {
+		return "", err
+	}
+	_, err = q.runner.RunScript(ctx, enqueueScript, q.keys, job.ID, string(data), runAt.UnixMilli())
+	if err != nil {
+		return "", fmt.Errorf("delayqueue enqueue error: %w", err)
+	}
+	return job.ID, nil
+}
+
+// Dequeue 取出一个到期的任务
+func (q *redisQueue) Dequeue(ctx context.Context) (*Job, error) {
+	ret, err := q.runner.RunScript(ctx, dequeueScript, q.keys,
+		q.cfg.Clock().UnixMilli(), q.cfg.VisibilityTimeout.Milliseconds(), q.cfg.MaxAttempts)
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("delayqueue dequeue error: %w", err)
+	}
+	list, ok := ret.([]any)
+	if !ok || len(list) != 2 {
+		return nil, fmt.Errorf("delayqueue unexpected dequeue result: %v", ret)
+	}
+	data, _ := list[0].(string)
+	attempts, _ := list[1].(int64)
+	job := new(Job)
+	if err = json.Unmarshal([]byte(data), job); err != nil {
+		return nil, fmt.Errorf("delayqueue unmarshal job error: %w", err)
+	}
+	job.Attempts = int(attempts)
+	return job, nil
+}
+
+// Ack 任务处理成功
+func (q *redisQueue) Ack(ctx context.Context, job *Job) error {
+	ret, err := q.runner.RunScript(ctx, ackScript, q.keys, job.ID, job.Attempts)
+	if err != nil {
+		return fmt.Errorf("delayqueue ack error: %w", err)
+	}
+	if n, _ := ret.(int64); n == 0 {
+		return ErrJobNotOwned
+	}
+	return nil
+}
+
+// Nack 任务处理失败，按退避时间重新投递
+func (q *redisQueue) Nack(ctx context.Context, job *Job) error {
+	now := q.cfg.Clock()
+	dead := "0"
+	at := now.Add(q.cfg.Backoff(job.Attempts))
+	if job.Attempts >= q.cfg.MaxAttempts {
+		dead, at = "1", now
+	}
+	ret, err := q.runner.RunScript(ctx, nackScript, q.keys, job.ID, job.Attempts, at.UnixMilli(), dead)
+	if err != nil {
+		return fmt.Errorf("delayqueue nack error: %w", err)
+	}
+	if n, _ := ret.(int64); n == 0 {
+		return ErrJobNotOwned
+	}
+	return nil
+}
@@ -2,9 +2,11 @@ package filter
 
 import (
 	"context"
-	"github.com/go-redis/redis/v8"
 	"github.com/hugh2632/bloomfilter"
 	"github.com/hugh2632/bloomfilter/global"
+	"github.com/magic-lib/go-plat-cache/cache"
+	"github.com/magic-lib/go-plat-startupcfg/startupcfg"
+	"github.com/redis/go-redis/v9"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
@@ -14,7 +16,8 @@ type BloomFilterOption struct {
 	Hashes         []global.HashFunc
 	FilterInstance bloomfilter.IFilter //自定义
 	RedisFilter    *struct {
-		RedisOptions *redis.Options
+		RedisConfig  *startupcfg.RedisConfig // 优先使用，与其他缓存共用连接池
+		RedisOptions *redis.Options          // 单独建立连接
 		FilterType   bloomfilter.RedisFilterType
 		Key          string
 	}
@@ -36,11 +39,23 @@ type BloomFilter struct {
 
 func NewBloomFilter(bo *BloomFilterOption) (*BloomFilter, error) {
 	bo = initOption(bo)
-	if bo.RedisFilter != nil && bo.RedisFilter.RedisOptions != nil {
-		cli := redis.NewClient(bo.RedisFilter.RedisOptions)
-		redisFilter, err := bloomfilter.NewRedisFilter(context.Background(), cli,
+	if bo.RedisFilter != nil && (bo.RedisFilter.RedisConfig != nil || bo.RedisFilter.RedisOptions != nil) {
+		var cli redis.UniversalClient
+		ownClient := bo.RedisFilter.RedisConfig == nil
+		if ownClient {
+			cli = redis.NewClient(bo.RedisFilter.RedisOptions)
+		} else {
+			var err error
+			if cli, err = cache.GetRedisClient(bo.RedisFilter.RedisConfig); err != nil {
+				return nil, err
+			}
+		}
+		redisFilter, err := newRedisFilter(context.Background(), cli, ownClient,
 			bo.RedisFilter.FilterType, bo.RedisFilter.Key, bo.ByteLen, bo.Hashes...)
 		if err != nil {
+			if ownClient {
+				_ = cli.Close()
+			}
 			return nil, err
 		}
 		return &BloomFilter{
@@ -87,7 +102,7 @@ func initOption(bo *BloomFilterOption) *BloomFilterOption {
 		bo.Hashes = bloomfilter.DefaultHash
 	}
 
-	if bo.RedisFilter != nil && bo.RedisFilter.RedisOptions != nil {
+	if bo.RedisFilter != nil && (bo.RedisFilter.RedisConfig != nil || bo.RedisFilter.RedisOptions != nil) {
 		if bo.RedisFilter.FilterType == 0 {
 			bo.RedisFilter.FilterType = bloomfilter.RedisFilterType_Cached
 		}
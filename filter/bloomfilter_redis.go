@@ -0,0 +1,166 @@
+package filter
+
+import (
+	"context"
+	"errors"
+	"github.com/hugh2632/bloomfilter"
+	"github.com/hugh2632/bloomfilter/global"
+	"github.com/hugh2632/bloomfilter/memory"
+	"github.com/redis/go-redis/v9"
+)
+
+const redisHashTableName = "bloom" // 缓存模式下保存字节数组的 hash 表名，与 bloomfilter 保持一致
+
+// newRedisFilter 基于 go-redis v9 的过滤器，数据结构与 bloomfilter.NewRedisFilter 一致，可以读取已有的数据。
+// ownClient 为 true 时 Close 会关闭客户端，共用的连接不能关闭
+func newRedisFilter(ctx context.Context, cli redis.UniversalClient, ownClient bool, tp bloomfilter.RedisFilterType,
+	key string, byteLen uint64, hashes ...global.HashFunc) (bloomfilter.IFilter, error) {
+	switch tp {
+	case bloomfilter.RedisFilterType_Cached:
+		f := &redisCachedFilter{
+			Filter: &memory.Filter{
+				Bytes:  make([]byte, byteLen),
+				Hashes: hashes,
+			},
+			key:       key,
+			cli:       cli,
+			ownClient: ownClient,
+			ctx:       ctx,
+		}
+		if err := f.init(); err != nil {
+			return nil, err
+		}
+		return f, nil
+	case bloomfilter.RedisFilterType_Interactive:
+		return &redisInteractiveFilter{
+			key:       key,
+			cli:       cli,
+			ownClient: ownClient,
+			ctx:       ctx,
+			byteLen:   byteLen,
+			hashes:    hashes,
+		}, nil
+	}
+	return nil, errors.New("不匹配的过滤器类型")
+}
+
+// redisCachedFilter 缓存模式，本地维护字节数组，Write 时才提交到 redis
+type redisCachedFilter struct {
+	*memory.Filter
+	key       string
+	cli       redis.UniversalClient
+	ownClient bool
+	ctx       context.Context
+}
+
+// init 从 redis 加载字节数组，不存在则写入空数组
+func (f *redisCachedFilter) init() error {
+	data, err := f.cli.HGet(f.ctx, redisHashTableName, f.key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return f.cli.HSet(f.ctx, redisHashTableName, f.key, f.Bytes).Err()
+	}
+	if err != nil {
+		return err
+	}
+	if len(data) != len(f.Bytes) {
+		return global.ErrUnMatchLength
+	}
+	f.Bytes = data
+	return nil
+}
+
+// Clear 删除 redis 中的数据并清空本地字节数组
+func (f *redisCachedFilter) Clear() error {
+	if err := f.cli.HDel(f.ctx, redisHashTableName, f.key).Err(); err != nil {
+		return err
+	}
+	return f.Filter.Clear()
+}
+
+// Write 字节数组有变化时提交到 redis
+func (f *redisCachedFilter) Write() error {
+	if !f.IsChanged {
+		return nil
+	}
+	if err := f.cli.HSet(f.ctx, redisHashTableName, f.key, f.Bytes).Err(); err != nil {
+		return err
+	}
+	f.IsChanged = false
+	return nil
+}
+
+func (f *redisCachedFilter) Close() error {
+	if f.ownClient {
+		return f.cli.Close()
+	}
+	return nil
+}
+
+// redisInteractiveFilter 交互模式，每次 Push/Exists 直接操作 redis 的 BITMAP
+type redisInteractiveFilter struct {
+	key       string
+	cli       redis.UniversalClient
+	ownClient bool
+	ctx       context.Context
+	byteLen   uint64
+	hashes    []global.HashFunc
+}
+
+// offsets 计算内容对应的 bit 位置，每个字节内的 bit 顺序翻转，与缓存模式的字节数组保持一致
+func (f *redisInteractiveFilter) offsets(content []byte) []int64 {
+	offsets := make([]int64, 0, len(f.hashes))
+	for _, h := range f.hashes {
+		v := h()
+		v.Reset()
+		_, _ = v.Write(content)
+		res := v.Sum64()
+		offsets = append(offsets, int64((res%f.byteLen)*8+7-(res/f.byteLen)&7))
+	}
+	return offsets
+}
+
+func (f *redisInteractiveFilter) Push(content []byte) {
+	_, err := f.cli.Pipelined(f.ctx, func(pipe redis.Pipeliner) error {
+		for _, offset := range f.offsets(content) {
+			pipe.SetBit(f.ctx, f.key, offset, 1)
+		}
+		return nil
+	})
+	if err != nil {
+		global.Logger.Println(err)
+	}
+}
+
+// Write 交互模式不需要提交
+func (f *redisInteractiveFilter) Write() error {
+	return nil
+}
+
+func (f *redisInteractiveFilter) Exists(content []byte) bool {
+	for _, offset := range f.offsets(content) {
+		n, err := f.cli.GetBit(f.ctx, f.key, offset).Result()
+		if err == nil && n == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *redisInteractiveFilter) IsEmpty() bool {
+	n, err := f.cli.BitCount(f.ctx, f.key, &redis.BitCount{
+		Start: 0,
+		End:   int64(f.byteLen - 1),
+	}).Result()
+	return err == nil && n == 0
+}
+
+func (f *redisInteractiveFilter) Clear() error {
+	return f.cli.Del(f.ctx, f.key).Err()
+}
+
+func (f *redisInteractiveFilter) Close() error {
+	if f.ownClient {
+		return f.cli.Close()
+	}
+	return nil
+}
@@ -1,10 +1,11 @@
 package filter_test
 
 import (
-	"context"
 	"fmt"
-	"github.com/go-redis/redis/v8"
 	"github.com/hugh2632/bloomfilter"
+	"github.com/magic-lib/go-plat-cache/filter"
+	"github.com/magic-lib/go-plat-startupcfg/startupcfg"
+	"github.com/redis/go-redis/v9"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"hash"
@@ -29,9 +30,25 @@ var options = &redis.Options{
 
 var key = "test"
 
+func newRedisFilter(tp bloomfilter.RedisFilterType) (bloomfilter.IFilter, error) {
+	bf, err := filter.NewBloomFilter(&filter.BloomFilterOption{
+		ByteLen: 10240,
+		Hashes:  bloomfilter.DefaultHash,
+		RedisFilter: &struct {
+			RedisConfig  *startupcfg.RedisConfig
+			RedisOptions *redis.Options
+			FilterType   bloomfilter.RedisFilterType
+			Key          string
+		}{RedisOptions: options, FilterType: tp, Key: key},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bf.Get(), nil
+}
+
 func TestRedisCachedFilter(t *testing.T) {
-	cli := redis.NewClient(options)
-	cachedFilter, err := bloomfilter.NewRedisFilter(context.TODO(), cli, bloomfilter.RedisFilterType_Cached, key, 10240, bloomfilter.DefaultHash...)
+	cachedFilter, err := newRedisFilter(bloomfilter.RedisFilterType_Cached)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -81,14 +98,13 @@ func TestSqlFilterExist(t *testing.T) {
 }
 
 func TestInteractiveFilter(t *testing.T) {
-	cli := redis.NewClient(options)
-	interactiveFilter, err := bloomfilter.NewRedisFilter(context.TODO(), cli, bloomfilter.RedisFilterType_Interactive, key, 10240, bloomfilter.DefaultHash...)
+	interactiveFilter, err := newRedisFilter(bloomfilter.RedisFilterType_Interactive)
 	if err != nil {
 		t.Fatal(err)
 	}
 	fillNums(interactiveFilter, 250, 300)
 
-	anotherFilter, _ := bloomfilter.NewRedisFilter(context.TODO(), cli, bloomfilter.RedisFilterType_Interactive, key, 10240, bloomfilter.DefaultHash...)
+	anotherFilter, _ := newRedisFilter(bloomfilter.RedisFilterType_Interactive)
 
 	t.Log(interactiveFilter.Exists([]byte(strconv.Itoa(290)))) // true
 	t.Log(anotherFilter.Exists([]byte(strconv.Itoa(290))))     // true
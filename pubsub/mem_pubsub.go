@@ -0,0 +1,140 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+)
+
+const memBufferSize = 1024 // 每个订阅的消息缓冲，写满后 Publish 阻塞直到消费或取消订阅
+
+// memSubscription 进程内的一个订阅
+type memSubscription struct {
+	pattern bool
+	names   []string
+	msgCh   chan *Message
+	done    chan struct{}
+}
+
+// match 返回匹配的模式，频道订阅时返回 true 和空串
+func (s *memSubscription) match(channel string) (string, bool) {
+	for _, name := range s.names {
+		if !s.pattern {
+			if name == channel {
+				return "", true
+			}
+			continue
+		}
+		if globMatch(name, channel) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// memPubSub 进程内发布订阅，用于测试和单节点部署
+type memPubSub struct {
+	mu      sync.RWMutex
+	subs    map[*memSubscription]struct{}
+	closeCh chan struct{}
+	closed  bool
+}
+
+// NewMemPubSub 新建进程内发布订阅
+func NewMemPubSub() PubSub {
+	return &memPubSub{
+		subs:    make(map[*memSubscription]struct{}),
+		closeCh: make(chan struct{}),
+	}
+}
+
+// Publish 发布消息
+func (m *memPubSub) Publish(ctx context.Context, channel string, payload string) (int64, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	m.mu.RLock()
+	if m.closed {
+		m.mu.RUnlock()
+		return 0, ErrClosed
+	}
+	targets := make(map[*memSubscription]string)
+	for sub := range m.subs {
+		if pattern, ok := sub.match(channel); ok {
+			targets[sub] = pattern
+		}
+	}
+	m.mu.RUnlock()
+
+	var n int64
+	for sub, pattern := range targets {
+		msg := &Message{Channel: channel, Pattern: pattern, Payload: payload}
+		select {
+		case sub.msgCh <- msg:
+			n++
+		case <-sub.done:
+		case <-ctx.Done():
+			return n, ctx.Err()
+		}
+	}
+	return n, nil
+}
+
+// Subscribe 订阅频道
+func (m *memPubSub) Subscribe(ctx context.Context, handler Handler, channels ...string) error {
+	return m.subscribe(ctx, false, handler, channels)
+}
+
+// PSubscribe 按模式订阅
+func (m *memPubSub) PSubscribe(ctx context.Context, handler Handler, patterns ...string) error {
+	return m.subscribe(ctx, true, handler, patterns)
+}
+
+func (m *memPubSub) subscribe(ctx context.Context, pattern bool, handler Handler, names []string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	sub := &memSubscription{
+		pattern: pattern,
+		names:   names,
+		msgCh:   make(chan *Message, memBufferSize),
+		done:    make(chan struct{}),
+	}
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return ErrClosed
+	}
+	m.subs[sub] = struct{}{}
+	m.mu.Unlock()
+
+	go func() {
+		defer func() {
+			m.mu.Lock()
+			delete(m.subs, sub)
+			m.mu.Unlock()
+			close(sub.done)
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-m.closeCh:
+				return
+			case msg := <-sub.msgCh:
+				handler(ctx, msg)
+			}
+		}
+	}()
+	return nil
+}
+
+// Close 关闭后取消所有订阅
+func (m *memPubSub) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.closed {
+		m.closed = true
+		close(m.closeCh)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+)
+
+// Message 收到的消息
+type Message struct {
+	Channel string // 消息所在的频道
+	Pattern string // 通过模式订阅收到时为匹配的模式
+	Payload string
+}
+
+// Handler 消息处理函数，同一个订阅的消息按顺序调用，不要长时间阻塞
+type Handler func(ctx context.Context, msg *Message)
+
+// PubSub 发布订阅，消息不持久化，订阅之前和断线期间发布的消息会丢失
+type PubSub interface {
+	// Publish 发布消息，返回收到消息的订阅数
+	Publish(ctx context.Context, channel string, payload string) (int64, error)
+	// Subscribe 订阅频道，订阅成功后返回，ctx 结束时取消订阅
+	Subscribe(ctx context.Context, handler Handler, channels ...string) error
+	// PSubscribe 按模式订阅，支持 * ? [] 通配符，ctx 结束时取消订阅
+	PSubscribe(ctx context.Context, handler Handler, patterns ...string) error
+	// Close 关闭后取消所有订阅
+	Close() error
+}
+
+// ErrClosed 已关闭
+var ErrClosed = errors.New("pubsub: closed")
+
+// globMatch redis 风格的通配符匹配，支持 * ? [abc] [a-z] [^a] 和 \ 转义
+func globMatch(pattern, s string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for len(pattern) > 0 && pattern[0] == '*' {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(s); i++ {
+				if globMatch(pattern, s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(s) == 0 {
+				return false
+			}
+		case '[':
+			if len(s) == 0 {
+				return false
+			}
+			end := 1
+			for end < len(pattern) && pattern[end] != ']' {
+				if pattern[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(pattern) {
+				// 没有闭合的 ] 按普通字符处理
+				if s[0] != '[' {
+					return false
+				}
+				break
+			}
+			if !matchClass(pattern[1:end], s[0]) {
+				return false
+			}
+			pattern = pattern[end:]
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(s) == 0 || s[0] != pattern[0] {
+				return false
+			}
+		}
+		pattern = pattern[1:]
+		s = s[1:]
+	}
+	return len(s) == 0
+}
+
+// matchClass 匹配 [] 中的字符集合
+func matchClass(class string, c byte) bool {
+	negate := len(class) > 0 && class[0] == '^'
+	if negate {
+		class = class[1:]
+	}
+	matched := false
+	for i := 0; i < len(class); i++ {
+		if class[i] == '\\' && i+1 < len(class) {
+			i++
+			if class[i] == c {
+				matched = true
+			}
+			continue
+		}
+		if i+2 < len(class) && class[i+1] == '-' {
+			lo, hi := class[i], class[i+2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			if c >= lo && c <= hi {
+				matched = true
+			}
+			i += 2
+			continue
+		}
+		if class[i] == c {
+			matched = true
+		}
+	}
+	return matched != negate
+}
@@ -0,0 +1,52 @@
+package pubsub_test
+
+import (
+	"context"
+	"github.com/magic-lib/go-plat-cache/pubsub"
+	"testing"
+	"time"
+)
+
+func TestMemPubSub(t *testing.T) {
+	ps := pubsub.NewMemPubSub()
+	defer func() {
+		_ = ps.Close()
+	}()
+
+	msgCh := make(chan *pubsub.Message, 10)
+	handler := func(ctx context.Context, msg *pubsub.Message) {
+		msgCh <- msg
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := ps.Subscribe(ctx, handler, "config.reload"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ps.PSubscribe(context.Background(), handler, "cache.invalidate.*"); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, _ := ps.Publish(context.Background(), "config.reload", "v2"); n != 1 {
+		t.Fatalf("publish got %d receivers", n)
+	}
+	if msg := <-msgCh; msg.Channel != "config.reload" || msg.Payload != "v2" || msg.Pattern != "" {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+	_, _ = ps.Publish(context.Background(), "cache.invalidate.user", "42")
+	if msg := <-msgCh; msg.Pattern != "cache.invalidate.*" || msg.Payload != "42" {
+		t.Fatalf("unexpected pattern message %+v", msg)
+	}
+
+	// ctx 结束后取消订阅
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for {
+		n, _ := ps.Publish(context.Background(), "config.reload", "v3")
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("subscription should be removed after ctx is canceled")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,156 @@
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"github.com/magic-lib/go-plat-cache/cache"
+	"github.com/magic-lib/go-plat-startupcfg/startupcfg"
+	"github.com/redis/go-redis/v9"
+	"sync"
+	"time"
+)
+
+const defaultResubscribeInterval = 5 * time.Second
+
+// redisPubSub 基于 redis 的发布订阅，连接取自全局连接管理。
+// 断线后由 go-redis 的 PubSub.Channel 自动重连并重新订阅；订阅被关闭(如客户端被关闭)时才重新取客户端订阅
+type redisPubSub struct {
+	getClient func() (redis.UniversalClient, error)
+	interval  time.Duration // 重新订阅失败后重试的间隔
+
+	closeCh   chan struct{}
+	closeOnce sync.Once
+}
+
+// NewRedisPubSub 新建 redis 发布订阅
+func NewRedisPubSub(redisCfg *startupcfg.RedisConfig, opt ...*cache.RedisOptions) (PubSub, error) {
+	if redisCfg == nil {
+		return nil, fmt.Errorf("redis config is nil")
+	}
+	getClient := func() (redis.UniversalClient, error) {
+		return cache.GetRedisClient(redisCfg, opt...)
+	}
+	if _, err := getClient(); err != nil {
+		return nil, fmt.Errorf("redis pubsub connect error: %w", err)
+	}
+	return &redisPubSub{
+		getClient: getClient,
+		interval:  defaultResubscribeInterval,
+		closeCh:   make(chan struct{}),
+	}, nil
+}
+
+// Publish 发布消息
+func (p *redisPubSub) Publish(ctx context.Context, channel string, payload string) (int64, error) {
+	if p.isClosed() {
+		return 0, ErrClosed
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cli, err := p.getClient()
+	if err != nil {
+		return 0, fmt.Errorf("redis getClient error: %w", err)
+	}
+	return cli.Publish(ctx, channel, payload).Result()
+}
+
+// Subscribe 订阅频道
+func (p *redisPubSub) Subscribe(ctx context.Context, handler Handler, channels ...string) error {
+	return p.subscribe(ctx, false, handler, channels)
+}
+
+// PSubscribe 按模式订阅
+func (p *redisPubSub) PSubscribe(ctx context.Context, handler Handler, patterns ...string) error {
+	return p.subscribe(ctx, true, handler, patterns)
+}
+
+// subscribe 第一次订阅成功后返回，之后在后台接收消息，订阅被关闭时重新订阅
+func (p *redisPubSub) subscribe(ctx context.Context, pattern bool, handler Handler, names []string) error {
+	if p.isClosed() {
+		return ErrClosed
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("redis pubsub channels is empty")
+	}
+	ps, err := p.open(ctx, pattern, names)
+	if err != nil {
+		return err
+	}
+	go func() {
+		for {
+			p.receive(ctx, ps, handler)
+			_ = ps.Close()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-p.closeCh:
+					return
+				case <-time.After(p.interval):
+				}
+				if ps, err = p.open(ctx, pattern, names); err == nil {
+					break
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// open 在连接管理当前的客户端上订阅，并等待订阅确认
+func (p *redisPubSub) open(ctx context.Context, pattern bool, names []string) (*redis.PubSub, error) {
+	cli, err := p.getClient()
+	if err != nil {
+		return nil, fmt.Errorf("redis getClient error: %w", err)
+	}
+	var ps *redis.PubSub
+	if pattern {
+		ps = cli.PSubscribe(ctx, names...)
+	} else {
+		ps = cli.Subscribe(ctx, names...)
+	}
+	if _, err = ps.Receive(ctx); err != nil {
+		_ = ps.Close()
+		return nil, fmt.Errorf("redis subscribe error: %w", err)
+	}
+	return ps, nil
+}
+
+// receive 接收消息直到 ctx 结束、关闭或订阅被关闭，断线重连由 PubSub.Channel 处理
+func (p *redisPubSub) receive(ctx context.Context, ps *redis.PubSub, handler Handler) {
+	msgCh := ps.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.closeCh:
+			return
+		case msg, ok := <-msgCh:
+			if !ok {
+				return
+			}
+			handler(ctx, &Message{Channel: msg.Channel, Pattern: msg.Pattern, Payload: msg.Payload})
+		}
+	}
+}
+
+func (p *redisPubSub) isClosed() bool {
+	select {
+	case <-p.closeCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close 关闭后取消所有订阅，连接由全局连接管理维护，不会关闭
+func (p *redisPubSub) Close() error {
+	p.closeOnce.Do(func() {
+		close(p.closeCh)
+	})
+	return nil
+}
@@ -0,0 +1,152 @@
+package pubsub
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeRedisServer 只支持订阅的 redis 服务端，subscribed 在每次订阅确认后收到该连接
+type fakeRedisServer struct {
+	ln         net.Listener
+	subscribed chan net.Conn
+}
+
+func newFakeRedisServer(t *testing.T) *fakeRedisServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("listen error: %v", err)
+	}
+	s := &fakeRedisServer{ln: ln, subscribed: make(chan net.Conn, 10)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() {
+		_ = ln.Close()
+	})
+	return s
+}
+
+func (s *fakeRedisServer) serve(conn net.Conn) {
+	defer func() {
+		_ = conn.Close()
+	}()
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		switch strings.ToUpper(args[0]) {
+		case "HELLO":
+			_, _ = conn.Write([]byte("-ERR unknown command\r\n"))
+		case "PING":
+			_, _ = conn.Write([]byte("*2\r\n$4\r\npong\r\n$0\r\n\r\n"))
+		case "SUBSCRIBE":
+			for i, ch := range args[1:] {
+				_, _ = fmt.Fprintf(conn, "*3\r\n$9\r\nsubscribe\r\n$%d\r\n%s\r\n:%d\r\n", len(ch), ch, i+1)
+			}
+			s.subscribed <- conn
+		default:
+			_, _ = conn.Write([]byte("+OK\r\n"))
+		}
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		if _, err = r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		arg, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, strings.TrimSuffix(arg, "\r\n"))
+	}
+	return args, nil
+}
+
+func publishTo(conn net.Conn, channel, payload string) {
+	_, _ = fmt.Fprintf(conn, "*3\r\n$7\r\nmessage\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n", len(channel), channel, len(payload), payload)
+}
+
+func TestRedisPubSubReopen(t *testing.T) {
+	s := newFakeRedisServer(t)
+	var mu sync.Mutex
+	var cli *redis.Client
+	var calls atomic.Int32
+	p := &redisPubSub{
+		getClient: func() (redis.UniversalClient, error) {
+			calls.Add(1)
+			mu.Lock()
+			defer mu.Unlock()
+			return cli, nil
+		},
+		interval: 10 * time.Millisecond,
+		closeCh:  make(chan struct{}),
+	}
+	defer func() {
+		_ = p.Close()
+	}()
+	first := redis.NewClient(&redis.Options{Addr: s.ln.Addr().String()})
+	cli = first
+
+	msgCh := make(chan string, 10)
+	err := p.Subscribe(context.Background(), func(ctx context.Context, msg *Message) {
+		msgCh <- msg.Payload
+	}, "events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	publishTo(<-s.subscribed, "events", "a")
+	if got := <-msgCh; got != "a" {
+		t.Fatalf("unexpected message %q", got)
+	}
+
+	// 客户端被关闭后订阅随之关闭，在 getClient 取到的新客户端上重新订阅
+	second := redis.NewClient(&redis.Options{Addr: s.ln.Addr().String()})
+	defer func() {
+		_ = second.Close()
+	}()
+	mu.Lock()
+	cli = second
+	mu.Unlock()
+	_ = first.Close()
+
+	select {
+	case conn := <-s.subscribed:
+		publishTo(conn, "events", "b")
+	case <-time.After(5 * time.Second):
+		t.Fatal("should resubscribe after the client is closed")
+	}
+	if got := <-msgCh; got != "b" {
+		t.Fatalf("unexpected message %q", got)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("expected 2 getClient calls, got %d", n)
+	}
+}
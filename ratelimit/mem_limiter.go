@@ -0,0 +1,196 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// memState 单个 key 的限流状态
+type memState struct {
+	count    int         // 固定窗口：当前窗口的请求数
+	windowAt time.Time   // 固定窗口：窗口开始时间
+	logs     []time.Time // 滑动日志：窗口内请求的时间，从旧到新
+	curIdx   int64       // 滑动计数：当前窗口序号
+	cur      int         // 滑动计数：当前窗口的请求数
+	prev     int         // 滑动计数：上一个窗口的请求数
+	tokens   float64     // 令牌桶：剩余令牌
+	last     time.Time   // 令牌桶：上次补充时间
+	expireAt time.Time   // 过期后可以清理
+}
+
+// memLimiter 进程内限流，只适用于单实例
+type memLimiter struct {
+	cfg *Config
+	now func() time.Time
+
+	mu        sync.Mutex
+	states    map[string]*memState
+	lastSweep time.Time
+}
+
+// NewMemLimiter 新建进程内限流器，多实例部署时使用 NewRedisLimiter
+func NewMemLimiter(cfg *Config) (Limiter, error) {
+	newCfg, err := checkConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &memLimiter{
+		cfg:       newCfg,
+		now:       time.Now,
+		states:    make(map[string]*memState),
+		lastSweep: time.Now(),
+	}, nil
+}
+
+// Allow 申请一次
+func (m *memLimiter) Allow(ctx context.Context, key string) (*Result, error) {
+	return m.AllowN(ctx, key, 1)
+}
+
+// AllowN 申请 n 次，未放行时不消耗
+func (m *memLimiter) AllowN(_ context.Context, key string, n int) (*Result, error) {
+	if n <= 0 {
+		n = 1
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+	st, ok := m.states[key]
+	if !ok {
+		st = &memState{}
+		m.states[key] = st
+	}
+	switch m.cfg.Algorithm {
+	case SlidingWindowLog:
+		return m.slidingLog(st, now, n), nil
+	case SlidingWindowCounter:
+		return m.slidingCounter(st, now, n), nil
+	case TokenBucket:
+		return m.tokenBucket(st, now, n), nil
+	}
+	return m.fixedWindow(st, now, n), nil
+}
+
+// Wait 等待直到放行或 ctx 结束
+func (m *memLimiter) Wait(ctx context.Context, key string) error {
+	return wait(ctx, m, key)
+}
+
+// sweep 每个周期清理一次过期的 key，调用方需持有锁
+func (m *memLimiter) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < m.cfg.Period {
+		return
+	}
+	m.lastSweep = now
+	for key, st := range m.states {
+		if !st.expireAt.After(now) {
+			delete(m.states, key)
+		}
+	}
+}
+
+func (m *memLimiter) fixedWindow(st *memState, now time.Time, n int) *Result {
+	limit, period := m.cfg.Rate, m.cfg.Period
+	if st.windowAt.IsZero() || now.Sub(st.windowAt) >= period {
+		st.windowAt, st.count = now, 0
+	}
+	reset := st.windowAt.Add(period).Sub(now)
+	st.expireAt = st.windowAt.Add(period)
+	if st.count+n > limit {
+		return &Result{Remaining: limit - st.count, RetryAfter: reset, ResetAfter: reset}
+	}
+	st.count += n
+	return &Result{Allowed: true, Remaining: limit - st.count, ResetAfter: reset}
+}
+
+func (m *memLimiter) slidingLog(st *memState, now time.Time, n int) *Result {
+	limit, period := m.cfg.Rate, m.cfg.Period
+	i := 0
+	for i < len(st.logs) && !st.logs[i].After(now.Add(-period)) {
+		i++
+	}
+	st.logs = st.logs[i:]
+	cur := len(st.logs)
+	if cur+n > limit {
+		retry := period
+		if idx := cur + n - limit - 1; idx < cur {
+			retry = st.logs[idx].Add(period).Sub(now)
+		}
+		reset := period
+		if cur > 0 {
+			reset = st.logs[cur-1].Add(period).Sub(now)
+		}
+		st.expireAt = now.Add(reset)
+		return &Result{Remaining: limit - cur, RetryAfter: retry, ResetAfter: reset}
+	}
+	for j := 0; j < n; j++ {
+		st.logs = append(st.logs, now)
+	}
+	st.expireAt = now.Add(period)
+	return &Result{Allowed: true, Remaining: limit - cur - n, ResetAfter: period}
+}
+
+func (m *memLimiter) slidingCounter(st *memState, now time.Time, n int) *Result {
+	limit, period := m.cfg.Rate, m.cfg.Period
+	idx := now.UnixNano() / int64(period)
+	switch {
+	case idx == st.curIdx+1:
+		st.prev, st.cur = st.cur, 0
+	case idx != st.curIdx:
+		st.prev, st.cur = 0, 0
+	}
+	st.curIdx = idx
+	elapsed := time.Duration(now.UnixNano() - idx*int64(period))
+	retry, reset, est := slidingCounterEstimate(limit, n, st.cur, st.prev, period, elapsed)
+	st.expireAt = now.Add(2*period - elapsed)
+	if retry > 0 {
+		return &Result{Remaining: int(math.Max(0, float64(limit)-est)), RetryAfter: retry, ResetAfter: reset}
+	}
+	st.cur += n
+	return &Result{Allowed: true, Remaining: int(math.Max(0, float64(limit)-est-float64(n))), ResetAfter: reset}
+}
+
+// slidingCounterEstimate 按上一个窗口剩余比例估算当前请求数，返回需要等待的时间，为 0 表示可以放行
+func slidingCounterEstimate(limit, n, cur, prev int, period, elapsed time.Duration) (retry, reset time.Duration, est float64) {
+	weight := float64(period-elapsed) / float64(period)
+	est = float64(prev)*weight + float64(cur)
+	reset = 2*period - elapsed
+	if est+float64(n) <= float64(limit) {
+		return 0, reset, est
+	}
+	if cur+n > limit || prev == 0 {
+		return period - elapsed, reset, est
+	}
+	// prev*(period-t)/period + cur + n <= limit 时放行
+	need := time.Duration(math.Ceil(float64(period)*(1-float64(limit-cur-n)/float64(prev)))) - elapsed
+	if need <= 0 {
+		need = time.Millisecond
+	}
+	return need, reset, est
+}
+
+func (m *memLimiter) tokenBucket(st *memState, now time.Time, n int) *Result {
+	capacity := float64(m.cfg.Burst)
+	fill := float64(m.cfg.Rate) / float64(m.cfg.Period) // 每纳秒补充的令牌
+	if st.last.IsZero() {
+		st.tokens = capacity
+	} else if delta := now.Sub(st.last); delta > 0 {
+		st.tokens = math.Min(capacity, st.tokens+float64(delta)*fill)
+	}
+	st.last = now
+	allowed := st.tokens >= float64(n)
+	if allowed {
+		st.tokens -= float64(n)
+	}
+	reset := time.Duration(math.Ceil((capacity - st.tokens) / fill))
+	st.expireAt = now.Add(reset)
+	if !allowed {
+		retry := time.Duration(math.Ceil((float64(n) - st.tokens) / fill))
+		return &Result{Remaining: int(st.tokens), RetryAfter: retry, ResetAfter: reset}
+	}
+	return &Result{Allowed: true, Remaining: int(st.tokens), ResetAfter: reset}
+}
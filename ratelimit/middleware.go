@@ -0,0 +1,54 @@
+package ratelimit
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// KeyFunc 从请求中取得限流 key，返回空表示不限流
+type KeyFunc func(r *http.Request) string
+
+// RemoteIPKey 按客户端 ip 限流
+func RemoteIPKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Middleware net/http 限流中间件，未放行时返回 429 和 Retry-After，限流器出错时放行
+func Middleware(l Limiter, keyFunc KeyFunc) func(http.Handler) http.Handler {
+	if keyFunc == nil {
+		keyFunc = RemoteIPKey
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := keyFunc(r)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			res, err := l.Allow(r.Context(), key)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			w.Header().Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(res.ResetAfter.Seconds())))
+			if !res.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter.Seconds())))
+				http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ceilSeconds 向上取整的秒数，Retry-After 只支持整秒
+func ceilSeconds(sec float64) int {
+	return int(math.Ceil(sec))
+}
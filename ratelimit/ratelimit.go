@@ -0,0 +1,118 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Algorithm 限流算法
+type Algorithm int
+
+const (
+	FixedWindow          Algorithm = iota // 固定窗口，第一次请求开始计时，窗口内最多 Rate 次
+	SlidingWindowLog                      // 滑动窗口日志，记录每次请求的时间，精确但占用内存较多
+	SlidingWindowCounter                  // 滑动窗口计数，按上一个窗口的剩余比例估算，内存占用小
+	TokenBucket                           // 令牌桶，每 Period 补充 Rate 个令牌，最多 Burst 个，允许突发
+)
+
+// String 算法名称
+func (a Algorithm) String() string {
+	switch a {
+	case FixedWindow:
+		return "fixed_window"
+	case SlidingWindowLog:
+		return "sliding_window_log"
+	case SlidingWindowCounter:
+		return "sliding_window_counter"
+	case TokenBucket:
+		return "token_bucket"
+	}
+	return "unknown"
+}
+
+const (
+	defaultPrefix      = "ratelimit:"
+	minWaitInterval    = 10 * time.Millisecond
+	defaultWaitTimeout = time.Minute
+)
+
+// Config 限流配置
+type Config struct {
+	Algorithm Algorithm     // 限流算法，默认固定窗口
+	Rate      int           // 每个 Period 允许的请求数
+	Period    time.Duration // 统计周期
+	Burst     int           // 令牌桶容量，默认为 Rate，其他算法忽略
+	Prefix    string        // redis key 前缀，默认 ratelimit:
+}
+
+// Result 限流结果
+type Result struct {
+	Allowed    bool          // 是否放行
+	Remaining  int           // 剩余可用次数
+	RetryAfter time.Duration // 未放行时需要等待的时间
+	ResetAfter time.Duration // 多久后完全恢复
+}
+
+// Limiter 限流器，key 为限流对象，比如用户 id、ip、接口名
+type Limiter interface {
+	Allow(ctx context.Context, key string) (*Result, error)
+	AllowN(ctx context.Context, key string, n int) (*Result, error)
+	Wait(ctx context.Context, key string) error
+}
+
+// checkConfig 检查配置并设置默认值
+func checkConfig(cfg *Config) (*Config, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("ratelimit config is nil")
+	}
+	if cfg.Rate <= 0 || cfg.Period <= 0 {
+		return nil, fmt.Errorf("ratelimit rate and period must be positive")
+	}
+	if cfg.Algorithm < FixedWindow || cfg.Algorithm > TokenBucket {
+		return nil, fmt.Errorf("ratelimit algorithm %d is not supported", cfg.Algorithm)
+	}
+	newCfg := *cfg
+	if newCfg.Burst <= 0 {
+		newCfg.Burst = newCfg.Rate
+	}
+	if newCfg.Prefix == "" {
+		newCfg.Prefix = defaultPrefix
+	}
+	return &newCfg, nil
+}
+
+// wait 循环申请直到放行，每次按 RetryAfter 等待
+func wait(ctx context.Context, l Limiter, key string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultWaitTimeout)
+		defer cancel()
+	}
+	for {
+		res, err := l.AllowN(ctx, key, 1)
+		if err != nil {
+			return err
+		}
+		if res.Allowed {
+			return nil
+		}
+		delay := res.RetryAfter
+		if delay < minWaitInterval {
+			delay = minWaitInterval
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return fmt.Errorf("ratelimit wait %s exceeds context deadline: %w", delay, context.DeadlineExceeded)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
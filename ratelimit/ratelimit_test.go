@@ -0,0 +1,57 @@
+package ratelimit_test
+
+import (
+	"context"
+	"github.com/magic-lib/go-plat-cache/ratelimit"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMemLimiter(t *testing.T) {
+	ctx := context.Background()
+	for _, alg := range []ratelimit.Algorithm{ratelimit.FixedWindow, ratelimit.SlidingWindowLog,
+		ratelimit.SlidingWindowCounter, ratelimit.TokenBucket} {
+		l, err := ratelimit.NewMemLimiter(&ratelimit.Config{Algorithm: alg, Rate: 3, Period: 200 * time.Millisecond})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 3; i++ {
+			res, _ := l.Allow(ctx, "u1")
+			if !res.Allowed {
+				t.Fatalf("%s: request %d should be allowed", alg, i)
+			}
+		}
+		res, _ := l.Allow(ctx, "u1")
+		if res.Allowed || res.RetryAfter <= 0 {
+			t.Fatalf("%s: 4th request should be limited with retry-after, got %+v", alg, res)
+		}
+		if res, _ = l.Allow(ctx, "u2"); !res.Allowed {
+			t.Fatalf("%s: other key should not be limited", alg)
+		}
+		waitCtx, cancel := context.WithTimeout(ctx, time.Second)
+		if err = l.Wait(waitCtx, "u1"); err != nil {
+			t.Fatalf("%s: wait error: %v", alg, err)
+		}
+		cancel()
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	l, _ := ratelimit.NewMemLimiter(&ratelimit.Config{Rate: 1, Period: time.Minute})
+	h := ratelimit.Middleware(l, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	codes := make([]int, 0)
+	var retryAfter string
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		codes = append(codes, rec.Code)
+		retryAfter = rec.Header().Get("Retry-After")
+	}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusTooManyRequests || retryAfter == "" {
+		t.Fatalf("unexpected codes %v retry-after %q", codes, retryAfter)
+	}
+}
@@ -0,0 +1,218 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"github.com/magic-lib/go-plat-cache/cache"
+	"github.com/magic-lib/go-plat-startupcfg/startupcfg"
+	"github.com/redis/go-redis/v9"
+	"math/rand"
+	"time"
+)
+
+// 脚本统一使用 redis 服务器时间，避免多实例时钟不一致；返回 {是否放行, 剩余次数, 等待毫秒, 恢复毫秒}
+const redisNowScript = `
+redis.replicate_commands()
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+`
+
+var (
+	// fixedWindowScript KEYS[1] 计数 key，ARGV: limit, period_ms, n
+	fixedWindowScript = redis.NewScript(`
+local limit, period, n = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+local cur = tonumber(redis.call('GET', KEYS[1]) or '0')
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl < 0 then ttl = period end
+if cur + n > limit then
+	return {0, limit - cur, ttl, ttl}
+end
+cur = redis.call('INCRBY', KEYS[1], n)
+if redis.call('PTTL', KEYS[1]) < 0 then
+	redis.call('PEXPIRE', KEYS[1], period)
+end
+return {1, limit - cur, 0, ttl}
+`)
+
+	// slidingLogScript KEYS[1] 请求时间的有序集合，ARGV: limit, period_ms, n, member 前缀
+	slidingLogScript = redis.NewScript(redisNowScript + `
+local limit, period, n = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - period)
+local cur = redis.call('ZCARD', KEYS[1])
+if cur + n > limit then
+	local retry, reset = period, period
+	local idx = cur + n - limit - 1
+	if idx < cur then
+		local e = redis.call('ZRANGE', KEYS[1], idx, idx, 'WITHSCORES')
+		retry = tonumber(e[2]) + period - now
+	end
+	if cur > 0 then
+		local e = redis.call('ZRANGE', KEYS[1], -1, -1, 'WITHSCORES')
+		reset = tonumber(e[2]) + period - now
+	end
+	return {0, limit - cur, retry, reset}
+end
+for i = 1, n do
+	redis.call('ZADD', KEYS[1], now, ARGV[4] .. ':' .. i)
+end
+redis.call('PEXPIRE', KEYS[1], period)
+return {1, limit - cur - n, 0, period}
+`)
+
+	// slidingCounterScript KEYS[1] 以窗口序号为 field 的 hash，ARGV: limit, period_ms, n
+	slidingCounterScript = redis.NewScript(redisNowScript + `
+local limit, period, n = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3])
+local idx = math.floor(now / period)
+local elapsed = now - idx * period
+local cur = tonumber(redis.call('HGET', KEYS[1], idx) or '0')
+local prev = tonumber(redis.call('HGET', KEYS[1], idx - 1) or '0')
+local est = prev * (period - elapsed) / period + cur
+local reset = 2 * period - elapsed
+if est + n > limit then
+	local retry = period - elapsed
+	if cur + n <= limit and prev > 0 then
+		retry = math.max(1, math.ceil(period * (1 - (limit - cur - n) / prev)) - elapsed)
+	end
+	return {0, math.max(0, math.floor(limit - est)), retry, reset}
+end
+redis.call('HINCRBY', KEYS[1], idx, n)
+for _, f in ipairs(redis.call('HKEYS', KEYS[1])) do
+	if tonumber(f) < idx - 1 then
+		redis.call('HDEL', KEYS[1], f)
+	end
+end
+redis.call('PEXPIRE', KEYS[1], 2 * period)
+return {1, math.max(0, math.floor(limit - est - n)), 0, reset}
+`)
+
+	// tokenBucketScript KEYS[1] 令牌桶 hash，ARGV: burst, rate, period_ms, n
+	tokenBucketScript = redis.NewScript(redisNowScript + `
+local capacity, rate, period, n = tonumber(ARGV[1]), tonumber(ARGV[2]), tonumber(ARGV[3]), tonumber(ARGV[4])
+local fill = rate / period
+local v = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(v[1]) or capacity
+local ts = tonumber(v[2]) or now
+if now > ts then
+	tokens = math.min(capacity, tokens + (now - ts) * fill)
+end
+local allowed = 0
+local retry = 0
+if tokens >= n then
+	allowed = 1
+	tokens = tokens - n
+else
+	retry = math.ceil((n - tokens) / fill)
+end
+local reset = math.ceil((capacity - tokens) / fill)
+redis.call('HSET', KEYS[1], 'tokens', tokens, 'ts', now)
+redis.call('PEXPIRE', KEYS[1], reset + 1000)
+return {allowed, math.floor(tokens), retry, reset}
+`)
+)
+
+// ScriptRunner 执行 lua 脚本，cache.NewRedisClient 返回的客户端已实现
+type ScriptRunner interface {
+	RunScript(ctx context.Context, script *redis.Script, keys []string, args ...any) (any, error)
+}
+
+// redisLimiter 基于 redis lua 脚本的分布式限流，每次申请只访问一个 key，支持集群
+type redisLimiter struct {
+	cfg    *Config
+	runner ScriptRunner
+}
+
+// NewRedisLimiter 新建分布式限流器
+func NewRedisLimiter(runner ScriptRunner, cfg *Config) (Limiter, error) {
+	if runner == nil {
+		return nil, fmt.Errorf("ratelimit script runner is nil")
+	}
+	newCfg, err := checkConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &redisLimiter{
+		cfg:    newCfg,
+		runner: runner,
+	}, nil
+}
+
+// NewRedisLimiterFromConfig 使用全局连接管理中的 redis 连接新建分布式限流器
+func NewRedisLimiterFromConfig(redisCfg *startupcfg.RedisConfig, cfg *Config, opt ...*cache.RedisOptions) (Limiter, error) {
+	if redisCfg == nil {
+		return nil, fmt.Errorf("redis config is nil")
+	}
+	return NewRedisLimiter(cache.NewRedisClient(redisCfg, opt...), cfg)
+}
+
+// Allow 申请一次
+func (r *redisLimiter) Allow(ctx context.Context, key string) (*Result, error) {
+	return r.AllowN(ctx, key, 1)
+}
+
+// AllowN 申请 n 次，未放行时不消耗
+func (r *redisLimiter) AllowN(ctx context.Context, key string, n int) (*Result, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if n <= 0 {
+		n = 1
+	}
+	periodMs := r.cfg.Period.Milliseconds()
+	if periodMs <= 0 {
+		periodMs = 1
+	}
+	redisKey := r.cfg.Prefix + r.cfg.Algorithm.String() + ":" + key
+
+	var script *redis.Script
+	var args []any
+	switch r.cfg.Algorithm {
+	case SlidingWindowLog:
+		script = slidingLogScript
+		args = []any{r.cfg.Rate, periodMs, n, fmt.Sprintf("%d-%d", time.Now().UnixNano(), rand.Int63())}
+	case SlidingWindowCounter:
+		script = slidingCounterScript
+		args = []any{r.cfg.Rate, periodMs, n}
+	case TokenBucket:
+		script = tokenBucketScript
+		args = []any{r.cfg.Burst, r.cfg.Rate, periodMs, n}
+	default:
+		script = fixedWindowScript
+		args = []any{r.cfg.Rate, periodMs, n}
+	}
+	ret, err := r.runner.RunScript(ctx, script, []string{redisKey}, args...)
+	if err != nil {
+		return nil, fmt.Errorf("ratelimit run script error: %w", err)
+	}
+	return parseScriptResult(ret)
+}
+
+// Wait 等待直到放行或 ctx 结束
+func (r *redisLimiter) Wait(ctx context.Context, key string) error {
+	return wait(ctx, r, key)
+}
+
+// parseScriptResult 解析脚本返回的 {是否放行, 剩余次数, 等待毫秒, 恢复毫秒}
+func parseScriptResult(ret any) (*Result, error) {
+	list, ok := ret.([]any)
+	if !ok || len(list) != 4 {
+		return nil, fmt.Errorf("ratelimit unexpected script result: %v", ret)
+	}
+	nums := make([]int64, len(list))
+	for i, one := range list {
+		num, ok := one.(int64)
+		if !ok {
+			return nil, fmt.Errorf("ratelimit unexpected script result: %v", ret)
+		}
+		nums[i] = num
+	}
+	remaining := int(nums[1])
+	if remaining < 0 {
+		remaining = 0
+	}
+	return &Result{
+		Allowed:    nums[0] == 1,
+		Remaining:  remaining,
+		RetryAfter: time.Duration(nums[2]) * time.Millisecond,
+		ResetAfter: time.Duration(nums[3]) * time.Millisecond,
+	}, nil
+}